@@ -1,10 +1,110 @@
 // API Client - Pure JavaScript HTTP client (replaces Go backend)
 
+// Thrown when the server doesn't accept our token and the client must pair
+export class PairingRequiredError extends Error {
+  constructor() {
+    super('Pairing required');
+    this.name = 'PairingRequiredError';
+  }
+}
+
+// An error response from the server; code is one of the codes in PROTOCOL.md
+export class APIError extends Error {
+  constructor(message, { code = '', field = '', obs_status = 0 } = {}) {
+    super(message);
+    this.name = 'APIError';
+    this.code = code;
+    this.field = field;
+    this.obsStatus = obs_status;
+  }
+}
+
+// Read the error from a failed response
+async function responseError(response) {
+  const text = await response.text();
+  try {
+    const body = JSON.parse(text);
+    return new APIError(body.error || `Server returned ${response.status}`, body);
+  } catch {
+    return new APIError(text.trim() || `Server returned ${response.status}`);
+  }
+}
+
+// Newest server API version this client speaks, and the oldest it can use
+const API_VERSION = 1;
+const MIN_SERVER_API_VERSION = 1;
+
 export class APIClient {
   constructor(serverURL) {
     this.serverURL = serverURL;
     this.sessionID = null;
     this.clientID = this.loadOrCreateClientID();
+    this.token = localStorage.getItem(`token:${serverURL}`);
+    this.clientName = localStorage.getItem('client_name') || 'Web Client';
+    this.locked = false;
+    this.events = null;
+    this.eventsRetryDelay = 1000;
+    // Set once the server accepted us on its WebSocket protocol; requests
+    // sent over it wait in pending for the reply carrying their ID
+    this.live = false;
+    this.nextRequestID = 0;
+    this.pending = new Map();
+    // Set by getServerInfo
+    this.apiPrefix = '/api';
+    this.capabilities = [];
+  }
+
+  // Whether we hold a token for this server
+  isPaired() {
+    return !!this.token;
+  }
+
+  // Headers for authenticated requests
+  headers(extra = {}) {
+    const headers = { ...extra };
+    if (this.token) headers['Authorization'] = `Bearer ${this.token}`;
+    if (this.sessionID) headers['X-Session-ID'] = this.sessionID;
+    return headers;
+  }
+
+  // Authenticated fetch that turns a rejected token into PairingRequiredError
+  async request(path, options = {}) {
+    const response = await fetch(`${this.serverURL}${this.apiPath(path)}`, {
+      ...options,
+      headers: this.headers(options.headers)
+    });
+    if (response.status === 401) throw new PairingRequiredError();
+    return response;
+  }
+
+  // Redeem a pairing code shown in the server UI for a token
+  async pair(code) {
+    const response = await fetch(`${this.serverURL}${this.apiPath('/pair')}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        code: code.trim(),
+        client_id: this.clientID,
+        client_name: 'Web Client'
+      })
+    });
+
+    if (!response.ok) {
+      const error = await responseError(response);
+      throw new Error(`Pairing failed: ${error.message}`);
+    }
+
+    const data = await response.json();
+    this.token = data.token;
+    localStorage.setItem(`token:${this.serverURL}`, this.token);
+    console.log('✓ Paired with server - Device:', data.device_id);
+  }
+
+  // Save the name the server UI gave this client, used from the next
+  // registration on
+  setClientName(name) {
+    this.clientName = name;
+    localStorage.setItem('client_name', name);
   }
 
   // Load or create persistent client ID
@@ -20,36 +120,58 @@ export class APIClient {
     return id;
   }
 
-  // Get server info (health check)
+  // Get server info (health check) and pick the newest API version both
+  // sides speak. Every server answers the unversioned health check.
   async getServerInfo() {
+    let info;
     try {
       const response = await fetch(`${this.serverURL}/api/health`);
       if (!response.ok) throw new Error(`Server returned ${response.status}`);
-      return await response.json();
+      info = await response.json();
     } catch (err) {
       throw new Error(`Failed to connect to server: ${err.message}`);
     }
+
+    // Servers from before the API was versioned don't report a version
+    if (!info.api_version || info.api_version < MIN_SERVER_API_VERSION) {
+      throw new Error('The server is too old for this client, please update the Robo-Stream server');
+    }
+    if (info.min_api_version > API_VERSION) {
+      throw new Error('The server is too new for this client, please update this client');
+    }
+
+    this.apiPrefix = `/api/v${Math.min(info.api_version, API_VERSION)}`;
+    this.capabilities = info.capabilities || [];
+    return info;
+  }
+
+  // Whether the server reported a capability
+  supports(capability) {
+    return this.capabilities.includes(capability);
+  }
+
+  // Server path of an API endpoint under the negotiated version
+  apiPath(endpoint) {
+    return `${this.apiPrefix}${endpoint}`;
   }
 
   // Register with server and get session ID
   async register() {
     try {
-      const response = await fetch(`${this.serverURL}/api/client/register`, {
+      const response = await this.request('/client/register', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
           client_id: this.clientID,
-          client_name: 'Web Client'
+          client_name: this.clientName
         })
       });
       
-      if (!response.ok) {
-        const error = await response.text();
-        throw new Error(`Registration failed: ${error}`);
-      }
+      if (!response.ok) throw await responseError(response);
       
       const data = await response.json();
       this.sessionID = data.session_id;
+      this.locked = !!data.locked;
       
       // Save session ID to localStorage for persistence
       localStorage.setItem('session_id', this.sessionID);
@@ -57,6 +179,7 @@ export class APIClient {
       console.log('✓ Registered with server - Session:', this.sessionID);
       return data.config;
     } catch (err) {
+      if (err instanceof PairingRequiredError || err instanceof APIError) throw err;
       throw new Error(`Failed to register: ${err.message}`);
     }
   }
@@ -64,7 +187,7 @@ export class APIClient {
   // Get all configurations
   async getConfigurations() {
     try {
-      const response = await fetch(`${this.serverURL}/api/configurations`);
+      const response = await this.request('/configurations');
       if (!response.ok) throw new Error(`Server returned ${response.status}`);
       return await response.json();
     } catch (err) {
@@ -79,43 +202,42 @@ export class APIClient {
         throw new Error('Not registered - no session ID');
       }
 
-      const response = await fetch(`${this.serverURL}/api/client/config/${configID}`, {
-        method: 'PUT',
-        headers: { 'X-Session-ID': this.sessionID }
+      const response = await this.request(`/client/config/${configID}`, {
+        method: 'PUT'
       });
       
-      if (!response.ok) {
-        const error = await response.text();
-        throw new Error(`Failed to load configuration: ${error}`);
-      }
+      if (!response.ok) throw await responseError(response);
       
       return await response.json();
     } catch (err) {
+      if (err instanceof APIError) throw err;
       throw new Error(`Failed to get configuration: ${err.message}`);
     }
   }
 
-  // Execute button action
-  async executeAction(action) {
+  // Execute button action; the button ID lets the server check the session's role.
+  // Goes over the live connection when there is one.
+  async executeAction(action, buttonID) {
     try {
       if (!this.sessionID) {
         throw new Error('Not registered - no session ID');
       }
+
+      if (this.live) {
+        const result = await this.liveRequest('action', { ...action, button_id: buttonID }, 3000);
+        if (result.error) throw new APIError(result.error, result);
+        return;
+      }
       
-      const response = await fetch(`${this.serverURL}/api/action`, {
+      const response = await this.request('/action', {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-          'X-Session-ID': this.sessionID
-        },
-        body: JSON.stringify(action)
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ...action, button_id: buttonID })
       });
       
-      if (!response.ok) {
-        const error = await response.text();
-        throw new Error(`Action failed: ${error}`);
-      }
+      if (!response.ok) throw await responseError(response);
     } catch (err) {
+      if (err instanceof APIError) throw err;
       throw new Error(`Failed to execute action: ${err.message}`);
     }
   }
@@ -123,7 +245,7 @@ export class APIClient {
   // Get OBS status
   async getOBSStatus() {
     try {
-      const response = await fetch(`${this.serverURL}/api/obs/status`);
+      const response = await this.request('/obs/status');
       if (!response.ok) throw new Error(`Server returned ${response.status}`);
       return await response.json();
     } catch (err) {
@@ -148,9 +270,7 @@ export class APIClient {
         source: sourceName 
       });
       
-      const response = await fetch(
-        `${this.serverURL}/api/obs/source-visibility?${params}`
-      );
+      const response = await this.request(`/obs/source-visibility?${params}`);
       
       if (!response.ok) throw new Error(`Server returned ${response.status}`);
       
@@ -161,4 +281,124 @@ export class APIClient {
       return false;
     }
   }
+
+  // Send a request over the live connection and wait for its reply
+  liveRequest(type, data, timeout) {
+    const socket = this.events;
+    const id = String(++this.nextRequestID);
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => {
+        this.pending.delete(id);
+        reject(new Error(`No reply to ${type} within ${timeout}ms`));
+      }, timeout);
+      this.pending.set(id, (event) => {
+        clearTimeout(timer);
+        resolve(event);
+      });
+      socket.send(JSON.stringify({ type, id, data }));
+    });
+  }
+
+  // Listen to the server's WebSocket, reconnecting with backoff until
+  // closeEvents is called. Commands from the server UI go to onCommand and
+  // its result is acknowledged. Once connected, actions go over the socket
+  // and status and state are pushed as events (see PROTOCOL.md). Servers
+  // without the protocol only have the events stream, where the token and
+  // session go in the query string as browsers can't set headers on
+  // WebSockets.
+  listenEvents(onEvent, onCommand) {
+    this.closeEvents();
+
+    const legacy = !this.supports('websocket');
+    const url = new URL(this.apiPath(legacy ? '/client/events' : '/ws'), this.serverURL);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    if (legacy) {
+      url.searchParams.set('token', this.token);
+      url.searchParams.set('session_id', this.sessionID);
+    }
+
+    const socket = new WebSocket(url);
+    this.events = socket;
+
+    // Heartbeats keep the session shown as online on the server
+    let heartbeat = null;
+    socket.onopen = () => {
+      if (legacy) {
+        console.log('✓ Listening for server events');
+        this.eventsRetryDelay = 1000;
+      } else {
+        socket.send(JSON.stringify({
+          type: 'auth',
+          id: 'auth',
+          data: { token: this.token, session_id: this.sessionID }
+        }));
+      }
+      heartbeat = setInterval(() => socket.send(JSON.stringify({ type: 'heartbeat' })), 20000);
+    };
+    socket.onmessage = async (message) => {
+      let event;
+      try {
+        event = JSON.parse(message.data);
+      } catch (err) {
+        console.error('Invalid event from server:', err);
+        return;
+      }
+
+      if (event.type === 'auth_result') {
+        if (event.error) {
+          console.error(`Server refused connection (${event.code}):`, event.error);
+          return;
+        }
+        console.log('✓ Connected to server over WebSocket');
+        this.eventsRetryDelay = 1000;
+        this.live = true;
+        socket.send(JSON.stringify({ type: 'subscribe', data: { topics: ['status', 'state'] } }));
+        return;
+      }
+      if (event.id && this.pending.has(event.id)) {
+        const resolve = this.pending.get(event.id);
+        this.pending.delete(event.id);
+        resolve(event);
+        return;
+      }
+      if (event.type !== 'command') {
+        onEvent(event);
+        return;
+      }
+
+      const command = event.data;
+      const ack = { id: command.id };
+      try {
+        await onCommand(command);
+      } catch (err) {
+        ack.error = err.message;
+      }
+      socket.send(JSON.stringify({ type: 'ack', data: ack }));
+
+      // Stay disconnected until the user reconnects
+      if (command.name === 'disconnect' && !ack.error) this.closeEvents();
+    };
+    socket.onclose = () => {
+      clearInterval(heartbeat);
+      if (this.events === socket) this.live = false;
+      for (const resolve of this.pending.values()) {
+        resolve({ error: 'connection closed' });
+      }
+      this.pending.clear();
+      if (this.events !== socket) return; // closed on purpose
+      console.warn(`Event stream closed, reconnecting in ${this.eventsRetryDelay}ms`);
+      setTimeout(() => {
+        if (this.events === socket) this.listenEvents(onEvent, onCommand);
+      }, this.eventsRetryDelay);
+      this.eventsRetryDelay = Math.min(this.eventsRetryDelay * 2, 30000);
+    };
+  }
+
+  // Stop listening to server events
+  closeEvents() {
+    const socket = this.events;
+    this.events = null;
+    this.live = false;
+    if (socket) socket.close();
+  }
 }
@@ -1,5 +1,5 @@
 // Robo-Stream Web Client - Touchscreen Optimized
-import { APIClient } from './api.js';
+import { APIClient, PairingRequiredError } from './api.js';
 import { initializeNativeFeatures, hapticFeedback, isNativeApp } from './native.js';
 
 let currentConfiguration = null;
@@ -13,6 +13,7 @@ let obsStatus = {
   studioModeActive: false
 };
 let sourceVisibility = {};
+let isLocked = false;
 
 // Initialize app when DOM is loaded
 document.addEventListener('DOMContentLoaded', () => {
@@ -53,28 +54,69 @@ async function connectAndLoad() {
         showConnectionBanner('Connecting to server...', 'connecting');
         await apiClient.getServerInfo();
         showConnectionBanner('Connected to server', 'connected');
+
+        // The server only talks to paired devices
+        if (!apiClient.isPaired()) {
+            handlePairingRequired();
+            return;
+        }
         
         // Register to get session ID
         console.log('Registering with server...');
         const config = await apiClient.register();
         handleConfigurationLoaded(config);
+        setLocked(apiClient.locked);
+
+        // Follow edits and commands from the server
+        apiClient.listenEvents(handleServerEvent, handleCommand);
         
         // Start status polling
         startStatusPolling();
         
         setTimeout(() => hideConnectionBanner(), 2000);
     } catch (err) {
+        if (err instanceof PairingRequiredError) {
+            handlePairingRequired();
+            return;
+        }
         console.error('Connection error:', err);
         showConnectionBanner('Failed to connect: ' + err.message, 'error');
     }
 }
 
+// Ask for the pairing code shown on the server
+function handlePairingRequired() {
+    showConnectionBanner('Pair this client: enter the code shown on the server under Clients → Pair Device', 'error');
+    openSettings();
+}
+
+// Pair with the server using the code shown in its UI
+async function pairWithServer() {
+    const code = document.getElementById('input-pairing-code').value.trim();
+
+    if (!code) {
+        showConnectionBanner('Please enter the pairing code shown on the server', 'error');
+        return;
+    }
+
+    try {
+        await apiClient.pair(code);
+        document.getElementById('input-pairing-code').value = '';
+        closeSettings();
+        await connectAndLoad();
+    } catch (err) {
+        console.error('Pairing error:', err);
+        showConnectionBanner(err.message, 'error');
+    }
+}
+
 // Setup event listeners
 function setupEventListeners() {
     // Settings button
     document.getElementById('btn-settings').addEventListener('click', openSettings);
     document.getElementById('btn-close-settings-modal').addEventListener('click', closeSettings);
     document.getElementById('btn-update-server').addEventListener('click', updateServerURL);
+    document.getElementById('btn-pair').addEventListener('click', pairWithServer);
 
     // Config selector
     document.getElementById('btn-select-config').addEventListener('click', openConfigSelector);
@@ -105,6 +147,68 @@ function handleConfigurationLoaded(config) {
     setTimeout(() => hideConnectionBanner(), 2000);
 }
 
+// Apply an event pushed by the server
+function handleServerEvent(event) {
+    switch (event.type) {
+        case 'config_updated':
+            console.log('Server updated configuration');
+            handleConfigurationLoaded(event.data);
+            break;
+        case 'status':
+            applyStatus(event.data);
+            break;
+        case 'state':
+            applySourceState(event.data.sources);
+            break;
+    }
+}
+
+// Run a command sent from the server UI; a thrown error is reported back
+async function handleCommand(command) {
+    console.log('Server sent command:', command.name);
+    const params = command.params || {};
+
+    switch (command.name) {
+        case 'switch_config':
+            handleConfigurationLoaded(await apiClient.getConfiguration(params.config_id));
+            break;
+        case 'identify':
+            showIdentify(params.name);
+            break;
+        case 'lock':
+            setLocked(!!params.locked);
+            break;
+        case 'rename':
+            if (!params.name) throw new Error('missing name');
+            apiClient.setClientName(params.name);
+            break;
+        case 'reload':
+            handleConfigurationLoaded(await apiClient.register());
+            setLocked(apiClient.locked);
+            break;
+        case 'disconnect':
+            showConnectionBanner('Disconnected by the server - open Settings and press Connect to reconnect', 'warning');
+            break;
+        default:
+            throw new Error(`unknown command ${command.name}`);
+    }
+}
+
+// Flash this client's name so it can be found from the server UI
+function showIdentify(name) {
+    const overlay = document.getElementById('identify-overlay');
+    document.getElementById('identify-name').textContent = name || 'This client';
+    overlay.classList.add('show');
+    setTimeout(() => overlay.classList.remove('show'), 3000);
+}
+
+// Lock or unlock the grid as set from the server UI
+function setLocked(locked) {
+    isLocked = locked;
+    document.getElementById('button-grid').classList.toggle('locked', locked);
+    document.getElementById('locked-badge').classList.toggle('show', locked);
+}
+
 // Render button grid
 function renderButtonGrid() {
     if (!currentConfiguration) {
@@ -178,7 +282,7 @@ function renderButton(button) {
   updateButtonIndicator(buttonEl);
 
   // Click handler
-  buttonEl.addEventListener('click', () => pressButton(`btn-${button.row}-${button.col}`, button.action));
+  buttonEl.addEventListener('click', () => pressButton(`btn-${button.row}-${button.col}`, button.action, button.id));
 
   grid.appendChild(buttonEl);
 }
@@ -280,7 +384,14 @@ function renderEmptyCell() {
 }
 
 // Press button
-async function pressButton(position, action) {
+async function pressButton(position, action, buttonID) {
+    if (isLocked) {
+        showConnectionBanner('This client is locked by the server', 'warning');
+        setTimeout(() => hideConnectionBanner(), 2000);
+        return;
+    }
+
+
     // Visual feedback
     const button = document.querySelector(`[data-position="${position}"]`);
     if (button) {
@@ -289,7 +400,10 @@ async function pressButton(position, action) {
     }
 
     try {
-        await apiClient.executeAction(action);
+        await apiClient.executeAction(action, buttonID);
+
+        // The server pushes the new status and state itself
+        if (apiClient.live) return;
         
         // Update status after toggle or scene actions
         if (isToggleAction(action.type) || action.type === 'switch_scene') {
@@ -312,6 +426,7 @@ async function pressButton(position, action) {
 // Start status polling
 function startStatusPolling() {
   setInterval(async () => {
+      if (apiClient && apiClient.live) return; // pushed by the server
       await updateStatusFromBackend();
       await updateSourceVisibility();
   }, 2000);
@@ -320,34 +435,37 @@ function startStatusPolling() {
 // Update status from backend
 async function updateStatusFromBackend() {
   try {
-      const status = await apiClient.getOBSStatus();
-      
-      // Track changes
-      const streamingChanged = obsStatus.streaming !== (status.streaming || false);
-      const recordingChanged = obsStatus.recording !== (status.recording || false);
-      const sceneChanged = obsStatus.currentScene !== (status.current_scene || '');
-      const virtualCamChanged = obsStatus.virtualCamActive !== (status.virtual_cam_active || false);
-      const replayBufferChanged = obsStatus.replayBufferActive !== (status.replay_buffer_active || false);
-      const studioModeChanged = obsStatus.studioModeActive !== (status.studio_mode_active || false);
-      
-      // Update state
-      obsStatus.streaming = status.streaming || false;
-      obsStatus.recording = status.recording || false;
-      obsStatus.currentScene = status.current_scene || '';
-      obsStatus.virtualCamActive = status.virtual_cam_active || false;
-      obsStatus.replayBufferActive = status.replay_buffer_active || false;
-      obsStatus.studioModeActive = status.studio_mode_active || false;
-      
-      // Update indicators if anything changed
-      if (streamingChanged || recordingChanged || sceneChanged || 
-          virtualCamChanged || replayBufferChanged || studioModeChanged) {
-          updateAllIndicators();
-      }
+      applyStatus(await apiClient.getOBSStatus());
   } catch (err) {
       console.error('Failed to get status:', err);
   }
 }
 
+// Apply OBS status, polled or pushed by the server
+function applyStatus(status) {
+  // Track changes
+  const streamingChanged = obsStatus.streaming !== (status.streaming || false);
+  const recordingChanged = obsStatus.recording !== (status.recording || false);
+  const sceneChanged = obsStatus.currentScene !== (status.current_scene || '');
+  const virtualCamChanged = obsStatus.virtualCamActive !== (status.virtual_cam_active || false);
+  const replayBufferChanged = obsStatus.replayBufferActive !== (status.replay_buffer_active || false);
+  const studioModeChanged = obsStatus.studioModeActive !== (status.studio_mode_active || false);
+  
+  // Update state
+  obsStatus.streaming = status.streaming || false;
+  obsStatus.recording = status.recording || false;
+  obsStatus.currentScene = status.current_scene || '';
+  obsStatus.virtualCamActive = status.virtual_cam_active || false;
+  obsStatus.replayBufferActive = status.replay_buffer_active || false;
+  obsStatus.studioModeActive = status.studio_mode_active || false;
+  
+  // Update indicators if anything changed
+  if (streamingChanged || recordingChanged || sceneChanged || 
+      virtualCamChanged || replayBufferChanged || studioModeChanged) {
+      updateAllIndicators();
+  }
+}
+
 // Update source visibility for all source buttons
 async function updateSourceVisibility() {
   const buttons = document.querySelectorAll('.deck-button');
@@ -383,6 +501,22 @@ async function updateSourceVisibility() {
   updateAllIndicators();
 }
 
+// Apply source visibility pushed by the server
+function applySourceState(sources) {
+  if (!currentConfiguration || !currentConfiguration.buttons) return;
+
+  for (const button of currentConfiguration.buttons) {
+      const params = (button.action && button.action.params) || {};
+      const source = (sources || []).find(s =>
+          s.scene_name === params.scene_name && s.source_name === params.source_name);
+      if (source) {
+          sourceVisibility[button.id] = source.visible;
+      }
+  }
+
+  updateAllIndicators();
+}
+
 // Open settings modal
 function openSettings() {
     document.getElementById('settings-modal').classList.add('open');
@@ -408,6 +542,7 @@ async function updateServerURL() {
     
     // Reconnect
     closeSettings();
+    apiClient.closeEvents();
     apiClient = new APIClient(url);
     await connectAndLoad();
 }
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"client/internal/client"
 	"client/internal/config"
@@ -24,8 +27,23 @@ type App struct {
 	logger        *logrus.Logger
 	serverURL     string
 	configDir     string
+
+	// locked is set by the server UI to ignore button presses
+	locked bool
+
+	// stopEvents stops listening to the server's event stream
+	stopEvents context.CancelFunc
 }
 
+// maxEventsRetryDelay caps the wait between event stream reconnects
+const maxEventsRetryDelay = 30 * time.Second
+
+// defaultServerURL is used until a server is chosen or found on the LAN
+const defaultServerURL = "http://localhost:8080"
+
+// discoveryTimeout is how long we browse the LAN for servers
+const discoveryTimeout = 3 * time.Second
+
 // getConfigDir returns the OS-appropriate config directory
 func getConfigDir() (string, error) {
 	var configDir string
@@ -58,12 +76,12 @@ func getConfigDir() (string, error) {
 	return configDir, nil
 }
 
-// loadServerURL loads the saved server URL
+// loadServerURL loads the saved server URL, or "" if none was chosen yet
 func loadServerURL(configDir string) string {
 	urlFile := filepath.Join(configDir, "server_url.txt")
 	data, err := os.ReadFile(urlFile)
 	if err != nil {
-		return "http://localhost:8080"
+		return ""
 	}
 	return string(data)
 }
@@ -92,13 +110,11 @@ func saveLastConfigID(configDir, configID string) error {
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-
 	configDir, err := getConfigDir()
 	if err != nil {
-		logger.Fatalf("Could not get config directory: %v", err)
+		logrus.Fatalf("Could not get config directory: %v", err)
 	}
+	logger := newLogger(configDir)
 
 	logger.Infof("Config directory: %s", configDir)
 
@@ -115,20 +131,51 @@ func (a *App) startup(ctx context.Context) {
 
 	a.logger.Infof("Robo-Stream Client starting...")
 	a.logger.Infof("Platform: %s/%s", runtime.GOOS, runtime.GOARCH)
-	a.logger.Infof("Server URL: %s", a.serverURL)
 
+	// Without a saved server, look for one on the LAN
+	if a.serverURL == "" {
+		a.serverURL = defaultServerURL
+		a.apiClient = client.NewAPIClient(a.serverURL, a.logger, a.configDir)
+		go a.findServer()
+		return
+	}
+
+	a.logger.Infof("Server URL: %s", a.serverURL)
 	a.apiClient = client.NewAPIClient(a.serverURL, a.logger, a.configDir)
 	go a.connectAndLoad()
 }
 
+// findServer connects to the only server on the LAN. With several, the
+// user picks one; with none, we try the default URL.
+func (a *App) findServer() {
+	servers, err := a.DiscoverServers()
+	switch {
+	case err == nil && len(servers) == 1:
+		a.logger.Infof("Found %s at %s", servers[0].Name, servers[0].URL)
+		a.SetServerURL(servers[0].URL)
+	case err == nil && len(servers) > 1:
+		a.logger.Infof("Found %d servers, asking which to use", len(servers))
+		wailsruntime.EventsEmit(a.ctx, "servers_found", servers)
+	default:
+		a.logger.Infof("No server found on the LAN, trying %s", a.serverURL)
+		a.connectAndLoad()
+	}
+}
+
 // shutdown is called when the app shuts down
 func (a *App) shutdown(ctx context.Context) {
 	a.logger.Info("Shutting down...")
+	if a.stopEvents != nil {
+		a.stopEvents()
+	}
 }
 
 // connectAndLoad connects to server and loads configuration
 func (a *App) connectAndLoad() {
-	info, err := a.apiClient.GetServerInfo()
+	info, err := a.apiClient.Negotiate()
+	if a.certificateChanged(err) {
+		return
+	}
 	if err != nil {
 		a.logger.Errorf("Failed to connect to server: %v", err)
 		wailsruntime.EventsEmit(a.ctx, "connection_error", err.Error())
@@ -138,9 +185,21 @@ func (a *App) connectAndLoad() {
 	a.logger.Infof("Connected to server: %v", info)
 	wailsruntime.EventsEmit(a.ctx, "connected", info)
 
+	// The server only talks to paired devices
+	if !a.apiClient.Paired() {
+		a.logger.Info("Not paired with this server yet")
+		wailsruntime.EventsEmit(a.ctx, "pairing_required")
+		return
+	}
+
 	// ALWAYS register to get a session ID - this is required for ExecuteAction
 	a.logger.Info("Registering with server to get session ID")
 	resolved, err := a.apiClient.Register()
+	if errors.Is(err, client.ErrPairingRequired) {
+		a.logger.Warn("Server rejected our token, pairing required")
+		wailsruntime.EventsEmit(a.ctx, "pairing_required")
+		return
+	}
 	if err != nil {
 		a.logger.Errorf("Failed to register with server: %v", err)
 		wailsruntime.EventsEmit(a.ctx, "config_error", err.Error())
@@ -161,6 +220,7 @@ func (a *App) connectAndLoad() {
 	}
 
 	a.configuration = resolved
+	a.setLocked(a.apiClient.Locked())
 
 	// Save this configuration ID for next time
 	if err := saveLastConfigID(a.configDir, resolved.ID); err != nil {
@@ -171,6 +231,174 @@ func (a *App) connectAndLoad() {
 		resolved.Name, resolved.Grid.Rows, resolved.Grid.Cols, len(resolved.Buttons))
 
 	wailsruntime.EventsEmit(a.ctx, "configuration_loaded", resolved)
+
+	// Follow edits made on the server from now on
+	if a.stopEvents != nil {
+		a.stopEvents()
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.stopEvents = cancel
+	go a.listenForEvents(ctx, a.apiClient)
+}
+
+// listenForEvents keeps the server's event stream open, reconnecting with
+// backoff until ctx is done
+func (a *App) listenForEvents(ctx context.Context, apiClient *client.APIClient) {
+	delay := time.Second
+	for {
+		err := apiClient.ListenEvents(ctx, a.handleServerEvent, a.handleCommand)
+		wailsruntime.EventsEmit(a.ctx, "live_updates", false)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, client.ErrDisconnected) {
+			a.logger.Warn("Disconnected by the server")
+			wailsruntime.EventsEmit(a.ctx, "disconnected")
+			return
+		}
+		if errors.Is(err, client.ErrPairingRequired) {
+			a.logger.Warn("Server rejected our token, pairing required")
+			wailsruntime.EventsEmit(a.ctx, "pairing_required")
+			return
+		}
+		if a.certificateChanged(err) {
+			return
+		}
+		a.logger.Warnf("%v, reconnecting in %s", err, delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay < maxEventsRetryDelay {
+			delay *= 2
+		}
+	}
+}
+
+// certificateChanged tells the frontend when err is a server certificate
+// that doesn't match the pinned one, so the user can decide whether to
+// trust it
+func (a *App) certificateChanged(err error) bool {
+	var changed *client.CertificateChangedError
+	if !errors.As(err, &changed) {
+		return false
+	}
+	wailsruntime.EventsEmit(a.ctx, "certificate_changed", map[string]string{
+		"server_url":  a.serverURL,
+		"pinned":      changed.Pinned,
+		"fingerprint": changed.Fingerprint,
+	})
+	return true
+}
+
+// handleServerEvent applies an event pushed by the server
+func (a *App) handleServerEvent(event client.Event) {
+	switch event.Type {
+	case "config_updated":
+		var resolved config.ResolvedConfiguration
+		if err := json.Unmarshal(event.Data, &resolved); err != nil {
+			a.logger.Errorf("Invalid configuration pushed by server: %v", err)
+			return
+		}
+		a.configuration = &resolved
+
+		if err := saveLastConfigID(a.configDir, resolved.ID); err != nil {
+			a.logger.Warnf("Failed to save last config ID: %v", err)
+		}
+
+		a.logger.Infof("Server updated configuration: %s", resolved.Name)
+		wailsruntime.EventsEmit(a.ctx, "configuration_loaded", &resolved)
+
+	case "subscribed":
+		// Status and state are pushed from now on, so the UI stops polling
+		wailsruntime.EventsEmit(a.ctx, "live_updates", true)
+
+	case client.TopicStatus:
+		var status map[string]interface{}
+		if err := json.Unmarshal(event.Data, &status); err != nil {
+			a.logger.Errorf("Invalid status pushed by server: %v", err)
+			return
+		}
+		wailsruntime.EventsEmit(a.ctx, "status_update", status)
+
+	case client.TopicState:
+		var state struct {
+			Sources []map[string]interface{} `json:"sources"`
+		}
+		if err := json.Unmarshal(event.Data, &state); err != nil {
+			a.logger.Errorf("Invalid state pushed by server: %v", err)
+			return
+		}
+		wailsruntime.EventsEmit(a.ctx, "source_state", state.Sources)
+	}
+}
+
+// handleCommand runs a command sent from the server UI; the returned error is
+// reported back to it
+func (a *App) handleCommand(cmd client.Command) error {
+	a.logger.Infof("Server sent command: %s", cmd.Name)
+
+	switch cmd.Name {
+	case client.CommandSwitchConfig:
+		configID, _ := cmd.Params["config_id"].(string)
+		if configID == "" {
+			return fmt.Errorf("missing config_id")
+		}
+		return a.LoadConfiguration(configID)
+
+	case client.CommandIdentify:
+		name, _ := cmd.Params["name"].(string)
+		wailsruntime.EventsEmit(a.ctx, "identify", name)
+		return nil
+
+	case client.CommandLock:
+		locked, _ := cmd.Params["locked"].(bool)
+		a.setLocked(locked)
+		return nil
+
+	case client.CommandRename:
+		name, _ := cmd.Params["name"].(string)
+		if name == "" {
+			return fmt.Errorf("missing name")
+		}
+		return a.apiClient.SetClientName(name)
+
+	case client.CommandReload:
+		return a.reloadConfiguration()
+
+	case client.CommandDisconnect:
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Name)
+	}
+}
+
+// setLocked locks or unlocks the grid
+func (a *App) setLocked(locked bool) {
+	a.locked = locked
+	wailsruntime.EventsEmit(a.ctx, "locked", locked)
+}
+
+// reloadConfiguration registers again and shows the configuration the server
+// has for this session
+func (a *App) reloadConfiguration() error {
+	resolved, err := a.apiClient.Register()
+	if err != nil {
+		return err
+	}
+	a.configuration = resolved
+	a.setLocked(a.apiClient.Locked())
+
+	if err := saveLastConfigID(a.configDir, resolved.ID); err != nil {
+		a.logger.Warnf("Failed to save last config ID: %v", err)
+	}
+
+	a.logger.Infof("Reloaded configuration: %s", resolved.Name)
+	wailsruntime.EventsEmit(a.ctx, "configuration_loaded", resolved)
+	return nil
 }
 
 // GetConfiguration returns the current configuration
@@ -195,7 +423,7 @@ func (a *App) LoadConfiguration(configID string) error {
 		a.logger.Errorf("Failed to load configuration: %v", err)
 
 		// If config was deleted, fallback to default
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, client.ErrConfigurationNotFound) {
 			a.logger.Warn("Configuration not found (may have been deleted), loading default")
 
 			// Get default configuration
@@ -239,6 +467,9 @@ func (a *App) PressButton(position string) error {
 	if a.configuration == nil {
 		return fmt.Errorf("no configuration loaded")
 	}
+	if a.locked {
+		return fmt.Errorf("this client is locked")
+	}
 
 	// Parse position string "btn-0-0" to row and col
 	parts := strings.Split(position, "-")
@@ -264,13 +495,16 @@ func (a *App) PressButton(position string) error {
 
 	// a.logger.Infof("Button pressed: %s (action: %s)", button.Text, button.Action.Type)
 
-	err = a.apiClient.ExecuteAction(button.Action)
+	err = a.apiClient.ExecuteAction(button.ID, button.Action)
 	if err != nil {
 		a.logger.Errorf("Failed to execute action: %v", err)
 		return err
 	}
 
-	go a.emitStatusUpdate()
+	// Status is pushed over the live connection
+	if !a.apiClient.Live() {
+		go a.emitStatusUpdate()
+	}
 	return nil
 }
 
@@ -302,6 +536,12 @@ func (a *App) GetOBSStatus() (map[string]interface{}, error) {
 	return status, nil
 }
 
+// GetButtonStates gets the state of every stateful button in the current
+// configuration at once
+func (a *App) GetButtonStates() ([]client.ButtonState, error) {
+	return a.apiClient.GetButtonStates()
+}
+
 // GetSourceVisibility checks if a source is currently visible
 func (a *App) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
 	// a.logger.Infof("Checking visibility: scene=%s, source=%s", sceneName, sourceName)
@@ -316,6 +556,9 @@ func (a *App) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
 
 // SetServerURL sets a new server URL and reconnects
 func (a *App) SetServerURL(url string) error {
+	if a.stopEvents != nil {
+		a.stopEvents()
+	}
 	a.serverURL = url
 	a.apiClient = client.NewAPIClient(url, a.logger, a.configDir)
 
@@ -328,6 +571,43 @@ func (a *App) SetServerURL(url string) error {
 	return nil
 }
 
+// PairWithServer redeems a pairing code shown in the server UI and connects
+func (a *App) PairWithServer(code string) error {
+	if err := a.apiClient.Pair(strings.TrimSpace(code)); err != nil {
+		a.logger.Errorf("Pairing failed: %v", err)
+		return err
+	}
+	go a.connectAndLoad()
+	return nil
+}
+
+// GetServerFingerprint returns the fingerprint of the server certificate we
+// trust, to compare with the one shown in the server UI
+func (a *App) GetServerFingerprint() string {
+	return a.apiClient.PinnedFingerprint()
+}
+
+// TrustServerCertificate trusts a changed server certificate and reconnects
+func (a *App) TrustServerCertificate(fingerprint string) error {
+	if err := a.apiClient.TrustCertificate(fingerprint); err != nil {
+		return err
+	}
+	a.logger.Warnf("Now trusting server certificate %s", fingerprint)
+	go a.connectAndLoad()
+	return nil
+}
+
+// DiscoverServers browses the LAN for servers
+func (a *App) DiscoverServers() ([]client.DiscoveredServer, error) {
+	servers, err := client.Discover(a.ctx, discoveryTimeout)
+	if err != nil {
+		a.logger.Warnf("Server discovery failed: %v", err)
+		return nil, err
+	}
+	a.logger.Debugf("Discovered %d servers", len(servers))
+	return servers, nil
+}
+
 // Reconnect attempts to reconnect to the server
 func (a *App) Reconnect() error {
 	go a.connectAndLoad()
@@ -7,9 +7,12 @@ let obsStatus = {
   currentScene: '',
   virtualCamActive: false,
   replayBufferActive: false,
-  studioModeActive: false
+  studioModeActive: false,
+  version: null
 };
 let sourceVisibility = {};
+// Status and source state are pushed by the server while this is set
+let liveUpdates = false;
 
 // Initialize app when DOM is loaded
 document.addEventListener('DOMContentLoaded', () => {
@@ -54,6 +57,8 @@ function setupEventListeners() {
     document.getElementById('btn-settings').addEventListener('click', openSettings);
     document.getElementById('btn-close-settings-modal').addEventListener('click', closeSettings);
     document.getElementById('btn-update-server').addEventListener('click', updateServerURL);
+    document.getElementById('btn-find-servers').addEventListener('click', findServers);
+    document.getElementById('btn-pair').addEventListener('click', pairWithServer);
 
     // Config selector
     document.getElementById('btn-select-config').addEventListener('click', openConfigSelector);
@@ -69,6 +74,34 @@ function setupEventListeners() {
     window.runtime.EventsOn('connection_error', handleConnectionError);
     window.runtime.EventsOn('configuration_loaded', handleConfigurationLoaded);
     window.runtime.EventsOn('config_error', handleConfigError);
+    window.runtime.EventsOn('pairing_required', handlePairingRequired);
+    window.runtime.EventsOn('certificate_changed', handleCertificateChanged);
+    window.runtime.EventsOn('servers_found', handleServersFound);
+    window.runtime.EventsOn('identify', handleIdentify);
+    window.runtime.EventsOn('locked', handleLocked);
+    window.runtime.EventsOn('disconnected', handleDisconnected);
+    window.runtime.EventsOn('live_updates', (live) => { liveUpdates = live; });
+    window.runtime.EventsOn('status_update', applyStatus);
+    window.runtime.EventsOn('source_state', applySourceState);
+}
+
+// Flash this client's name so it can be found from the server UI
+function handleIdentify(name) {
+    const overlay = document.getElementById('identify-overlay');
+    document.getElementById('identify-name').textContent = name || 'This client';
+    overlay.classList.add('show');
+    setTimeout(() => overlay.classList.remove('show'), 3000);
+}
+
+// Lock or unlock the grid as set from the server UI
+function handleLocked(locked) {
+    document.getElementById('button-grid').classList.toggle('locked', locked);
+    document.getElementById('locked-badge').classList.toggle('show', locked);
+}
+
+// Handle being disconnected from the server UI
+function handleDisconnected() {
+    showConnectionBanner('Disconnected by the server - open Settings and press Connect to reconnect', 'warning');
 }
 
 // Handle connected event
@@ -97,6 +130,41 @@ function handleConfigurationLoaded(config) {
     setTimeout(() => hideConnectionBanner(), 2000);
 }
 
+// Handle pairing required
+function handlePairingRequired() {
+    console.log('Pairing required');
+    showConnectionBanner('Pair this client: enter the code shown on the server under Clients → Pair Device', 'error');
+    openSettings();
+}
+
+// Handle a server certificate that doesn't match the one we trusted before
+async function handleCertificateChanged(info) {
+    console.warn('Server certificate changed:', info);
+    showConnectionBanner('Warning: the server\'s certificate changed. Check its fingerprint on the server dashboard.', 'error');
+
+    const trust = confirm(
+        `The certificate of ${info.server_url} changed.\n\n` +
+        `Trusted: ${info.pinned}\n` +
+        `Now: ${info.fingerprint}\n\n` +
+        'Only continue if the new fingerprint matches the one on the server dashboard. ' +
+        'Otherwise someone may be intercepting the connection.\n\nTrust the new certificate?'
+    );
+    if (!trust) return;
+
+    try {
+        await window.go.main.App.TrustServerCertificate(info.fingerprint);
+    } catch (err) {
+        alert('Error: ' + err);
+    }
+}
+
+// Handle several servers found on the network at first start
+function handleServersFound(servers) {
+    showConnectionBanner('Several servers found, choose one in the settings', 'error');
+    openSettings();
+    showServers(servers);
+}
+
 // Handle config error
 function handleConfigError(error) {
     console.error('Config error:', error);
@@ -333,6 +401,11 @@ async function pressButton(position, actionType) {
     try {
         await window.go.main.App.PressButton(position);
         
+        // The server pushes the new status and state itself
+        if (liveUpdates) {
+            return;
+        }
+
         // Update indicators immediately after pressing toggle or scene buttons
         if (isToggleAction(actionType) || actionType === 'switch_scene') {
             setTimeout(() => updateStatusFromBackend(), 100);
@@ -355,6 +428,9 @@ async function pressButton(position, actionType) {
 function startStatusPolling() {
   // Poll every 2 seconds
   setInterval(async () => {
+      if (liveUpdates) {
+          return;
+      }
       await updateStatusFromBackend();
       await updateSourceVisibility();  // ← ADD THIS
   }, 2000);
@@ -363,66 +439,78 @@ function startStatusPolling() {
 // Update status from backend
 async function updateStatusFromBackend() {
   try {
-      const status = await window.go.main.App.GetOBSStatus();
-      
-      // Track what changed
-      const streamingChanged = obsStatus.streaming !== (status.streaming || false);
-      const recordingChanged = obsStatus.recording !== (status.recording || false);
-      const sceneChanged = obsStatus.currentScene !== (status.current_scene || '');
-      const virtualCamChanged = obsStatus.virtualCamActive !== (status.virtual_cam_active || false);        // ← ADD
-      const replayBufferChanged = obsStatus.replayBufferActive !== (status.replay_buffer_active || false); // ← ADD
-      const studioModeChanged = obsStatus.studioModeActive !== (status.studio_mode_active || false);       // ← ADD
-      
-      // Update state
-      obsStatus.streaming = status.streaming || false;
-      obsStatus.recording = status.recording || false;
-      obsStatus.currentScene = status.current_scene || '';
-      obsStatus.virtualCamActive = status.virtual_cam_active || false;        // ← ADD
-      obsStatus.replayBufferActive = status.replay_buffer_active || false;    // ← ADD
-      obsStatus.studioModeActive = status.studio_mode_active || false;        // ← ADD
-      
-      // Debug log ALL status updates to see what we're getting
-      console.log('Status update:', {
-          streaming: obsStatus.streaming,
-          recording: obsStatus.recording,
-          currentScene: obsStatus.currentScene,
-          virtualCamActive: obsStatus.virtualCamActive,        // ← ADD
-          replayBufferActive: obsStatus.replayBufferActive,    // ← ADD
-          studioModeActive: obsStatus.studioModeActive         // ← ADD
-      });
-      
-      // Debug log significant changes
-      if (streamingChanged) {
-          console.log('Streaming state changed:', obsStatus.streaming);
-      }
-      if (recordingChanged) {
-          console.log('Recording state changed:', obsStatus.recording);
-      }
-      if (sceneChanged) {
-          console.log('Scene changed:', obsStatus.currentScene);
-      }
-      if (virtualCamChanged) {                                                // ← ADD
-          console.log('Virtual Camera changed:', obsStatus.virtualCamActive);
-      }
-      if (replayBufferChanged) {                                              // ← ADD
-          console.log('Replay Buffer changed:', obsStatus.replayBufferActive);
-      }
-      if (studioModeChanged) {                                                // ← ADD
-          console.log('Studio Mode changed:', obsStatus.studioModeActive);
-      }
-      
-      // Update all indicators if anything changed
-      if (streamingChanged || recordingChanged || sceneChanged || 
-          virtualCamChanged || replayBufferChanged || studioModeChanged) {    // ← ADD
-          updateAllIndicators();
-      }
+      applyStatus(await window.go.main.App.GetOBSStatus());
   } catch (err) {
       console.error('Failed to get status:', err);
   }
 }
 
+// Apply OBS status, polled or pushed by the server
+function applyStatus(status) {
+  // Newer servers number their status; skip snapshots we've seen
+  if (status.version !== undefined && status.version === obsStatus.version) {
+      return;
+  }
+  obsStatus.version = status.version;
+
+  // Track what changed
+  const streamingChanged = obsStatus.streaming !== (status.streaming || false);
+  const recordingChanged = obsStatus.recording !== (status.recording || false);
+  const sceneChanged = obsStatus.currentScene !== (status.current_scene || '');
+  const virtualCamChanged = obsStatus.virtualCamActive !== (status.virtual_cam_active || false);        // ← ADD
+  const replayBufferChanged = obsStatus.replayBufferActive !== (status.replay_buffer_active || false); // ← ADD
+  const studioModeChanged = obsStatus.studioModeActive !== (status.studio_mode_active || false);       // ← ADD
+  
+  // Update state
+  obsStatus.streaming = status.streaming || false;
+  obsStatus.recording = status.recording || false;
+  obsStatus.currentScene = status.current_scene || '';
+  obsStatus.virtualCamActive = status.virtual_cam_active || false;        // ← ADD
+  obsStatus.replayBufferActive = status.replay_buffer_active || false;    // ← ADD
+  obsStatus.studioModeActive = status.studio_mode_active || false;        // ← ADD
+  
+  // Debug log ALL status updates to see what we're getting
+  console.log('Status update:', {
+      streaming: obsStatus.streaming,
+      recording: obsStatus.recording,
+      currentScene: obsStatus.currentScene,
+      virtualCamActive: obsStatus.virtualCamActive,        // ← ADD
+      replayBufferActive: obsStatus.replayBufferActive,    // ← ADD
+      studioModeActive: obsStatus.studioModeActive         // ← ADD
+  });
+  
+  // Debug log significant changes
+  if (streamingChanged) {
+      console.log('Streaming state changed:', obsStatus.streaming);
+  }
+  if (recordingChanged) {
+      console.log('Recording state changed:', obsStatus.recording);
+  }
+  if (sceneChanged) {
+      console.log('Scene changed:', obsStatus.currentScene);
+  }
+  if (virtualCamChanged) {                                                // ← ADD
+      console.log('Virtual Camera changed:', obsStatus.virtualCamActive);
+  }
+  if (replayBufferChanged) {                                              // ← ADD
+      console.log('Replay Buffer changed:', obsStatus.replayBufferActive);
+  }
+  if (studioModeChanged) {                                                // ← ADD
+      console.log('Studio Mode changed:', obsStatus.studioModeActive);
+  }
+  
+  // Update all indicators if anything changed
+  if (streamingChanged || recordingChanged || sceneChanged || 
+      virtualCamChanged || replayBufferChanged || studioModeChanged) {    // ← ADD
+      updateAllIndicators();
+  }
+}
+
 // Open settings modal
-function openSettings() {
+async function openSettings() {
+    const fingerprint = await window.go.main.App.GetServerFingerprint();
+    document.getElementById('server-fingerprint-group').style.display = fingerprint ? '' : 'none';
+    document.getElementById('server-fingerprint').textContent = fingerprint;
     document.getElementById('settings-modal').classList.add('open');
     setTimeout(() => lucide.createIcons(), 100);
 }
@@ -432,6 +520,70 @@ function closeSettings() {
     document.getElementById('settings-modal').classList.remove('open');
 }
 
+// Browse the network for servers and list them in the settings
+async function findServers() {
+    const list = document.getElementById('server-list');
+    list.innerHTML = '<div class="config-item-description">Searching...</div>';
+    try {
+        showServers(await window.go.main.App.DiscoverServers());
+    } catch (err) {
+        console.error('Failed to search for servers:', err);
+        list.innerHTML = '';
+        const message = document.createElement('div');
+        message.className = 'config-item-description';
+        message.textContent = 'Search failed: ' + err;
+        list.appendChild(message);
+    }
+}
+
+// List servers found on the network; picking one connects to it
+function showServers(servers) {
+    const list = document.getElementById('server-list');
+    list.innerHTML = '';
+
+    if (!servers || servers.length === 0) {
+        list.innerHTML = '<div class="config-item-description">No servers found, enter the URL below</div>';
+        return;
+    }
+
+    servers.forEach(server => {
+        const item = document.createElement('div');
+        item.className = 'config-item';
+
+        const header = document.createElement('div');
+        header.className = 'config-item-header';
+        const name = document.createElement('span');
+        name.className = 'config-item-name';
+        name.textContent = server.name;
+        header.appendChild(name);
+        if (server.tls) {
+            const badge = document.createElement('span');
+            badge.className = 'config-badge';
+            badge.textContent = 'HTTPS';
+            header.appendChild(badge);
+        }
+        item.appendChild(header);
+
+        const url = document.createElement('div');
+        url.className = 'config-item-description';
+        url.textContent = server.version ? `${server.url} · v${server.version}` : server.url;
+        item.appendChild(url);
+
+        if (server.fingerprint) {
+            const fingerprint = document.createElement('code');
+            fingerprint.className = 'fingerprint';
+            fingerprint.textContent = server.fingerprint;
+            item.appendChild(fingerprint);
+        }
+
+        item.addEventListener('click', () => {
+            document.getElementById('input-server-url').value = server.url;
+            updateServerURL();
+        });
+        list.appendChild(item);
+    });
+}
+
 // Update server URL
 async function updateServerURL() {
     const url = document.getElementById('input-server-url').value.trim();
@@ -454,6 +606,26 @@ async function updateServerURL() {
     }
 }
 
+// Pair with the server using the code shown in its UI
+async function pairWithServer() {
+    const code = document.getElementById('input-pairing-code').value.trim();
+
+    if (!code) {
+        alert('Please enter the pairing code shown on the server');
+        return;
+    }
+
+    try {
+        await window.go.main.App.PairWithServer(code);
+        document.getElementById('input-pairing-code').value = '';
+        closeSettings();
+        showConnectionBanner('Paired, connecting...', 'connecting');
+    } catch (err) {
+        console.error('Failed to pair:', err);
+        alert('Pairing failed: ' + err);
+    }
+}
+
 // Open configuration selector
 async function openConfigSelector() {
     try {
@@ -539,6 +711,26 @@ async function updateSourceVisibility() {
   // console.log('🔍 Checking source visibility...');
   
   const buttons = document.querySelectorAll('.deck-button');
+
+  // Newer servers answer for every button at once
+  try {
+      const states = await window.go.main.App.GetButtonStates();
+      const visible = Object.fromEntries((states || []).map(state => [state.id, state.active]));
+      for (const buttonEl of buttons) {
+          const actionType = buttonEl.dataset.actionType;
+          if (actionType === 'toggle_source_visibility' ||
+              actionType === 'show_source' ||
+              actionType === 'hide_source') {
+              const buttonId = buttonEl.dataset.buttonId;
+              sourceVisibility[buttonId] = visible[buttonId] || false;
+              buttonEl.dataset.sourceVisible = sourceVisibility[buttonId] ? 'true' : 'false';
+          }
+      }
+      updateAllIndicators();
+      return;
+  } catch (err) {
+      // Older server: ask per button below
+  }
   
   for (const buttonEl of buttons) {
       const actionType = buttonEl.dataset.actionType;
@@ -576,3 +768,28 @@ async function updateSourceVisibility() {
   // console.log('📊 Source visibility version:', sourceVisibilityVersion);
   updateAllIndicators();
 }
+
+// Apply source visibility pushed by the server
+function applySourceState(sources) {
+  const buttons = document.querySelectorAll('.deck-button');
+
+  for (const buttonEl of buttons) {
+      const buttonId = buttonEl.dataset.buttonId;
+      const button = currentConfiguration && currentConfiguration.buttons
+          ? currentConfiguration.buttons.find(b => b.id === buttonId)
+          : null;
+      if (!button || !button.action || !button.action.params) {
+          continue;
+      }
+
+      const params = button.action.params;
+      const source = (sources || []).find(s =>
+          s.scene_name === params.scene_name && s.source_name === params.source_name);
+      if (source) {
+          sourceVisibility[buttonId] = source.visible;
+          buttonEl.dataset.sourceVisible = source.visible ? 'true' : 'false';
+      }
+  }
+
+  updateAllIndicators();
+}
@@ -1,7 +1,12 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {client} from '../models';
 import {config} from '../models';
 
+export function DiscoverServers():Promise<Array<client.DiscoveredServer>>;
+
+export function GetButtonStates():Promise<Array<client.ButtonState>>;
+
 export function GetConfiguration():Promise<config.ResolvedConfiguration>;
 
 export function GetConfigurations():Promise<Array<config.Configuration>>;
@@ -10,6 +15,8 @@ export function GetCurrentConfiguration():Promise<config.ResolvedConfiguration>;
 
 export function GetOBSStatus():Promise<Record<string, any>>;
 
+export function GetServerFingerprint():Promise<string>;
+
 export function GetServerURL():Promise<string>;
 
 export function GetSourceVisibility(arg1:string,arg2:string):Promise<boolean>;
@@ -18,6 +25,8 @@ export function GetStatus():Promise<Record<string, any>>;
 
 export function LoadConfiguration(arg1:string):Promise<void>;
 
+export function PairWithServer(arg1:string):Promise<void>;
+
 export function PressButton(arg1:string):Promise<void>;
 
 export function Reconnect():Promise<void>;
@@ -25,3 +34,5 @@ export function Reconnect():Promise<void>;
 export function SetServerURL(arg1:string):Promise<void>;
 
 export function ToggleFullscreen():Promise<void>;
+
+export function TrustServerCertificate(arg1:string):Promise<void>;
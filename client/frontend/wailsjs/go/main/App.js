@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function DiscoverServers() {
+  return window['go']['main']['App']['DiscoverServers']();
+}
+
+export function GetButtonStates() {
+  return window['go']['main']['App']['GetButtonStates']();
+}
+
 export function GetConfiguration() {
   return window['go']['main']['App']['GetConfiguration']();
 }
@@ -18,6 +26,10 @@ export function GetOBSStatus() {
   return window['go']['main']['App']['GetOBSStatus']();
 }
 
+export function GetServerFingerprint() {
+  return window['go']['main']['App']['GetServerFingerprint']();
+}
+
 export function GetServerURL() {
   return window['go']['main']['App']['GetServerURL']();
 }
@@ -34,6 +46,10 @@ export function LoadConfiguration(arg1) {
   return window['go']['main']['App']['LoadConfiguration'](arg1);
 }
 
+export function PairWithServer(arg1) {
+  return window['go']['main']['App']['PairWithServer'](arg1);
+}
+
 export function PressButton(arg1) {
   return window['go']['main']['App']['PressButton'](arg1);
 }
@@ -49,3 +65,7 @@ export function SetServerURL(arg1) {
 export function ToggleFullscreen() {
   return window['go']['main']['App']['ToggleFullscreen']();
 }
+
+export function TrustServerCertificate(arg1) {
+  return window['go']['main']['App']['TrustServerCertificate'](arg1);
+}
@@ -1,3 +1,44 @@
+export namespace client {
+	
+	export class ButtonState {
+	    id: string;
+	    action_type: string;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ButtonState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.action_type = source["action_type"];
+	        this.active = source["active"];
+	    }
+	}
+	export class DiscoveredServer {
+	    name: string;
+	    url: string;
+	    version: string;
+	    tls: boolean;
+	    fingerprint?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiscoveredServer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.version = source["version"];
+	        this.tls = source["tls"];
+	        this.fingerprint = source["fingerprint"];
+	    }
+	}
+
+}
+
 export namespace config {
 	
 	export class ButtonAction {
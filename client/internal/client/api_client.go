@@ -3,25 +3,76 @@ package client
 import (
 	"bytes"
 	"client/internal/config"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	neturl "net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
+// tokenFile stores the token issued when pairing with a server
+const tokenFile = "token.json"
+
+// nameFile stores the name the server UI gave this client
+const nameFile = "client_name.txt"
+
+// defaultClientName is used until the server UI renames the client
+const defaultClientName = "Wails Desktop Client"
+
 // APIClient handles communication with YOUR actual robo-stream server
 type APIClient struct {
 	serverURL  string
 	sessionID  string
 	clientID   string
+	clientName string
+	locked     bool
+	token      string
+	configDir  string
 	httpClient *http.Client
+	dialer     *websocket.Dialer
 	logger     *logrus.Logger
+
+	// pinned is the fingerprint of the server certificate we trust
+	pinMu  sync.Mutex
+	pinned string
+
+	// server and apiPrefix are set by Negotiate
+	server    *ServerInfo
+	apiPrefix string
+
+	// live is the WebSocket connection held by ListenEvents, if any, and
+	// limits the action time limits the server announced on it
+	liveMu sync.Mutex
+	live   *liveConn
+	limits *authResult
+}
+
+// savedToken is the content of the token file
+type savedToken struct {
+	ServerURL string `json:"server_url"`
+	Token     string `json:"token"`
+}
+
+// loadToken returns the token saved for a server, if any
+func loadToken(configDir, serverURL string) string {
+	data, err := os.ReadFile(filepath.Join(configDir, tokenFile))
+	if err != nil {
+		return ""
+	}
+	var saved savedToken
+	if err := json.Unmarshal(data, &saved); err != nil || saved.ServerURL != serverURL {
+		return ""
+	}
+	return saved.Token
 }
 
 // loadClientID loads or creates a persistent client ID
@@ -47,21 +98,40 @@ func loadClientID(configDir string) string {
 	return clientID
 }
 
+// loadClientName returns the saved client name, or the default
+func loadClientName(configDir string) string {
+	data, err := os.ReadFile(filepath.Join(configDir, nameFile))
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return defaultClientName
+	}
+	return string(bytes.TrimSpace(data))
+}
+
 // NewAPIClient creates a new API client
 func NewAPIClient(serverURL string, logger *logrus.Logger, configDir string) *APIClient {
-	return &APIClient{
-		serverURL: serverURL,
-		clientID:  loadClientID(configDir),
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-			// Transport: &http.Transport{
-			// 	TLSClientConfig: &tls.Config{
-			// 		InsecureSkipVerify: true, // Skip cert verification for dev
-			// 	},
-			// },
-		},
-		logger: logger,
-	}
+	c := &APIClient{
+		serverURL:  serverURL,
+		clientID:   loadClientID(configDir),
+		clientName: loadClientName(configDir),
+		token:      loadToken(configDir, serverURL),
+		configDir:  configDir,
+		pinned:     loadPins(configDir)[serverURL],
+		logger:     logger,
+	}
+
+	// Servers with self-signed certificates are pinned, see tlsConfig
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.tlsConfig()
+	c.httpClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+	c.dialer = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		TLSClientConfig:  c.tlsConfig(),
+	}
+	return c
 }
 
 // RegisterResponse from server
@@ -69,38 +139,113 @@ type RegisterResponse struct {
 	SessionID string                       `json:"session_id"`
 	ConfigID  string                       `json:"config_id"`
 	Config    config.ResolvedConfiguration `json:"config"`
+	Locked    bool                         `json:"locked"`
+}
+
+// Paired reports whether we hold a token for the server
+func (c *APIClient) Paired() bool {
+	return c.token != ""
 }
 
-// GetServerInfo gets server information
-func (c *APIClient) GetServerInfo() (map[string]interface{}, error) {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/api/health", c.serverURL))
+// Pair redeems a pairing code shown in the server UI and saves the token
+func (c *APIClient) Pair(code string) error {
+	hostname, _ := os.Hostname()
+	jsonData, err := json.Marshal(map[string]string{
+		"code":        code,
+		"client_id":   c.clientID,
+		"client_name": fmt.Sprintf("Desktop Client (%s)", hostname),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get server info: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(
+		c.serverURL+c.apiPath("/pair"),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to pair: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status: %d", resp.StatusCode)
+		return responseError(resp)
 	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var pairResp struct {
+		DeviceID string `json:"device_id"`
+		Token    string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &pairResp); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	data, err := json.Marshal(savedToken{ServerURL: c.serverURL, Token: pairResp.Token})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.configDir, tokenFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
 	}
 
-	var info map[string]interface{}
-	if err := json.Unmarshal(body, &info); err != nil {
-		return nil, fmt.Errorf("failed to parse server info: %w", err)
+	c.token = pairResp.Token
+	c.logger.Infof("Paired with server as device %s", pairResp.DeviceID)
+	return nil
+}
+
+// newRequest creates a request to the server carrying our token
+func (c *APIClient) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.serverURL+c.apiPath(path), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.sessionID != "" {
+		req.Header.Set("X-Session-ID", c.sessionID)
+	}
+	return req, nil
+}
+
+// do sends a request, turning a rejected token into an error matching
+// ErrPairingRequired
+func (c *APIClient) do(req *http.Request) (*http.Response, error) {
+	return c.send(c.httpClient, req)
+}
+
+// send is do with another HTTP client
+func (c *APIClient) send(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
 	}
+	return resp, nil
+}
 
-	return info, nil
+// get sends an authenticated GET request
+func (c *APIClient) get(path string) (*http.Response, error) {
+	req, err := c.newRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
 }
 
 // Register registers this client with the server and gets a session
 func (c *APIClient) Register() (*config.ResolvedConfiguration, error) {
 	reqBody := map[string]string{
 		"client_id":   c.clientID,
-		"client_name": "Wails Desktop Client",
+		"client_name": c.clientName,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -108,19 +253,20 @@ func (c *APIClient) Register() (*config.ResolvedConfiguration, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/api/client/register", c.serverURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	req, err := c.newRequest("POST", "/client/register", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -135,6 +281,7 @@ func (c *APIClient) Register() (*config.ResolvedConfiguration, error) {
 
 	// Store session ID for future requests
 	c.sessionID = regResp.SessionID
+	c.locked = regResp.Locked
 
 	c.logger.Infof("Registered with server - Session: %s, Config: %s",
 		regResp.SessionID, regResp.ConfigID)
@@ -142,16 +289,29 @@ func (c *APIClient) Register() (*config.ResolvedConfiguration, error) {
 	return &regResp.Config, nil
 }
 
+// Locked reports whether the server locked this client's grid when we
+// registered
+func (c *APIClient) Locked() bool {
+	return c.locked
+}
+
+// SetClientName saves the name the server UI gave this client, used from the
+// next registration on
+func (c *APIClient) SetClientName(name string) error {
+	c.clientName = name
+	return os.WriteFile(filepath.Join(c.configDir, nameFile), []byte(name), 0644)
+}
+
 // GetConfigurations gets all available configurations
 func (c *APIClient) GetConfigurations() ([]config.Configuration, error) {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/api/configurations", c.serverURL))
+	resp, err := c.get("/configurations")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get configurations: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status: %d", resp.StatusCode)
+		return nil, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -171,23 +331,19 @@ func (c *APIClient) GetConfigurations() ([]config.Configuration, error) {
 func (c *APIClient) GetConfiguration(configID string) (*config.ResolvedConfiguration, error) {
 	// First switch to this config in our session
 	if c.sessionID != "" {
-		req, err := http.NewRequest("PUT",
-			fmt.Sprintf("%s/api/client/config/%s", c.serverURL, configID),
-			nil)
+		req, err := c.newRequest("PUT", "/client/config/"+configID, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+			return nil, err
 		}
-		req.Header.Set("X-Session-ID", c.sessionID)
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := c.do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to switch config: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+			return nil, responseError(resp)
 		}
 
 		body, err := io.ReadAll(resp.Body)
@@ -204,15 +360,14 @@ func (c *APIClient) GetConfiguration(configID string) (*config.ResolvedConfigura
 	}
 
 	// No session yet, just fetch it
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/api/configurations/%s", c.serverURL, configID))
+	resp, err := c.get("/configurations/" + configID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get configuration: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -236,14 +391,14 @@ func (c *APIClient) GetDefaultConfiguration() (*config.ResolvedConfiguration, er
 	}
 
 	// Otherwise fetch default
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/api/configurations/default", c.serverURL))
+	resp, err := c.get("/configurations/default")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default configuration: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status: %d", resp.StatusCode)
+		return nil, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -259,38 +414,61 @@ func (c *APIClient) GetDefaultConfiguration() (*config.ResolvedConfiguration, er
 	return &resolved, nil
 }
 
-// ExecuteAction sends an action request to the server with session header
-func (c *APIClient) ExecuteAction(action config.ButtonAction) error {
+// actionRequest is an action plus the button that triggered it, which the
+// server uses to check the session's role
+type actionRequest struct {
+	config.ButtonAction
+	ButtonID string `json:"button_id,omitempty"`
+}
+
+// ExecuteAction sends an action request to the server, over the live
+// connection when there is one and otherwise over HTTP
+func (c *APIClient) ExecuteAction(buttonID string, action config.ButtonAction) error {
 	if c.sessionID == "" {
 		return fmt.Errorf("not registered - no session ID")
 	}
 
+	c.logger.Debugf("Executing action: %s with params: %v", action.Type, action.Params)
+
+	req := actionRequest{ButtonAction: action, ButtonID: buttonID}
+	result, err := c.liveRequest("action", req, c.actionWait(action.Type, actionTimeout))
+	if err == nil {
+		return eventError(result)
+	}
+	if !errors.Is(err, errNotLive) {
+		return err
+	}
+
+	return c.executeActionHTTP(req)
+}
+
+// executeActionHTTP sends an action request to the server with session header
+func (c *APIClient) executeActionHTTP(action actionRequest) error {
 	jsonData, err := json.Marshal(action)
 	if err != nil {
 		return fmt.Errorf("failed to marshal action: %w", err)
 	}
 
-	c.logger.Debugf("Executing action: %s with params: %v", action.Type, action.Params)
-
-	req, err := http.NewRequest("POST",
-		fmt.Sprintf("%s/api/action", c.serverURL),
-		bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("POST", "/action", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Session-ID", c.sessionID)
+	// Actions may take longer than the HTTP client's timeout allows
+	ctx, cancel := context.WithTimeout(context.Background(), c.actionWait(action.Type, c.httpClient.Timeout))
+	defer cancel()
+	untimed := *c.httpClient
+	untimed.Timeout = 0
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(&untimed, httpReq.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return responseError(resp)
 	}
 
 	return nil
@@ -298,14 +476,14 @@ func (c *APIClient) ExecuteAction(action config.ButtonAction) error {
 
 // GetOBSStatus gets the current OBS status
 func (c *APIClient) GetOBSStatus() (map[string]interface{}, error) {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/api/obs/status", c.serverURL))
+	resp, err := c.get("/obs/status")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OBS status: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status: %d", resp.StatusCode)
+		return nil, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -321,27 +499,56 @@ func (c *APIClient) GetOBSStatus() (map[string]interface{}, error) {
 	return status, nil
 }
 
+// ButtonState is whether what a button controls is on, e.g. the stream
+// running or the source visible
+type ButtonState struct {
+	ID         string `json:"id"` // the button's position
+	ActionType string `json:"action_type"`
+	Active     bool   `json:"active"`
+}
+
+// GetButtonStates gets the state of every stateful button in our
+// configuration with one request. Servers without the state capability
+// return ErrNotSupported.
+func (c *APIClient) GetButtonStates() ([]ButtonState, error) {
+	if !c.Supports(CapabilityState) {
+		return nil, ErrNotSupported
+	}
+
+	resp, err := c.get("/client/state")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get button state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result struct {
+		Buttons []ButtonState `json:"buttons"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse button state: %w", err)
+	}
+	return result.Buttons, nil
+}
+
 // GetSourceVisibility checks if a source is visible in a scene
 func (c *APIClient) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
-	// Build URL with proper encoding
-	baseURL := fmt.Sprintf("%s/api/obs/source-visibility", c.serverURL)
-
 	// Use url.Values for proper query parameter encoding
 	params := neturl.Values{}
 	params.Add("scene", sceneName)
 	params.Add("source", sourceName)
 
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-	resp, err := c.httpClient.Get(fullURL)
+	resp, err := c.get("/obs/source-visibility?" + params.Encode())
 	if err != nil {
 		return false, fmt.Errorf("failed to get source visibility: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return false, responseError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// serviceType is the DNS-SD service servers advertise on the LAN
+const serviceType = "_robostream._tcp"
+
+// DiscoveredServer is a server found on the LAN
+type DiscoveredServer struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+	TLS     bool   `json:"tls"`
+	// Fingerprint is the certificate fingerprint the server advertises, to
+	// compare with the one shown in the server UI
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Discover browses the LAN for servers for up to timeout
+func Discover(ctx context.Context, timeout time.Duration) ([]DiscoveredServer, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to browse for servers: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, serviceType, "local.", entries); err != nil {
+		return nil, fmt.Errorf("failed to browse for servers: %w", err)
+	}
+
+	// The same server can answer on several interfaces
+	found := make(map[string]DiscoveredServer)
+	for {
+		select {
+		case entry := <-entries:
+			if server, ok := discoveredServer(entry); ok {
+				found[server.URL] = server
+			}
+		case <-ctx.Done():
+			servers := make([]DiscoveredServer, 0, len(found))
+			for _, server := range found {
+				servers = append(servers, server)
+			}
+			sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+			return servers, nil
+		}
+	}
+}
+
+// discoveredServer reads a browse result
+func discoveredServer(entry *zeroconf.ServiceEntry) (DiscoveredServer, bool) {
+	if entry == nil || len(entry.AddrIPv4) == 0 {
+		return DiscoveredServer{}, false
+	}
+
+	txt := make(map[string]string)
+	for _, record := range entry.Text {
+		if key, value, ok := strings.Cut(record, "="); ok {
+			txt[key] = value
+		}
+	}
+
+	scheme := "http"
+	if txt["scheme"] == "https" {
+		scheme = "https"
+	}
+	return DiscoveredServer{
+		Name:        unescapeInstance(entry.Instance),
+		URL:         fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(entry.AddrIPv4[0].String(), strconv.Itoa(entry.Port))),
+		Version:     txt["version"],
+		TLS:         scheme == "https",
+		Fingerprint: txt["fingerprint"],
+	}, true
+}
+
+// unescapeInstance removes the DNS escaping of spaces and dots in a service
+// instance name
+func unescapeInstance(name string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range name {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes the server sends with error responses
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeInvalidParam       = "INVALID_PARAM"
+	CodePairingRequired    = "PAIRING_REQUIRED"
+	CodeDeviceNotPaired    = "DEVICE_NOT_PAIRED"
+	CodeInvalidPairingCode = "INVALID_PAIRING_CODE"
+	CodeSessionNotFound    = "SESSION_NOT_FOUND"
+	CodeSessionForbidden   = "SESSION_FORBIDDEN"
+	CodeConfigNotFound     = "CONFIG_NOT_FOUND"
+	CodeButtonNotFound     = "BUTTON_NOT_FOUND"
+	CodeClientLocked       = "CLIENT_LOCKED"
+	CodeActionForbidden    = "ACTION_FORBIDDEN"
+	CodeUnknownAction      = "UNKNOWN_ACTION"
+	CodeOBSNotConnected    = "OBS_NOT_CONNECTED"
+	CodeOBSRequestFailed   = "OBS_REQUEST_FAILED"
+	CodeOBSTimeout         = "OBS_TIMEOUT"
+	CodeCanceled           = "CANCELED"
+	CodeInternal           = "INTERNAL"
+)
+
+// Errors the server's error codes map to; match them with errors.Is
+var (
+	// ErrPairingRequired is returned when the server doesn't accept our token
+	ErrPairingRequired = errors.New("pairing required")
+
+	// ErrInvalidPairingCode is returned for a wrong or expired pairing code
+	ErrInvalidPairingCode = errors.New("invalid or expired pairing code")
+
+	// ErrSessionNotFound is returned when the server forgot our session, so
+	// we need to register again
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrConfigurationNotFound is returned for configurations the server
+	// doesn't have, e.g. because they were deleted
+	ErrConfigurationNotFound = errors.New("configuration not found")
+
+	// ErrButtonNotFound is returned when a pressed button isn't in our
+	// configuration on the server
+	ErrButtonNotFound = errors.New("button not found")
+
+	// ErrClientLocked is returned while the server UI has locked this client
+	ErrClientLocked = errors.New("this client is locked")
+
+	// ErrActionForbidden is returned for actions our role doesn't allow
+	ErrActionForbidden = errors.New("action not allowed")
+
+	// ErrInvalidParam is returned for an action with a missing or wrong
+	// parameter; APIError.Field names it
+	ErrInvalidParam = errors.New("invalid parameter")
+
+	// ErrUnknownAction is returned for action types the server can't run
+	ErrUnknownAction = errors.New("unknown action type")
+
+	// ErrOBSNotConnected is returned while the server isn't connected to OBS
+	ErrOBSNotConnected = errors.New("server is not connected to OBS")
+
+	// ErrOBSRequestFailed is returned when OBS refused a request;
+	// APIError.OBSStatus has its status code
+	ErrOBSRequestFailed = errors.New("OBS request failed")
+
+	// ErrOBSTimeout is returned when OBS didn't answer the server in time;
+	// the action may still happen
+	ErrOBSTimeout = errors.New("OBS did not answer in time")
+)
+
+// codeErrors maps error codes to the errors above
+var codeErrors = map[string]error{
+	CodePairingRequired:    ErrPairingRequired,
+	CodeDeviceNotPaired:    ErrPairingRequired,
+	CodeInvalidPairingCode: ErrInvalidPairingCode,
+	CodeSessionNotFound:    ErrSessionNotFound,
+	CodeSessionForbidden:   ErrSessionNotFound,
+	CodeConfigNotFound:     ErrConfigurationNotFound,
+	CodeButtonNotFound:     ErrButtonNotFound,
+	CodeClientLocked:       ErrClientLocked,
+	CodeActionForbidden:    ErrActionForbidden,
+	CodeInvalidParam:       ErrInvalidParam,
+	CodeUnknownAction:      ErrUnknownAction,
+	CodeOBSNotConnected:    ErrOBSNotConnected,
+	CodeOBSRequestFailed:   ErrOBSRequestFailed,
+	CodeOBSTimeout:         ErrOBSTimeout,
+}
+
+// APIError is an error response from the server, over HTTP or as a failed
+// reply on the WebSocket
+type APIError struct {
+	// StatusCode is the HTTP status, or 0 for WebSocket replies
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"error"`
+	// Field names the offending parameter for INVALID_PARAM
+	Field string `json:"field,omitempty"`
+	// OBSStatus is the OBS request status for OBS_REQUEST_FAILED
+	OBSStatus int `json:"obs_status,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned status %d", e.StatusCode)
+	}
+	return e.Message
+}
+
+// Is matches the error its code maps to, so callers can use errors.Is
+func (e *APIError) Is(target error) bool {
+	return codeErrors[e.Code] == target
+}
+
+// responseError reads the error from a failed response
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil {
+		apiErr.Message = fmt.Sprintf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return apiErr
+}
+
+// eventError returns the error of a failed WebSocket reply, or nil
+func eventError(event Event) error {
+	if event.Error == "" {
+		return nil
+	}
+	return &APIError{Code: event.Code, Message: event.Error, Field: event.Field, OBSStatus: event.OBSStatus}
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// heartbeatInterval is how often we tell the server we're still here
+	heartbeatInterval = 20 * time.Second
+
+	// authTimeout is how long we wait for the server to accept our token
+	authTimeout = 10 * time.Second
+
+	// actionTimeout is how long a button press over the live connection may
+	// take on servers that don't announce their time limits
+	actionTimeout = 3 * time.Second
+
+	// actionTimeoutMargin is added to the server's time limit for an action,
+	// so its OBS_TIMEOUT reply arrives before we give up
+	actionTimeoutMargin = 2 * time.Second
+)
+
+// Topics we subscribe to over the live connection
+const (
+	TopicStatus = "status"
+	TopicState  = "state"
+)
+
+// Commands sent from the server UI
+const (
+	CommandSwitchConfig = "switch_config"
+	CommandIdentify     = "identify"
+	CommandLock         = "lock"
+	CommandRename       = "rename"
+	CommandReload       = "reload"
+	CommandDisconnect   = "disconnect"
+)
+
+// ErrDisconnected is returned by ListenEvents when the server told us to
+// disconnect
+var ErrDisconnected = errors.New("disconnected by server")
+
+// errNotLive is returned for requests while there's no live connection
+var errNotLive = errors.New("no live connection")
+
+// Event is a message pushed by the server. Replies to our messages carry
+// their ID.
+type Event struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+	// Code, Field and OBSStatus describe the error as in APIError
+	Code      string `json:"code,omitempty"`
+	Field     string `json:"field,omitempty"`
+	OBSStatus int    `json:"obs_status,omitempty"`
+}
+
+// message is a message we send to the server
+type message struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Command is a remote command from the server UI, sent as a "command" event
+type Command struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// commandAck answers a command
+type commandAck struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// authResult is the data of a successful auth_result. Older servers don't
+// send the action time limits.
+type authResult struct {
+	ActionTimeoutMS  int64            `json:"action_timeout_ms"`
+	ActionTimeoutsMS map[string]int64 `json:"action_timeouts_ms"`
+}
+
+// liveConn is an authenticated connection speaking the server's WebSocket
+// protocol, over which requests are matched to replies by ID
+type liveConn struct {
+	write func(interface{}) error
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan Event
+}
+
+// request sends a message and waits for the reply carrying its ID
+func (l *liveConn) request(msgType string, data interface{}, timeout time.Duration) (Event, error) {
+	reply := make(chan Event, 1)
+
+	l.mu.Lock()
+	if l.pending == nil {
+		l.mu.Unlock()
+		return Event{}, errNotLive
+	}
+	l.nextID++
+	id := strconv.Itoa(l.nextID)
+	l.pending[id] = reply
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		delete(l.pending, id)
+		l.mu.Unlock()
+	}()
+
+	if err := l.write(message{Type: msgType, ID: id, Data: data}); err != nil {
+		return Event{}, err
+	}
+
+	select {
+	case event, ok := <-reply:
+		if !ok {
+			return Event{}, errNotLive
+		}
+		return event, nil
+	case <-time.After(timeout):
+		return Event{}, fmt.Errorf("no reply to %s within %s", msgType, timeout)
+	}
+}
+
+// resolve hands a reply to the request waiting for it
+func (l *liveConn) resolve(event Event) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reply, ok := l.pending[event.ID]
+	if ok {
+		reply <- event
+		delete(l.pending, event.ID)
+	}
+	return ok
+}
+
+// close fails the requests still waiting for a reply
+func (l *liveConn) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, reply := range l.pending {
+		close(reply)
+	}
+	l.pending = nil
+}
+
+// Live reports whether we hold a live connection, over which actions are
+// sent and status is pushed
+func (c *APIClient) Live() bool {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	return c.live != nil
+}
+
+// setLive records the current live connection
+func (c *APIClient) setLive(live *liveConn) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live = live
+}
+
+// liveRequest sends a request over the live connection
+func (c *APIClient) liveRequest(msgType string, data interface{}, timeout time.Duration) (Event, error) {
+	c.liveMu.Lock()
+	live := c.live
+	c.liveMu.Unlock()
+
+	if live == nil {
+		return Event{}, errNotLive
+	}
+	return live.request(msgType, data, timeout)
+}
+
+// ListenEvents connects to the server's WebSocket, calling handle for each
+// event and handleCommand for each command, whose result is sent back as an
+// acknowledgement. While connected, actions go over the socket and status
+// and state are pushed as events. Servers without the WebSocket capability
+// only push events. It returns when the connection drops, or nil once ctx is
+// done.
+func (c *APIClient) ListenEvents(ctx context.Context, handle func(Event), handleCommand func(Command) error) error {
+	if c.sessionID == "" {
+		return fmt.Errorf("not registered - no session ID")
+	}
+
+	conn, protocol, err := c.dialEvents(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the read below when we're told to stop
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Requests, acks and heartbeats are written from different goroutines
+	var writeMu sync.Mutex
+	write := func(msg interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(authTimeout))
+		return conn.WriteJSON(msg)
+	}
+
+	var live *liveConn
+	if protocol {
+		result, err := c.authenticate(conn)
+		if err != nil {
+			return err
+		}
+		c.liveMu.Lock()
+		c.limits = result
+		c.liveMu.Unlock()
+		live = &liveConn{write: write, pending: make(map[string]chan Event)}
+		defer func() {
+			c.setLive(nil)
+			live.close()
+		}()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := write(message{Type: "heartbeat"}); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if live != nil {
+		// Subscribing answers with the current values, so send it from here
+		// and read the reply in the loop below
+		if err := write(message{Type: "subscribe", Data: map[string][]string{
+			"topics": {TopicStatus, TopicState},
+		}}); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		c.setLive(live)
+		c.logger.Info("Connected to server over WebSocket")
+	} else {
+		c.logger.Info("Listening for server events")
+	}
+
+	for {
+		var event Event
+		conn.SetReadDeadline(time.Time{})
+		if err := conn.ReadJSON(&event); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("event stream closed: %w", err)
+		}
+		c.logger.Debugf("Received %s event", event.Type)
+
+		if event.ID != "" && live != nil && live.resolve(event) {
+			continue
+		}
+		if event.Error != "" {
+			c.logger.Warnf("Server reported an error for %s: %s", event.Type, event.Error)
+			continue
+		}
+		if event.Type != "command" {
+			handle(event)
+			continue
+		}
+
+		var cmd Command
+		if err := json.Unmarshal(event.Data, &cmd); err != nil {
+			c.logger.Errorf("Invalid command from server: %v", err)
+			continue
+		}
+		ack := commandAck{ID: cmd.ID}
+		if err := handleCommand(cmd); err != nil {
+			ack.Error = err.Error()
+		}
+		if err := write(message{Type: "ack", Data: ack}); err != nil {
+			return fmt.Errorf("failed to acknowledge %s: %w", cmd.Name, err)
+		}
+		if cmd.Name == CommandDisconnect && ack.Error == "" {
+			return ErrDisconnected
+		}
+	}
+}
+
+// dialEvents connects to the server's WebSocket, or to the events stream of
+// servers that don't speak the protocol. It reports which one it connected
+// to.
+func (c *APIClient) dialEvents(ctx context.Context) (*websocket.Conn, bool, error) {
+	u, err := neturl.Parse(c.serverURL)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid server URL: %w", err)
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+
+	if c.Supports(CapabilityWebSocket) {
+		u.Path = c.apiPath("/ws")
+		conn, _, err := c.dialer.DialContext(ctx, u.String(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to connect to server: %w", err)
+		}
+		return conn, true, nil
+	}
+
+	c.logger.Debug("Server has no WebSocket protocol, using the events stream")
+	u.Path = c.apiPath("/client/events")
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.token)
+	header.Set("X-Session-ID", c.sessionID)
+
+	conn, resp, err := c.dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			defer resp.Body.Close()
+			return nil, false, responseError(resp)
+		}
+		return nil, false, fmt.Errorf("failed to connect to events: %w", err)
+	}
+	return conn, false, nil
+}
+
+// authenticate sends our token and session as the connection's first
+// message and waits for the server to accept them
+func (c *APIClient) authenticate(conn *websocket.Conn) (*authResult, error) {
+	conn.SetWriteDeadline(time.Now().Add(authTimeout))
+	if err := conn.WriteJSON(message{Type: "auth", ID: "auth", Data: map[string]string{
+		"token":      c.token,
+		"session_id": c.sessionID,
+	}}); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	var result Event
+	if err := conn.ReadJSON(&result); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	if result.Type != "auth_result" {
+		return nil, fmt.Errorf("unexpected %s message while authenticating", result.Type)
+	}
+	if err := eventError(result); err != nil {
+		return nil, err
+	}
+
+	var data authResult
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		c.logger.Warnf("Ignoring unreadable auth result: %v", err)
+	}
+	return &data, nil
+}
+
+// actionWait returns how long to wait for an action: the server's time
+// limit for its type plus a margin, or fallback if the server didn't
+// announce its limits
+func (c *APIClient) actionWait(actionType string, fallback time.Duration) time.Duration {
+	c.liveMu.Lock()
+	limits := c.limits
+	c.liveMu.Unlock()
+
+	if limits == nil || limits.ActionTimeoutMS <= 0 {
+		return fallback
+	}
+	ms := limits.ActionTimeoutMS
+	if typeMS, ok := limits.ActionTimeoutsMS[actionType]; ok {
+		ms = typeMS
+	}
+	return time.Duration(ms)*time.Millisecond + actionTimeoutMargin
+}
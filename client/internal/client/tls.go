@@ -0,0 +1,136 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownServersFile stores the certificate fingerprint pinned for each HTTPS
+// server
+const knownServersFile = "known_servers.json"
+
+// ErrCertificateChanged is matched by errors from servers whose certificate
+// no longer has the fingerprint we pinned
+var ErrCertificateChanged = errors.New("server certificate changed")
+
+// CertificateChangedError is returned when a server presents a certificate
+// other than the one we trusted on first use. It may have been replaced on
+// purpose, or someone may be in between.
+type CertificateChangedError struct {
+	Pinned      string
+	Fingerprint string
+}
+
+func (e *CertificateChangedError) Error() string {
+	return fmt.Sprintf("server certificate changed: expected fingerprint %s, got %s", e.Pinned, e.Fingerprint)
+}
+
+// Is matches ErrCertificateChanged
+func (e *CertificateChangedError) Is(target error) bool {
+	return target == ErrCertificateChanged
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a DER certificate, in the
+// format the server UI shows it
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":")
+}
+
+// loadPins reads the pinned fingerprints, by server URL
+func loadPins(configDir string) map[string]string {
+	pins := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(configDir, knownServersFile))
+	if err == nil {
+		json.Unmarshal(data, &pins)
+	}
+	return pins
+}
+
+// savePin records the fingerprint pinned for a server
+func savePin(configDir, serverURL, fingerprint string) error {
+	pins := loadPins(configDir)
+	pins[serverURL] = fingerprint
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, knownServersFile), data, 0600)
+}
+
+// tlsConfig returns the TLS config for connections to the server.
+// Certificates a system CA vouches for are accepted as usual; others, like
+// the server's self-signed one, must match the fingerprint pinned the first
+// time we connected.
+func (c *APIClient) tlsConfig() *tls.Config {
+	return &tls.Config{
+		// Checked by verifyConnection instead
+		InsecureSkipVerify: true,
+		VerifyConnection:   c.verifyConnection,
+	}
+}
+
+// verifyConnection checks the server's certificate
+func (c *APIClient) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server sent no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: state.ServerName, Intermediates: intermediates}); err == nil {
+		return nil
+	}
+
+	fingerprint := Fingerprint(leaf.Raw)
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+	switch c.pinned {
+	case fingerprint:
+		return nil
+	case "":
+		c.logger.Warnf("Trusting the server's certificate on first use, fingerprint %s", fingerprint)
+		c.pinned = fingerprint
+		if err := savePin(c.configDir, c.serverURL, fingerprint); err != nil {
+			c.logger.Warnf("Failed to save certificate fingerprint: %v", err)
+		}
+		return nil
+	default:
+		c.logger.Errorf("The server's certificate changed: pinned %s, got %s", c.pinned, fingerprint)
+		return &CertificateChangedError{Pinned: c.pinned, Fingerprint: fingerprint}
+	}
+}
+
+// PinnedFingerprint returns the fingerprint of the server certificate we
+// trust, if we pinned one
+func (c *APIClient) PinnedFingerprint() string {
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+	return c.pinned
+}
+
+// TrustCertificate pins a new certificate fingerprint for the server, after
+// the user confirmed the certificate was replaced on purpose
+func (c *APIClient) TrustCertificate(fingerprint string) error {
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+	if err := savePin(c.configDir, c.serverURL, fingerprint); err != nil {
+		return fmt.Errorf("failed to save certificate fingerprint: %w", err)
+	}
+	c.pinned = fingerprint
+	return nil
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// apiVersion is the newest server API version this client speaks
+	apiVersion = 1
+
+	// minServerAPIVersion is the oldest server API version this client can
+	// work with
+	minServerAPIVersion = 1
+)
+
+// Capabilities a server may report
+const (
+	CapabilityPairing   = "pairing"
+	CapabilityWebSocket = "websocket"
+	CapabilityButtonID  = "button_id"
+	CapabilityRoles     = "roles"
+	CapabilityCommands  = "commands"
+	CapabilityHeartbeat = "heartbeat"
+	CapabilityState     = "state"
+)
+
+var (
+	// ErrServerTooOld is returned when the server's API is older than this
+	// client supports
+	ErrServerTooOld = errors.New("the server is too old for this client, please update the Robo-Stream server")
+
+	// ErrServerTooNew is returned when the server no longer supports this
+	// client's API
+	ErrServerTooNew = errors.New("the server is too new for this client, please update this client")
+
+	// ErrNotSupported is returned for features the server doesn't report as
+	// a capability
+	ErrNotSupported = errors.New("not supported by the server")
+)
+
+// ServerInfo is what a server reports about itself from its health check
+type ServerInfo struct {
+	Status          string   `json:"status"`
+	OBSConnected    bool     `json:"obs_connected"`
+	Version         string   `json:"version"`
+	APIVersion      int      `json:"api_version"`
+	MinAPIVersion   int      `json:"min_api_version"`
+	ProtocolVersion int      `json:"protocol_version"`
+	Capabilities    []string `json:"capabilities"`
+}
+
+// Supports reports whether the server has a capability
+func (i *ServerInfo) Supports(capability string) bool {
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate checks that the server speaks an API version we support and
+// picks the newest one both sides know. The unversioned health check is
+// used because every server answers it.
+func (c *APIClient) Negotiate() (*ServerInfo, error) {
+	resp, err := c.httpClient.Get(c.serverURL + "/api/health")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var info ServerInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse server info: %w", err)
+	}
+
+	// Servers from before the API was versioned don't report a version
+	switch {
+	case info.APIVersion < minServerAPIVersion:
+		return nil, fmt.Errorf("%w (server API v%d, client needs v%d or newer)", ErrServerTooOld, info.APIVersion, minServerAPIVersion)
+	case info.MinAPIVersion > apiVersion:
+		return nil, fmt.Errorf("%w (server %s needs API v%d, client speaks v%d)", ErrServerTooNew, info.Version, info.MinAPIVersion, apiVersion)
+	}
+
+	version := min(info.APIVersion, apiVersion)
+	c.server = &info
+	c.apiPrefix = fmt.Sprintf("/api/v%d", version)
+
+	c.logger.Infof("Server %s, using API v%d", info.Version, version)
+	return &info, nil
+}
+
+// Supports reports whether the negotiated server has a capability. Before
+// negotiating, nothing is assumed.
+func (c *APIClient) Supports(capability string) bool {
+	return c.server != nil && c.server.Supports(capability)
+}
+
+// apiPath returns the server path of an API endpoint under the negotiated
+// version, or the unversioned path before negotiating
+func (c *APIClient) apiPath(endpoint string) string {
+	if c.apiPrefix == "" {
+		return "/api" + endpoint
+	}
+	return c.apiPrefix + endpoint
+}
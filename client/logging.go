@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// logLevelEnv sets the log level: debug, info, warn or error
+const logLevelEnv = "ROBO_STREAM_LOG_LEVEL"
+
+// The log file is rotated at logMaxSizeMB, keeping logMaxBackups old files
+const (
+	logFile       = "robo-stream-client.log"
+	logMaxSizeMB  = 10
+	logMaxBackups = 3
+	logMaxAgeDays = 30
+)
+
+// newLogger logs readable text to stderr and JSON lines to a rotated file in
+// the config directory's logs folder
+func newLogger(configDir string) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	if name := os.Getenv(logLevelEnv); name != "" {
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			logger.Warnf("Ignoring %s: %v", logLevelEnv, err)
+		} else {
+			logger.SetLevel(level)
+		}
+	}
+
+	dir := filepath.Join(configDir, "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Warnf("Logging to stderr only: %v", err)
+		return logger
+	}
+	logger.AddHook(&fileHook{
+		writer: &lumberjack.Logger{
+			Filename:   filepath.Join(dir, logFile),
+			MaxSize:    logMaxSizeMB,
+			MaxBackups: logMaxBackups,
+			MaxAge:     logMaxAgeDays,
+		},
+		formatter: &logrus.JSONFormatter{},
+	})
+	return logger
+}
+
+// fileHook writes every entry to the log file as JSON
+type fileHook struct {
+	writer    *lumberjack.Logger
+	formatter logrus.Formatter
+}
+
+func (h *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fileHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(line)
+	return err
+}
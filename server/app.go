@@ -2,215 +2,93 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	neturl "net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/robomon1/robo-stream/server/internal/api"
+	"github.com/robomon1/robo-stream/server/internal/core"
+	"github.com/robomon1/robo-stream/server/internal/declarative"
+	"github.com/robomon1/robo-stream/server/internal/logging"
 	"github.com/robomon1/robo-stream/server/internal/manager"
 	"github.com/robomon1/robo-stream/server/internal/models"
-	"github.com/robomon1/robo-stream/server/internal/storage"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+var appLog = logging.For(logging.App)
+
 // App struct
 type App struct {
 	ctx                  context.Context
-	storage              *storage.Storage
+	core                 *core.Core
 	buttonManager        *manager.ButtonManager
 	configManager        *manager.ConfigManager
 	sessionManager       *manager.SessionManager
 	obsManager           *manager.OBSManager
-	apiServer            *api.Server
 	lastOBSConnected     bool
 	obsStatusInitialized bool
+
+	// settingOverrides come from flags and environment variables
+	settingOverrides map[string]string
 }
 
 // NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
+func NewApp(settingOverrides map[string]string) *App {
+	return &App{settingOverrides: settingOverrides}
 }
 
 // startup is called when the app starts
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
-	// Get data directory
-	dataDir, err := a.getDataDir()
-	if err != nil {
-		log.Fatal("Failed to get data directory:", err)
-	}
-
-	// Initialize storage
-	a.storage, err = storage.New(dataDir)
+	c, err := core.New(a.settingOverrides)
 	if err != nil {
-		log.Fatal("Failed to initialize storage:", err)
+		log.Fatal(err)
 	}
-
-	// Initialize managers
-	a.buttonManager = manager.NewButtonManager(a.storage)
-	a.configManager = manager.NewConfigManager(a.storage, a.buttonManager)
-	a.sessionManager = manager.NewSessionManager(a.storage)
-	a.obsManager = manager.NewOBSManager()
-
-	// Initialize with some default data if needed
-	a.initializeDefaults()
-
-	// Start session cleanup routine
-	go a.sessionCleanupLoop()
-
-	// Auto-connect to OBS on startup
-	go func() {
-		log.Println("🔌 Attempting to auto-connect to OBS...")
-		savedConfig := a.GetSavedOBSConfig()
-
-		// Try with saved config first
-		err := a.obsManager.Connect(savedConfig.URL, savedConfig.Password)
-		if err != nil {
-			log.Printf("⚠️  Auto-connect to OBS failed: %v (this is normal if OBS isn't running)", err)
-		} else {
-			log.Println("✅ Auto-connected to OBS successfully!")
-		}
-	}()
-
-	// Start API server for clients
-	a.apiServer = api.NewServer(a.configManager, a.sessionManager, a.obsManager)
+	a.core = c
+	a.buttonManager = c.ButtonManager
+	a.configManager = c.ConfigManager
+	a.sessionManager = c.SessionManager
+	a.obsManager = c.OBSManager
+
+	// Show reloads of externally edited data files in the UI
+	c.OnReload(func(result *manager.ReloadResult) {
+		wailsruntime.EventsEmit(a.ctx, "data_reloaded", result)
+	})
+
+	// Flag buttons that won't work in OBS
+	c.OnButtonIssues(func(issues []manager.ButtonIssue) {
+		wailsruntime.EventsEmit(a.ctx, "button_issues", issues)
+	})
+
+	// Stream log lines to the log viewer
+	lines, unsubscribe := logging.Subscribe()
 	go func() {
-		log.Println("Starting API server on 0.0.0.0:8080")
-		if err := a.apiServer.Start("0.0.0.0:8080"); err != nil {
-			log.Printf("API server error: %v", err)
+		defer unsubscribe()
+		for {
+			select {
+			case line := <-lines:
+				wailsruntime.EventsEmit(a.ctx, "log_entry", line)
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
-	log.Println("Robo-Stream Server started successfully")
-}
-
-// sessionCleanupLoop periodically cleans up inactive sessions
-func (a *App) sessionCleanupLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	// Initial cleanup on startup
-	inactiveTimeout := 30 * time.Minute
-	if err := a.sessionManager.CleanupInactive(inactiveTimeout); err != nil {
-		log.Printf("⚠️  Failed to cleanup inactive sessions: %v", err)
-	} else {
-		activeSessions := len(a.sessionManager.List())
-		log.Printf("🧹 Session cleanup complete (%d active sessions)", activeSessions)
-	}
-
-	// Periodic cleanup
-	for range ticker.C {
-		if err := a.sessionManager.CleanupInactive(inactiveTimeout); err != nil {
-			log.Printf("⚠️  Failed to cleanup inactive sessions: %v", err)
-		} else {
-			activeSessions := len(a.sessionManager.List())
-			log.Printf("🧹 Session cleanup complete (%d active sessions)", activeSessions)
-		}
+	if err := c.Start(); err != nil {
+		appLog.Error("Server failed to start", "error", err)
 	}
 }
 
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
-	if a.obsManager != nil {
-		a.obsManager.Disconnect()
-	}
-	log.Println("Robo-Stream Server shutdown complete")
-}
-
-// getDataDir returns the appropriate data directory for the platform
-func (a *App) getDataDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-
-	var dataDir string
-	switch {
-	case filepath.Dir("/") == "/": // Unix-like
-		dataDir = filepath.Join(homeDir, ".robo-stream-server")
-	default: // Windows
-		dataDir = filepath.Join(homeDir, "AppData", "Roaming", "RoboStreamServer")
-	}
-
-	return dataDir, nil
-}
-
-// initializeDefaults creates default configuration if none exist
-func (a *App) initializeDefaults() {
-	// Check if we have any configurations
-	configs := a.configManager.List()
-	if len(configs) > 0 {
-		return // Already initialized
-	}
-
-	log.Println("Initializing default configuration...")
-
-	// Create some default buttons
-	defaultButtons := []struct {
-		name        string
-		description string
-		icon        string
-		color       string
-		actionType  string
-		params      map[string]interface{}
-	}{
-		{"Go Live", "Start streaming", "video", "#e74c3c", "start_stream", nil},
-		{"Stop Stream", "Stop streaming", "stop-circle", "#95a5a6", "stop_stream", nil},
-		{"Start Record", "Start recording", "circle", "#e74c3c", "start_record", nil},
-		{"Stop Record", "Stop recording", "stop-circle", "#95a5a6", "stop_record", nil},
-		{"Mute Mic", "Mute microphone", "mic-off", "#e67e22", "toggle_input_mute", map[string]interface{}{"input_name": "Mic/Aux"}},
-		{"Scene", "Switch to main scene", "layout", "#3498db", "switch_scene", map[string]interface{}{"scene_name": "Scene"}},
-	}
-
-	buttonIDs := make([]string, 0, len(defaultButtons))
-	for _, btn := range defaultButtons {
-		button := &models.Button{
-			Name:        btn.name,
-			Description: btn.description,
-			Icon:        btn.icon,
-			Color:       btn.color,
-			Action: models.ButtonAction{
-				Type:   btn.actionType,
-				Params: btn.params,
-			},
-		}
-		if err := a.buttonManager.Create(button); err != nil {
-			log.Printf("Failed to create button %s: %v", btn.name, err)
-			continue
-		}
-		buttonIDs = append(buttonIDs, button.ID)
-	}
-
-	// Create default configuration
-	defaultConfig := &models.Configuration{
-		Name:        "Default",
-		Description: "Default configuration",
-		Grid: models.GridConfig{
-			Rows: 3,
-			Cols: 4,
-		},
-		Buttons:   make(map[string]string),
-		IsDefault: true,
-	}
-
-	// Assign buttons to positions
-	positions := []string{"btn-0-0", "btn-0-1", "btn-1-0", "btn-1-1", "btn-2-0", "btn-2-1"}
-	for i, btnID := range buttonIDs {
-		if i < len(positions) {
-			defaultConfig.Buttons[positions[i]] = btnID
-		}
-	}
-
-	if err := a.configManager.Create(defaultConfig); err != nil {
-		log.Printf("Failed to create default configuration: %v", err)
+	if a.core != nil {
+		a.core.Stop(ctx)
 	}
-
-	log.Println("Default configuration created successfully")
 }
 
 // ==================== WAILS BINDINGS ====================
@@ -270,9 +148,123 @@ func (a *App) ResolveConfiguration(id string) (*models.ResolvedConfiguration, er
 	return a.configManager.Resolve(id)
 }
 
+// GetReloadResults returns the most recent reloads of externally edited data
+// files, including any conflicts with in-app edits
+func (a *App) GetReloadResults() []*manager.ReloadResult {
+	return a.core.ReloadResults()
+}
+
+// GetButtonIssues returns the library buttons that didn't work in OBS when
+// last checked. New results arrive as button_issues events.
+func (a *App) GetButtonIssues() []manager.ButtonIssue {
+	return a.core.ButtonIssues()
+}
+
+// GetAuditLog returns up to limit of the latest changes the server made on
+// its own, like buttons following renames in OBS, newest first
+func (a *App) GetAuditLog(limit int) []*models.AuditEntry {
+	return a.core.AuditManager.List(limit)
+}
+
+// Logs
+
+// GetRecentLogs returns up to limit of the latest server log lines at level
+// or above, oldest first. New lines arrive as log_entry events.
+func (a *App) GetRecentLogs(limit int, level string) ([]map[string]interface{}, error) {
+	min, err := logging.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	lines := logging.Recent(limit, min)
+	entries := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// GetLogFile returns where the server log is written
+func (a *App) GetLogFile() string {
+	return logging.Path()
+}
+
+// CollectDiagnostics asks where to save a diagnostics archive and writes it.
+// It returns the path, or "" if the user cancelled.
+func (a *App) CollectDiagnostics() (string, error) {
+	path, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+		Title:           "Save Diagnostics",
+		DefaultFilename: api.DiagnosticsFileName(time.Now()),
+		Filters: []wailsruntime.FileFilter{
+			{DisplayName: "Zip Archives (*.zip)", Pattern: "*.zip"},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := a.core.CollectDiagnostics(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	appLog.Info("Saved diagnostics", "path", path)
+	return path, nil
+}
+
+// Declarative config operations
+
+// PlanDeclarativeConfig diffs a layout file against the current buttons,
+// configurations and assignment rules without changing anything. Plan with
+// prune set before applying with it.
+func (a *App) PlanDeclarativeConfig(path string, prune bool) (*declarative.Plan, error) {
+	return a.core.PlanDeclarativeConfig(path, prune)
+}
+
+// ApplyDeclarativeConfig applies a layout file. With prune set, buttons and
+// configurations missing from the file are deleted, once a plan with prune
+// has shown what that removes.
+func (a *App) ApplyDeclarativeConfig(path string, prune bool) (*declarative.Plan, error) {
+	return a.core.ApplyDeclarativeConfig(path, prune)
+}
+
+// ExportDeclarativeConfig writes the current buttons, configurations and
+// assignment rules to a layout file
+func (a *App) ExportDeclarativeConfig(path string) error {
+	return a.core.ExportDeclarativeConfig(path)
+}
+
+// Settings operations
+
+// GetSettings returns the settings in effect
+func (a *App) GetSettings() models.Settings {
+	return a.core.SettingsManager.Get()
+}
+
+// GetSettingOverrides returns the settings fixed by flags or environment
+// variables, which the UI shows as read-only
+func (a *App) GetSettingOverrides() []string {
+	return a.core.SettingsManager.Overridden()
+}
+
+// UpdateSettings saves settings and applies them where possible without a
+// restart
+func (a *App) UpdateSettings(settings models.Settings) (models.Settings, error) {
+	return a.core.UpdateSettings(settings)
+}
+
 // Session operations
 func (a *App) GetSessions() []*models.ClientSession {
-	return a.sessionManager.List()
+	return a.core.Sessions()
 }
 
 func (a *App) GetSession(sessionID string) (*models.ClientSession, error) {
@@ -283,9 +275,87 @@ func (a *App) UpdateClientConfig(sessionID, configID string) error {
 	return a.sessionManager.UpdateConfig(sessionID, configID)
 }
 
+// Remote client commands; each waits until the client acknowledges it
+
+// SwitchClientConfig tells a connected client to show another configuration
+func (a *App) SwitchClientConfig(sessionID, configID string) error {
+	return a.core.SwitchClientConfig(sessionID, configID)
+}
+
+// IdentifyClient makes a connected client flash its name on screen
+func (a *App) IdentifyClient(sessionID string) error {
+	return a.core.IdentifyClient(sessionID)
+}
+
+// LockClient locks or unlocks a client's grid
+func (a *App) LockClient(sessionID string, locked bool) error {
+	return a.core.LockClient(sessionID, locked)
+}
+
+// RenameClient renames a connected client
+func (a *App) RenameClient(sessionID, name string) error {
+	return a.core.RenameClient(sessionID, name)
+}
+
+// ReloadClient makes a connected client reload its configuration
+func (a *App) ReloadClient(sessionID string) error {
+	return a.core.ReloadClient(sessionID)
+}
+
+// DisconnectClient disconnects a client
+func (a *App) DisconnectClient(sessionID string) error {
+	return a.core.DisconnectClient(sessionID)
+}
+
+// Role operations
+
+// GetRoles returns the roles that limit what client sessions may do
+func (a *App) GetRoles() []*models.Role {
+	return a.core.RoleManager.List()
+}
+
+// SaveRole creates or replaces a role
+func (a *App) SaveRole(role *models.Role) error {
+	return a.core.RoleManager.Save(role)
+}
+
+// DeleteRole removes a role
+func (a *App) DeleteRole(name string) error {
+	return a.core.RoleManager.Delete(name)
+}
+
+// SetSessionRole sets the role of a client session and caps its device at
+// it; empty uses the role of its configuration
+func (a *App) SetSessionRole(sessionID, role string) error {
+	if role != "" {
+		if _, err := a.core.RoleManager.Get(role); err != nil {
+			return err
+		}
+	}
+	return a.sessionManager.SetRole(sessionID, role)
+}
+
+// Device pairing operations
+
+// StartPairing creates a short-lived code (and QR code) a client redeems to
+// pair with this server
+func (a *App) StartPairing() (*models.PairingCode, error) {
+	return a.core.StartPairing()
+}
+
+// GetPairedDevices returns the devices allowed to use the client API
+func (a *App) GetPairedDevices() []*models.PairedDevice {
+	return a.sessionManager.Devices()
+}
+
+// RevokeDevice unpairs a device so its token stops working
+func (a *App) RevokeDevice(deviceID string) error {
+	return a.core.APIServer.RevokeDevice(deviceID)
+}
+
 // OBS operations
 func (a *App) ConnectOBS(url, password string) error {
-	log.Printf("🔌 ConnectOBS called with RAW url: %q", url)
+	appLog.Debug("ConnectOBS called", "url", url)
 
 	// Workaround: Wails might be double-encoding the URL
 	// Decode it if needed
@@ -294,30 +364,18 @@ func (a *App) ConnectOBS(url, password string) error {
 		var err error
 		decodedURL, err = neturl.QueryUnescape(url)
 		if err != nil {
-			log.Printf("⚠️  Failed to decode URL: %v", err)
+			appLog.Warn("Failed to decode OBS URL", "url", url, "error", err)
 		} else {
-			log.Printf("🔧 Decoded URL from %q to %q", url, decodedURL)
+			appLog.Debug("Decoded OBS URL", "from", url, "to", decodedURL)
 		}
 	}
 
-	log.Printf("🔌 Connecting to: %s", decodedURL)
-	err := a.obsManager.Connect(decodedURL, password)
-	if err != nil {
-		log.Printf("❌ ConnectOBS failed: %v", err)
+	// The UI never sees the saved password, so a blank one means "keep it"
+	if err := a.core.ConnectOBS(decodedURL, password); err != nil {
+		appLog.Error("ConnectOBS failed", "error", err)
 		return err
 	}
-	log.Printf("✅ ConnectOBS succeeded")
-
-	// Save credentials for next time
-	config := &models.OBSConfig{
-		URL:      decodedURL,
-		Password: password,
-	}
-	if err := a.storage.SaveJSON("obs_config.json", config); err != nil {
-		log.Printf("⚠️  Failed to save OBS config: %v", err)
-	} else {
-		log.Println("💾 OBS config saved")
-	}
+	appLog.Info("ConnectOBS succeeded")
 
 	// Reset state tracking so next status check logs
 	a.obsStatusInitialized = false
@@ -326,7 +384,7 @@ func (a *App) ConnectOBS(url, password string) error {
 }
 
 func (a *App) DisconnectOBS() error {
-	log.Println("🔌 DisconnectOBS called")
+	appLog.Debug("DisconnectOBS called")
 	// Reset state tracking
 	a.obsStatusInitialized = false
 	return a.obsManager.Disconnect()
@@ -362,7 +420,7 @@ func (a *App) DisconnectOBS() error {
 
 func (a *App) GetOBSStatus() map[string]interface{} {
 	// Get detailed status from OBS manager (includes streaming, recording, current_scene)
-	status, err := a.obsManager.GetStatus()
+	status, err := a.obsManager.GetStatus(a.ctx)
 	if err != nil {
 		// Return disconnected state
 		return map[string]interface{}{
@@ -377,144 +435,81 @@ func (a *App) GetOBSStatus() map[string]interface{} {
 	return status
 }
 
-// GetSavedOBSConfig returns the saved OBS credentials
+// GetSavedOBSConfig returns the saved OBS connection settings. The password
+// itself is never returned, only whether one is saved.
 func (a *App) GetSavedOBSConfig() *models.OBSConfig {
-	// Check environment variables first
-	envURL := os.Getenv("OBS_WEBSOCKET_URL")
-	envPassword := os.Getenv("OBS_WEBSOCKET_PASSWORD")
-
-	if envURL != "" {
-		log.Printf("📋 Using OBS config from environment variables")
-		return &models.OBSConfig{
-			URL:      envURL,
-			Password: envPassword,
-		}
+	config := a.core.LoadOBSConfig()
+	return &models.OBSConfig{
+		URL:         config.URL,
+		HasPassword: config.Password != "",
 	}
-
-	// Fall back to saved config file
-	var config models.OBSConfig
-	if err := a.storage.LoadJSON("obs_config.json", &config); err != nil {
-		log.Printf("📋 No saved OBS config found, using defaults")
-		return &models.OBSConfig{
-			URL:      "localhost:4455",
-			Password: "",
-		}
-	}
-	log.Printf("📋 Loaded saved OBS config: url=%s", config.URL)
-	return &config
 }
 
 func (a *App) GetScenes() ([]string, error) {
-	log.Println("📞 GetScenes() called from frontend")
+	appLog.Debug("GetScenes called")
 	return a.obsManager.GetScenes()
 }
 
 func (a *App) GetInputs() ([]string, error) {
-	log.Println("📞 GetInputs() called from frontend")
+	appLog.Debug("GetInputs called")
 	return a.obsManager.GetInputs()
 }
 
+// GetSceneItems returns the sources in a scene, including those in groups
+func (a *App) GetSceneItems(sceneName string) ([]manager.SceneItem, error) {
+	return a.obsManager.SceneItems(sceneName)
+}
+
+// GetSourceFilters returns the filters on a scene or input
+func (a *App) GetSourceFilters(sourceName string) ([]manager.SourceFilter, error) {
+	return a.obsManager.SourceFilters(sourceName)
+}
+
+// GetTransitions returns the scene transitions and the current one
+func (a *App) GetTransitions() (*manager.Transitions, error) {
+	return a.obsManager.Transitions()
+}
+
+// GetInputKinds returns the kinds of inputs OBS can create
+func (a *App) GetInputKinds() ([]string, error) {
+	return a.obsManager.InputKinds()
+}
+
+// GetSpecialInputs returns the global desktop and mic inputs
+func (a *App) GetSpecialInputs() (*manager.SpecialInputs, error) {
+	return a.obsManager.SpecialInputs()
+}
+
 func (a *App) ExecuteAction(action models.ButtonAction) error {
-	return a.obsManager.ExecuteAction(action)
+	return a.obsManager.ExecuteAction(a.ctx, action)
 }
 
 // GetSourceVisibility checks if a source is currently visible
 func (a *App) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
 	// log.Printf("Checking visibility: scene=%s, source=%s", sceneName, sourceName)
-	visible, err := a.obsManager.GetSourceVisibility(sceneName, sourceName)
+	visible, err := a.obsManager.GetSourceVisibility(a.ctx, sceneName, sourceName)
 	if err != nil {
-		log.Printf("Failed to check visibility: %v", err)
+		appLog.Warn("Failed to check visibility", "scene", sceneName, "source", sourceName, "error", err)
 		return false, err
 	}
 	// log.Printf("Source %s is visible: %v", sourceName, visible)
 	return visible, nil
 }
 
-// Test configuration by executing all actions in preview mode
-func (a *App) TestConfiguration(configID string) error {
-	config, err := a.configManager.Resolve(configID)
-	if err != nil {
-		return err
-	}
-
-	if !a.obsManager.IsConnected() {
-		return fmt.Errorf("not connected to OBS")
-	}
-
-	log.Printf("Testing configuration: %s (%d buttons)", config.Name, len(config.Buttons))
-	return nil
-}
-
-// getLocalIPs returns all non-loopback IPv4 addresses
-func (a *App) getLocalIPs() []string {
-	var ips []string
-
-	interfaces, err := net.Interfaces()
-	if err != nil {
-		log.Printf("Failed to get network interfaces: %v", err)
-		return ips
-	}
-
-	for _, iface := range interfaces {
-		// Skip down interfaces
-		if iface.Flags&net.FlagUp == 0 {
-			continue
-		}
-
-		// Skip loopback
-		if iface.Flags&net.FlagLoopback != 0 {
-			continue
-		}
-
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-
-			// Skip loopback and IPv6
-			if ip == nil || ip.IsLoopback() || ip.To4() == nil {
-				continue
-			}
-
-			ips = append(ips, ip.String())
-		}
-	}
-
-	return ips
+// TestConfiguration checks every button of a configuration against what
+// exists in OBS without running anything, for red and green badges in the UI
+func (a *App) TestConfiguration(configID string) (*manager.ConfigurationReport, error) {
+	return a.core.TestConfiguration(configID)
 }
 
 // Get server info
 func (a *App) GetServerInfo() map[string]interface{} {
-	ips := a.getLocalIPs()
-	clientURLs := make([]string, len(ips))
-	for i, ip := range ips {
-		clientURLs[i] = fmt.Sprintf("http://%s:8080", ip)
-	}
-
-	return map[string]interface{}{
-		"version":         "1.0.0",
-		"api_port":        8080,
-		"ip_addresses":    ips,
-		"client_urls":     clientURLs,
-		"obs_connected":   a.obsManager.IsConnected(),
-		"active_sessions": len(a.sessionManager.List()),
-		"configurations":  len(a.configManager.List()),
-		"buttons":         len(a.buttonManager.List()),
-	}
+	return a.core.ServerInfo()
 }
 
 // TestBinding - Simple test to verify Wails bindings are working
 func (a *App) TestBinding(message string) string {
 	response := fmt.Sprintf("✅ Wails binding works! You sent: %s", message)
-	log.Println(response)
+	appLog.Debug(response)
 	return response
 }
@@ -0,0 +1,76 @@
+// Command headless runs the Robo-Stream server without the desktop UI, e.g.
+// on a rack machine or in a container next to OBS. It takes the same
+// settings flags and environment variables as the desktop app and shuts down
+// gracefully on SIGINT or SIGTERM. With --pair it prints a pairing code for a
+// new client, since there's no UI to show one.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/core"
+	"github.com/robomon1/robo-stream/server/internal/logging"
+)
+
+// shutdownTimeout is how long running API requests get to finish
+const shutdownTimeout = 10 * time.Second
+
+var logger = logging.For(logging.App)
+
+func main() {
+	flags := flag.NewFlagSet("robo-stream-server-headless", flag.ContinueOnError)
+	pair := flags.Bool("pair", false, "print a pairing code for a new client at startup")
+	overrides, err := core.ParseSettingOverrides(flags, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	c, err := core.New(overrides)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.Start(); err != nil {
+		log.Fatal(err)
+	}
+	if *pair {
+		printPairingCode(c)
+	}
+
+	<-ctx.Done()
+	logger.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.Stop(shutdownCtx); err != nil {
+		logger.Warn("Shutdown did not complete cleanly", "error", err)
+	}
+}
+
+// printPairingCode creates a pairing code and prints it to stdout rather than
+// the log, which ends up in diagnostics
+func printPairingCode(c *core.Core) {
+	code, err := c.StartPairing()
+	if err != nil {
+		logger.Error("Failed to create pairing code", "error", err)
+		return
+	}
+	fmt.Printf("Pairing code: %s (valid until %s)\n", code.Code, code.ExpiresAt.Format(time.Kitchen))
+	if code.Fingerprint != "" {
+		fmt.Printf("Certificate fingerprint: %s\n", code.Fingerprint)
+	}
+}
@@ -1,6 +1,12 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {declarative} from '../models';
 import {models} from '../models';
+import {manager} from '../models';
+
+export function ApplyDeclarativeConfig(arg1:string,arg2:boolean):Promise<declarative.Plan>;
+
+export function CollectDiagnostics():Promise<string>;
 
 export function ConnectOBS(arg1:string,arg2:string):Promise<void>;
 
@@ -12,12 +18,22 @@ export function DeleteButton(arg1:string):Promise<void>;
 
 export function DeleteConfiguration(arg1:string):Promise<void>;
 
+export function DeleteRole(arg1:string):Promise<void>;
+
+export function DisconnectClient(arg1:string):Promise<void>;
+
 export function DisconnectOBS():Promise<void>;
 
 export function ExecuteAction(arg1:models.ButtonAction):Promise<void>;
 
+export function ExportDeclarativeConfig(arg1:string):Promise<void>;
+
+export function GetAuditLog(arg1:number):Promise<Array<models.AuditEntry>>;
+
 export function GetButton(arg1:string):Promise<models.Button>;
 
+export function GetButtonIssues():Promise<Array<manager.ButtonIssue>>;
+
 export function GetButtons():Promise<Array<models.Button>>;
 
 export function GetConfiguration(arg1:string):Promise<models.Configuration>;
@@ -26,12 +42,26 @@ export function GetConfigurations():Promise<Array<models.Configuration>>;
 
 export function GetDefaultConfiguration():Promise<models.Configuration>;
 
+export function GetInputKinds():Promise<Array<string>>;
+
 export function GetInputs():Promise<Array<string>>;
 
+export function GetLogFile():Promise<string>;
+
 export function GetOBSStatus():Promise<Record<string, any>>;
 
+export function GetPairedDevices():Promise<Array<models.PairedDevice>>;
+
+export function GetRecentLogs(arg1:number,arg2:string):Promise<Array<Record<string, any>>>;
+
+export function GetReloadResults():Promise<Array<manager.ReloadResult>>;
+
+export function GetRoles():Promise<Array<models.Role>>;
+
 export function GetSavedOBSConfig():Promise<models.OBSConfig>;
 
+export function GetSceneItems(arg1:string):Promise<Array<manager.SceneItem>>;
+
 export function GetScenes():Promise<Array<string>>;
 
 export function GetServerInfo():Promise<Record<string, any>>;
@@ -40,18 +70,50 @@ export function GetSession(arg1:string):Promise<models.ClientSession>;
 
 export function GetSessions():Promise<Array<models.ClientSession>>;
 
+export function GetSettingOverrides():Promise<Array<string>>;
+
+export function GetSettings():Promise<models.Settings>;
+
+export function GetSourceFilters(arg1:string):Promise<Array<manager.SourceFilter>>;
+
 export function GetSourceVisibility(arg1:string,arg2:string):Promise<boolean>;
 
+export function GetSpecialInputs():Promise<manager.SpecialInputs>;
+
+export function GetTransitions():Promise<manager.Transitions>;
+
+export function IdentifyClient(arg1:string):Promise<void>;
+
+export function LockClient(arg1:string,arg2:boolean):Promise<void>;
+
+export function PlanDeclarativeConfig(arg1:string,arg2:boolean):Promise<declarative.Plan>;
+
+export function ReloadClient(arg1:string):Promise<void>;
+
+export function RenameClient(arg1:string,arg2:string):Promise<void>;
+
 export function ResolveConfiguration(arg1:string):Promise<models.ResolvedConfiguration>;
 
+export function RevokeDevice(arg1:string):Promise<void>;
+
+export function SaveRole(arg1:models.Role):Promise<void>;
+
 export function SetDefaultConfiguration(arg1:string):Promise<void>;
 
+export function SetSessionRole(arg1:string,arg2:string):Promise<void>;
+
+export function StartPairing():Promise<models.PairingCode>;
+
+export function SwitchClientConfig(arg1:string,arg2:string):Promise<void>;
+
 export function TestBinding(arg1:string):Promise<string>;
 
-export function TestConfiguration(arg1:string):Promise<void>;
+export function TestConfiguration(arg1:string):Promise<manager.ConfigurationReport>;
 
 export function UpdateButton(arg1:models.Button):Promise<void>;
 
 export function UpdateClientConfig(arg1:string,arg2:string):Promise<void>;
 
 export function UpdateConfiguration(arg1:models.Configuration):Promise<void>;
+
+export function UpdateSettings(arg1:models.Settings):Promise<models.Settings>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyDeclarativeConfig(arg1, arg2) {
+  return window['go']['main']['App']['ApplyDeclarativeConfig'](arg1, arg2);
+}
+
+export function CollectDiagnostics() {
+  return window['go']['main']['App']['CollectDiagnostics']();
+}
+
 export function ConnectOBS(arg1, arg2) {
   return window['go']['main']['App']['ConnectOBS'](arg1, arg2);
 }
@@ -22,6 +30,14 @@ export function DeleteConfiguration(arg1) {
   return window['go']['main']['App']['DeleteConfiguration'](arg1);
 }
 
+export function DeleteRole(arg1) {
+  return window['go']['main']['App']['DeleteRole'](arg1);
+}
+
+export function DisconnectClient(arg1) {
+  return window['go']['main']['App']['DisconnectClient'](arg1);
+}
+
 export function DisconnectOBS() {
   return window['go']['main']['App']['DisconnectOBS']();
 }
@@ -30,10 +46,22 @@ export function ExecuteAction(arg1) {
   return window['go']['main']['App']['ExecuteAction'](arg1);
 }
 
+export function ExportDeclarativeConfig(arg1) {
+  return window['go']['main']['App']['ExportDeclarativeConfig'](arg1);
+}
+
+export function GetAuditLog(arg1) {
+  return window['go']['main']['App']['GetAuditLog'](arg1);
+}
+
 export function GetButton(arg1) {
   return window['go']['main']['App']['GetButton'](arg1);
 }
 
+export function GetButtonIssues() {
+  return window['go']['main']['App']['GetButtonIssues']();
+}
+
 export function GetButtons() {
   return window['go']['main']['App']['GetButtons']();
 }
@@ -50,18 +78,46 @@ export function GetDefaultConfiguration() {
   return window['go']['main']['App']['GetDefaultConfiguration']();
 }
 
+export function GetInputKinds() {
+  return window['go']['main']['App']['GetInputKinds']();
+}
+
 export function GetInputs() {
   return window['go']['main']['App']['GetInputs']();
 }
 
+export function GetLogFile() {
+  return window['go']['main']['App']['GetLogFile']();
+}
+
 export function GetOBSStatus() {
   return window['go']['main']['App']['GetOBSStatus']();
 }
 
+export function GetPairedDevices() {
+  return window['go']['main']['App']['GetPairedDevices']();
+}
+
+export function GetRecentLogs(arg1, arg2) {
+  return window['go']['main']['App']['GetRecentLogs'](arg1, arg2);
+}
+
+export function GetReloadResults() {
+  return window['go']['main']['App']['GetReloadResults']();
+}
+
+export function GetRoles() {
+  return window['go']['main']['App']['GetRoles']();
+}
+
 export function GetSavedOBSConfig() {
   return window['go']['main']['App']['GetSavedOBSConfig']();
 }
 
+export function GetSceneItems(arg1) {
+  return window['go']['main']['App']['GetSceneItems'](arg1);
+}
+
 export function GetScenes() {
   return window['go']['main']['App']['GetScenes']();
 }
@@ -78,18 +134,78 @@ export function GetSessions() {
   return window['go']['main']['App']['GetSessions']();
 }
 
+export function GetSettingOverrides() {
+  return window['go']['main']['App']['GetSettingOverrides']();
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
+export function GetSourceFilters(arg1) {
+  return window['go']['main']['App']['GetSourceFilters'](arg1);
+}
+
 export function GetSourceVisibility(arg1, arg2) {
   return window['go']['main']['App']['GetSourceVisibility'](arg1, arg2);
 }
 
+export function GetSpecialInputs() {
+  return window['go']['main']['App']['GetSpecialInputs']();
+}
+
+export function GetTransitions() {
+  return window['go']['main']['App']['GetTransitions']();
+}
+
+export function IdentifyClient(arg1) {
+  return window['go']['main']['App']['IdentifyClient'](arg1);
+}
+
+export function LockClient(arg1, arg2) {
+  return window['go']['main']['App']['LockClient'](arg1, arg2);
+}
+
+export function PlanDeclarativeConfig(arg1, arg2) {
+  return window['go']['main']['App']['PlanDeclarativeConfig'](arg1, arg2);
+}
+
+export function ReloadClient(arg1) {
+  return window['go']['main']['App']['ReloadClient'](arg1);
+}
+
+export function RenameClient(arg1, arg2) {
+  return window['go']['main']['App']['RenameClient'](arg1, arg2);
+}
+
 export function ResolveConfiguration(arg1) {
   return window['go']['main']['App']['ResolveConfiguration'](arg1);
 }
 
+export function RevokeDevice(arg1) {
+  return window['go']['main']['App']['RevokeDevice'](arg1);
+}
+
+export function SaveRole(arg1) {
+  return window['go']['main']['App']['SaveRole'](arg1);
+}
+
 export function SetDefaultConfiguration(arg1) {
   return window['go']['main']['App']['SetDefaultConfiguration'](arg1);
 }
 
+export function SetSessionRole(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionRole'](arg1, arg2);
+}
+
+export function StartPairing() {
+  return window['go']['main']['App']['StartPairing']();
+}
+
+export function SwitchClientConfig(arg1, arg2) {
+  return window['go']['main']['App']['SwitchClientConfig'](arg1, arg2);
+}
+
 export function TestBinding(arg1) {
   return window['go']['main']['App']['TestBinding'](arg1);
 }
@@ -109,3 +225,7 @@ export function UpdateClientConfig(arg1, arg2) {
 export function UpdateConfiguration(arg1) {
   return window['go']['main']['App']['UpdateConfiguration'](arg1);
 }
+
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
@@ -1,5 +1,351 @@
+export namespace declarative {
+	
+	export class Change {
+	    action: string;
+	    kind: string;
+	    slug?: string;
+	    fields?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Change(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.kind = source["kind"];
+	        this.slug = source["slug"];
+	        this.fields = source["fields"];
+	    }
+	}
+	export class Plan {
+	    changes: Change[];
+	    prune: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Plan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.changes = this.convertValues(source["changes"], Change);
+	        this.prune = source["prune"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace manager {
+	
+	export class ButtonCheck {
+	    position: string;
+	    row: number;
+	    col: number;
+	    button_id: string;
+	    button_name?: string;
+	    action_type?: string;
+	    ok: boolean;
+	    problems?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ButtonCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.position = source["position"];
+	        this.row = source["row"];
+	        this.col = source["col"];
+	        this.button_id = source["button_id"];
+	        this.button_name = source["button_name"];
+	        this.action_type = source["action_type"];
+	        this.ok = source["ok"];
+	        this.problems = source["problems"];
+	    }
+	}
+	export class ButtonIssue {
+	    button_id: string;
+	    button_name: string;
+	    problems: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ButtonIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.button_id = source["button_id"];
+	        this.button_name = source["button_name"];
+	        this.problems = source["problems"];
+	    }
+	}
+	export class ConfigurationReport {
+	    config_id: string;
+	    config_name: string;
+	    ok: boolean;
+	    failed: number;
+	    buttons: ButtonCheck[];
+	    // Go type: time
+	    checked_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigurationReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.config_id = source["config_id"];
+	        this.config_name = source["config_name"];
+	        this.ok = source["ok"];
+	        this.failed = source["failed"];
+	        this.buttons = this.convertValues(source["buttons"], ButtonCheck);
+	        this.checked_at = this.convertValues(source["checked_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Conflict {
+	    id: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Conflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ReloadResult {
+	    file: string;
+	    changed: string[];
+	    conflicts: Conflict[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReloadResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.changed = source["changed"];
+	        this.conflicts = this.convertValues(source["conflicts"], Conflict);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SceneItem {
+	    id: number;
+	    source_name: string;
+	    source_type: string;
+	    input_kind?: string;
+	    enabled: boolean;
+	    index: number;
+	    is_group: boolean;
+	    group?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SceneItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.source_name = source["source_name"];
+	        this.source_type = source["source_type"];
+	        this.input_kind = source["input_kind"];
+	        this.enabled = source["enabled"];
+	        this.index = source["index"];
+	        this.is_group = source["is_group"];
+	        this.group = source["group"];
+	    }
+	}
+	export class SourceFilter {
+	    name: string;
+	    kind: string;
+	    enabled: boolean;
+	    index: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SourceFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.enabled = source["enabled"];
+	        this.index = source["index"];
+	    }
+	}
+	export class SpecialInputs {
+	    desktop1: string;
+	    desktop2: string;
+	    mic1: string;
+	    mic2: string;
+	    mic3: string;
+	    mic4: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpecialInputs(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.desktop1 = source["desktop1"];
+	        this.desktop2 = source["desktop2"];
+	        this.mic1 = source["mic1"];
+	        this.mic2 = source["mic2"];
+	        this.mic3 = source["mic3"];
+	        this.mic4 = source["mic4"];
+	    }
+	}
+	export class Transition {
+	    name: string;
+	    kind: string;
+	    fixed: boolean;
+	    configurable: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Transition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.fixed = source["fixed"];
+	        this.configurable = source["configurable"];
+	    }
+	}
+	export class Transitions {
+	    current: string;
+	    transitions: Transition[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Transitions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.current = source["current"];
+	        this.transitions = this.convertValues(source["transitions"], Transition);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace models {
 	
+	export class AuditEntry {
+	    // Go type: time
+	    time: any;
+	    action: string;
+	    message: string;
+	    button_ids?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.action = source["action"];
+	        this.message = source["message"];
+	        this.button_ids = source["button_ids"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ButtonAction {
 	    type: string;
 	    params?: Record<string, any>;
@@ -16,6 +362,7 @@ export namespace models {
 	}
 	export class Button {
 	    id: string;
+	    slug?: string;
 	    name: string;
 	    description: string;
 	    icon: string;
@@ -33,6 +380,7 @@ export namespace models {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.slug = source["slug"];
 	        this.name = source["name"];
 	        this.description = source["description"];
 	        this.icon = source["icon"];
@@ -65,12 +413,19 @@ export namespace models {
 	    session_id: string;
 	    client_id: string;
 	    client_name: string;
+	    device_id?: string;
 	    config_id: string;
+	    role?: string;
+	    locked?: boolean;
 	    ip_address: string;
 	    // Go type: time
 	    last_connected: any;
 	    // Go type: time
 	    last_active: any;
+	    // Go type: time
+	    last_seen: any;
+	    online: boolean;
+	    presence?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ClientSession(source);
@@ -81,10 +436,16 @@ export namespace models {
 	        this.session_id = source["session_id"];
 	        this.client_id = source["client_id"];
 	        this.client_name = source["client_name"];
+	        this.device_id = source["device_id"];
 	        this.config_id = source["config_id"];
+	        this.role = source["role"];
+	        this.locked = source["locked"];
 	        this.ip_address = source["ip_address"];
 	        this.last_connected = this.convertValues(source["last_connected"], null);
 	        this.last_active = this.convertValues(source["last_active"], null);
+	        this.last_seen = this.convertValues(source["last_seen"], null);
+	        this.online = source["online"];
+	        this.presence = source["presence"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -121,11 +482,13 @@ export namespace models {
 	}
 	export class Configuration {
 	    id: string;
+	    slug?: string;
 	    name: string;
 	    description: string;
 	    grid: GridConfig;
 	    buttons: Record<string, string>;
 	    is_default: boolean;
+	    role?: string;
 	    // Go type: time
 	    created_at: any;
 	    // Go type: time
@@ -138,11 +501,13 @@ export namespace models {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.slug = source["slug"];
 	        this.name = source["name"];
 	        this.description = source["description"];
 	        this.grid = this.convertValues(source["grid"], GridConfig);
 	        this.buttons = source["buttons"];
 	        this.is_default = source["is_default"];
+	        this.role = source["role"];
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
 	    }
@@ -168,7 +533,9 @@ export namespace models {
 	
 	export class OBSConfig {
 	    url: string;
-	    password: string;
+	    password?: string;
+	    encrypted_password?: string;
+	    has_password: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new OBSConfig(source);
@@ -178,8 +545,89 @@ export namespace models {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.url = source["url"];
 	        this.password = source["password"];
+	        this.encrypted_password = source["encrypted_password"];
+	        this.has_password = source["has_password"];
 	    }
 	}
+	export class PairedDevice {
+	    id: string;
+	    name: string;
+	    client_id: string;
+	    role?: string;
+	    // Go type: time
+	    paired_at: any;
+	    // Go type: time
+	    last_seen: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new PairedDevice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.client_id = source["client_id"];
+	        this.role = source["role"];
+	        this.paired_at = this.convertValues(source["paired_at"], null);
+	        this.last_seen = this.convertValues(source["last_seen"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PairingCode {
+	    code: string;
+	    // Go type: time
+	    expires_at: any;
+	    qr_code?: string;
+	    fingerprint?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PairingCode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.expires_at = this.convertValues(source["expires_at"], null);
+	        this.qr_code = source["qr_code"];
+	        this.fingerprint = source["fingerprint"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ResolvedButton {
 	    id: string;
 	    row: number;
@@ -258,6 +706,56 @@ export namespace models {
 		    return a;
 		}
 	}
+	export class Role {
+	    name: string;
+	    description: string;
+	    allowed_actions: string[];
+	    allowed_buttons?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Role(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.allowed_actions = source["allowed_actions"];
+	        this.allowed_buttons = source["allowed_buttons"];
+	    }
+	}
+	export class Settings {
+	    listen_address: string;
+	    port: number;
+	    data_dir?: string;
+	    idle_timeout_minutes: number;
+	    session_retention_days: number;
+	    cleanup_interval_minutes: number;
+	    obs_auto_connect: boolean;
+	    log_level: string;
+	    tls: boolean;
+	    tls_cert_file?: string;
+	    tls_key_file?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.listen_address = source["listen_address"];
+	        this.port = source["port"];
+	        this.data_dir = source["data_dir"];
+	        this.idle_timeout_minutes = source["idle_timeout_minutes"];
+	        this.session_retention_days = source["session_retention_days"];
+	        this.cleanup_interval_minutes = source["cleanup_interval_minutes"];
+	        this.obs_auto_connect = source["obs_auto_connect"];
+	        this.log_level = source["log_level"];
+	        this.tls = source["tls"];
+	        this.tls_cert_file = source["tls_cert_file"];
+	        this.tls_key_file = source["tls_key_file"];
+	    }
+	}
 
 }
 
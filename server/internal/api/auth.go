@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// deviceKey is the request context key for the authenticated device
+type deviceKey struct{}
+
+// requireDevice rejects requests without a valid token for a paired device.
+// The token is sent as "Authorization: Bearer <token>"; browsers can't set
+// headers on WebSocket requests, so the token query parameter is accepted too.
+func (s *Server) requireDevice(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if token == "" {
+			s.respondError(w, http.StatusUnauthorized, CodePairingRequired, "pairing required")
+			return
+		}
+
+		deviceID, err := s.signer.Verify(token)
+		if err != nil {
+			s.respondError(w, http.StatusUnauthorized, CodePairingRequired, err.Error())
+			return
+		}
+		device, err := s.sessionManager.Device(deviceID)
+		if err != nil {
+			s.respondError(w, http.StatusUnauthorized, CodeDeviceNotPaired, "device is not paired")
+			return
+		}
+		s.sessionManager.TouchDevice(device.ID)
+
+		ctx := context.WithValue(r.Context(), deviceKey{}, device)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestDevice returns the device authenticated by requireDevice
+func requestDevice(r *http.Request) *models.PairedDevice {
+	device, _ := r.Context().Value(deviceKey{}).(*models.PairedDevice)
+	return device
+}
+
+// requireOperator only lets requests through from a session of the
+// device whose role is operator
+func (s *Server) requireOperator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, ok := s.requestSession(w, r)
+		if !ok {
+			return
+		}
+		role, err := s.sessionRole(session)
+		if err != nil || role.Name != models.RoleOperator {
+			apiLog.Warn("Refused operator request", "path", r.URL.Path, "session", session.SessionID)
+			s.respondFailure(w, forbidden("only operator sessions may do this"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSession returns the session named by the X-Session-ID header (or the
+// session_id query parameter) if it belongs to the authenticated device. On
+// failure the error response has already been written.
+func (s *Server) requestSession(w http.ResponseWriter, r *http.Request) (*models.ClientSession, bool) {
+	sessionID := r.Header.Get("X-Session-ID")
+	if sessionID == "" {
+		sessionID = r.URL.Query().Get("session_id")
+	}
+	if sessionID == "" {
+		s.respondFailure(w, invalidParam("X-Session-ID", "missing X-Session-ID header"))
+		return nil, false
+	}
+
+	session, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, CodeSessionNotFound, "session not found")
+		return nil, false
+	}
+	if device := requestDevice(r); device == nil || session.DeviceID != device.ID {
+		s.respondError(w, http.StatusForbidden, CodeSessionForbidden, "session belongs to another device")
+		return nil, false
+	}
+	return session, true
+}
+
+// pairDevice redeems a pairing code shown in the server UI for a token
+func (s *Server) pairDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code       string `json:"code"`
+		ClientID   string `json:"client_id"`
+		ClientName string `json:"client_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	device, err := s.sessionManager.Pair(strings.TrimSpace(req.Code), req.ClientID, req.ClientName)
+	if errors.Is(err, manager.ErrInvalidPairingCode) {
+		apiLog.Warn("Rejected pairing attempt", "ip", s.getClientIP(r))
+		s.respondError(w, http.StatusUnauthorized, CodeInvalidPairingCode, err.Error())
+		return
+	}
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+
+	apiLog.Info("Paired device", "device", device.Name, "id", device.ID)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"device_id": device.ID,
+		"token":     s.signer.Issue(device.ID),
+	})
+}
+
+// RevokeDevice unpairs a device and closes its open event streams
+func (s *Server) RevokeDevice(deviceID string) error {
+	sessionIDs, err := s.sessionManager.RevokeDevice(deviceID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		s.hub.Disconnect(sessionID)
+	}
+	apiLog.Info("Revoked device", "id", deviceID)
+	return nil
+}
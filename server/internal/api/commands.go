@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Commands the server UI can send to a connected client
+const (
+	CommandSwitchConfig = "switch_config" // params: config_id
+	CommandIdentify     = "identify"      // params: name
+	CommandLock         = "lock"          // params: locked
+	CommandRename       = "rename"        // params: name
+	CommandReload       = "reload"
+	CommandDisconnect   = "disconnect"
+)
+
+// commandTimeout is how long a client has to acknowledge a command
+const commandTimeout = 5 * time.Second
+
+var (
+	// ErrSessionOffline is returned for commands to a session without a live
+	// connection
+	ErrSessionOffline = errors.New("client is offline")
+
+	// ErrCommandTimeout is returned when a client doesn't acknowledge a
+	// command in time
+	ErrCommandTimeout = errors.New("client did not acknowledge the command")
+)
+
+// Command is pushed to a client as the data of a "command" event. The client
+// answers with an "ack" message carrying the same ID.
+type Command struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// commandAck is a client's answer to a command
+type commandAck struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// pendingCommand is a command waiting for the session it was sent to
+type pendingCommand struct {
+	sessionID string
+	ack       chan commandAck
+}
+
+// SendCommand sends a command to a connected session and waits until the
+// client acknowledges it
+func (s *Server) SendCommand(sessionID, name string, params map[string]interface{}) error {
+	if !s.hub.Connected(sessionID) {
+		return ErrSessionOffline
+	}
+
+	cmd := Command{ID: uuid.New().String(), Name: name, Params: params}
+	ack := make(chan commandAck, 1)
+
+	s.pendingMu.Lock()
+	s.pending[cmd.ID] = pendingCommand{sessionID: sessionID, ack: ack}
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, cmd.ID)
+		s.pendingMu.Unlock()
+	}()
+
+	if !s.hub.Send(sessionID, Event{Type: "command", Data: cmd}) {
+		return ErrSessionOffline
+	}
+
+	select {
+	case result := <-ack:
+		if result.Error != "" {
+			return fmt.Errorf("client failed to %s: %s", name, result.Error)
+		}
+		sessionLog.Info("Command acknowledged", "command", name, "session", sessionID)
+		return nil
+	case <-time.After(commandTimeout):
+		return ErrCommandTimeout
+	}
+}
+
+// Online reports whether a session holds a live events connection
+func (s *Server) Online(sessionID string) bool {
+	return s.hub.Connected(sessionID)
+}
+
+// DisconnectSession closes a session's events connection
+func (s *Server) DisconnectSession(sessionID string) {
+	s.hub.Disconnect(sessionID)
+}
+
+// commandAcked hands a client's ack to the command waiting for it
+func (s *Server) commandAcked(sessionID string, data json.RawMessage) {
+	var ack commandAck
+	if err := json.Unmarshal(data, &ack); err != nil {
+		sessionLog.Warn("Invalid ack", "session", sessionID, "error", err)
+		return
+	}
+
+	s.pendingMu.Lock()
+	waiting, ok := s.pending[ack.ID]
+	s.pendingMu.Unlock()
+	if !ok {
+		return
+	}
+	// Only the session the command went to may answer it
+	if waiting.sessionID != sessionID {
+		sessionLog.Warn("Ignoring ack for another session's command", "session", sessionID, "command", ack.ID)
+		return
+	}
+	// A session may hold several connections; the first answer wins
+	select {
+	case waiting.ack <- ack:
+	default:
+	}
+}
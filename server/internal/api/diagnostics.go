@@ -0,0 +1,46 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DiagnosticsFileName suggests a name for a diagnostics archive
+func DiagnosticsFileName(now time.Time) string {
+	return fmt.Sprintf("robo-stream-diagnostics-%s.zip", now.Format("20060102-150405"))
+}
+
+// SetDiagnostics sets what writes the archive served at /diagnostics
+func (s *Server) SetDiagnostics(collect func(w io.Writer) error) {
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	s.diagnostics = collect
+}
+
+// getDiagnostics serves a diagnostics archive for troubleshooting
+func (s *Server) getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	s.diagnosticsMu.Lock()
+	collect := s.diagnostics
+	s.diagnosticsMu.Unlock()
+
+	if collect == nil {
+		s.respondError(w, http.StatusServiceUnavailable, CodeInternal, "diagnostics are not available")
+		return
+	}
+
+	// Collect first so a failure can still be reported as an error
+	var archive bytes.Buffer
+	if err := collect(&archive); err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", DiagnosticsFileName(time.Now())))
+	w.Header().Set("Content-Length", fmt.Sprint(archive.Len()))
+	w.WriteHeader(http.StatusOK)
+	archive.WriteTo(w)
+}
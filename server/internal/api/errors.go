@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// Error codes sent with every error response. Clients should match on the
+// code; messages are for people and may change.
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"      // the body or message can't be decoded
+	CodeInvalidParam       = "INVALID_PARAM"        // a parameter is missing or wrong; see field
+	CodePairingRequired    = "PAIRING_REQUIRED"     // no token, or one the server didn't issue
+	CodeDeviceNotPaired    = "DEVICE_NOT_PAIRED"    // the token's device was revoked
+	CodeInvalidPairingCode = "INVALID_PAIRING_CODE" // wrong or expired pairing code
+	CodeSessionNotFound    = "SESSION_NOT_FOUND"    // register again
+	CodeSessionForbidden   = "SESSION_FORBIDDEN"    // the session belongs to another device
+	CodeConfigNotFound     = "CONFIG_NOT_FOUND"     // e.g. deleted
+	CodeButtonNotFound     = "BUTTON_NOT_FOUND"     // no button at button_id
+	CodeClientLocked       = "CLIENT_LOCKED"        // locked from the server UI
+	CodeActionForbidden    = "ACTION_FORBIDDEN"     // the session's role doesn't allow it
+	CodeUnknownAction      = "UNKNOWN_ACTION"       // unknown or unsupported action type
+	CodeOBSNotConnected    = "OBS_NOT_CONNECTED"    // the server isn't connected to OBS
+	CodeOBSRequestFailed   = "OBS_REQUEST_FAILED"   // OBS refused the request; see obs_status
+	CodeOBSTimeout         = "OBS_TIMEOUT"          // OBS didn't answer in time; the action may still happen
+	CodeCanceled           = "CANCELED"             // the client went away or the server is stopping
+	CodeInternal           = "INTERNAL"             // anything else
+)
+
+// statusClientClosed is the non-standard status for requests the client
+// gave up on, as nginx logs them
+const statusClientClosed = 499
+
+// apiError is the body of an error response. Failed replies over the
+// WebSocket carry the same fields.
+type apiError struct {
+	status int
+
+	Code    string `json:"code"`
+	Message string `json:"error"`
+	// Field names the offending parameter for INVALID_PARAM
+	Field string `json:"field,omitempty"`
+	// OBSStatus is the OBS WebSocket request status for OBS_REQUEST_FAILED,
+	// if OBS answered
+	OBSStatus int `json:"obs_status,omitempty"`
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+// newError returns an API error
+func newError(status int, code, message string) *apiError {
+	return &apiError{status: status, Code: code, Message: message}
+}
+
+// invalidParam returns an INVALID_PARAM error for a field
+func invalidParam(field, message string) *apiError {
+	return &apiError{status: http.StatusBadRequest, Code: CodeInvalidParam, Message: message, Field: field}
+}
+
+// errorFor turns an error from the managers into an API error
+func errorFor(err error) *apiError {
+	var apiErr *apiError
+	var paramErr *manager.ParamError
+	var requestErr *manager.OBSRequestError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &paramErr):
+		return invalidParam(paramErr.Param, err.Error())
+	case errors.Is(err, manager.ErrSessionForbidden):
+		return newError(http.StatusForbidden, CodeSessionForbidden, err.Error())
+	case errors.Is(err, manager.ErrOBSNotConnected):
+		return newError(http.StatusServiceUnavailable, CodeOBSNotConnected, err.Error())
+	case errors.Is(err, manager.ErrUnknownAction), errors.Is(err, manager.ErrUnsupportedAction):
+		return newError(http.StatusBadRequest, CodeUnknownAction, err.Error())
+	case errors.Is(err, manager.ErrOBSTimeout):
+		return newError(http.StatusGatewayTimeout, CodeOBSTimeout, err.Error())
+	case errors.Is(err, context.Canceled):
+		return newError(statusClientClosed, CodeCanceled, err.Error())
+	case errors.As(err, &requestErr):
+		e := newError(http.StatusBadGateway, CodeOBSRequestFailed, err.Error())
+		e.OBSStatus = requestErr.Status
+		return e
+	default:
+		return newError(http.StatusInternalServerError, CodeInternal, err.Error())
+	}
+}
+
+// event returns a failed reply to a WebSocket message
+func (e *apiError) event(eventType, id string) Event {
+	return Event{Type: eventType, ID: id, Error: e.Message, Code: e.Code, Field: e.Field, OBSStatus: e.OBSStatus}
+}
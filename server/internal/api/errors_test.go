@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// TestErrorForGivenUp checks the codes clients get for actions the server
+// gave up on
+func TestErrorForGivenUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "timeout", err: manager.ErrOBSTimeout, wantStatus: http.StatusGatewayTimeout, wantCode: CodeOBSTimeout},
+		{name: "wrapped timeout", err: fmt.Errorf("toggle_studio_mode: %w", manager.ErrOBSTimeout), wantStatus: http.StatusGatewayTimeout, wantCode: CodeOBSTimeout},
+		{name: "canceled", err: context.Canceled, wantStatus: statusClientClosed, wantCode: CodeCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := errorFor(tt.err)
+			if apiErr.status != tt.wantStatus || apiErr.Code != tt.wantCode {
+				t.Errorf("errorFor(%v) = %d %s, want %d %s", tt.err, apiErr.status, apiErr.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
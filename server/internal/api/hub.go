@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write a message to a client
+	writeWait = 10 * time.Second
+
+	// pongWait is the time allowed to read the next pong from a client
+	pongWait = 60 * time.Second
+
+	// pingPeriod is how often we ping clients; must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Clients connect from apps and other origins on the LAN
+	},
+}
+
+// Event is a message pushed to connected clients. Replies to a client
+// message carry the message's ID; failed ones carry the fields of an error
+// response (see errors.go).
+type Event struct {
+	Type      string      `json:"type"`
+	ID        string      `json:"id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Field     string      `json:"field,omitempty"`
+	OBSStatus int         `json:"obs_status,omitempty"`
+}
+
+// clientMessage is a message sent by a client over its connection
+type clientMessage struct {
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// hubClient is one WebSocket connection belonging to a session
+type hubClient struct {
+	hub       *Hub
+	sessionID string
+	conn      *websocket.Conn
+	send      chan []byte
+
+	// ctx is cancelled when the connection closes, stopping the actions
+	// it's waiting on
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// topics the connection subscribed to
+	topicsMu sync.Mutex
+	topics   map[string]bool
+}
+
+// hubEvents is told about connections and what clients send. The hub calls
+// it without holding its lock.
+type hubEvents interface {
+	// sessionConnected is called for each new connection
+	sessionConnected(sessionID string)
+	// sessionDisconnected is called when a connection closes
+	sessionDisconnected(sessionID string)
+	// sessionSeen is called for every pong and message, i.e. heartbeat
+	sessionSeen(sessionID string)
+	// sessionMessage handles a message sent by a client on one of its
+	// connections
+	sessionMessage(client *hubClient, msg clientMessage)
+}
+
+// Hub tracks clients connected over WebSocket so the server can push to them
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*hubClient]bool // session ID -> connections
+	events  hubEvents
+}
+
+// NewHub creates a new Hub reporting to events
+func NewHub(events hubEvents) *Hub {
+	return &Hub{
+		clients: make(map[string]map[*hubClient]bool),
+		events:  events,
+	}
+}
+
+// Serve upgrades the request and keeps the connection registered for the
+// session until it closes
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, sessionID string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		apiLog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	h.serveConn(conn, sessionID)
+}
+
+// serveConn keeps an upgraded connection registered for the session until
+// it closes
+func (h *Hub) serveConn(conn *websocket.Conn, sessionID string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &hubClient{
+		hub:       h,
+		sessionID: sessionID,
+		conn:      conn,
+		send:      make(chan []byte, 64),
+		ctx:       ctx,
+		cancel:    cancel,
+		topics:    make(map[string]bool),
+	}
+	h.register(client)
+	h.events.sessionConnected(sessionID)
+
+	go client.writePump()
+	client.readPump()
+}
+
+// Send pushes an event to every connection of a session. It returns false
+// if the session has no live connection.
+func (h *Hub) Send(sessionID string, event Event) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		apiLog.Error("Failed to encode event", "type", event.Type, "error", err)
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := h.clients[sessionID]
+	for client := range clients {
+		client.enqueue(event.Type, data)
+	}
+	return len(clients) > 0
+}
+
+// Publish pushes an event to the connections subscribed to a topic, or only
+// to those of one session if sessionID is set
+func (h *Hub) Publish(topic, sessionID string, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		apiLog.Error("Failed to encode event", "type", event.Type, "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for id, clients := range h.clients {
+		if sessionID != "" && id != sessionID {
+			continue
+		}
+		for client := range clients {
+			if client.subscribed(topic) {
+				client.enqueue(event.Type, data)
+			}
+		}
+	}
+}
+
+// Subscribers returns the sessions with a connection subscribed to a topic
+func (h *Hub) Subscribers(topic string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var sessionIDs []string
+	for sessionID, clients := range h.clients {
+		for client := range clients {
+			if client.subscribed(topic) {
+				sessionIDs = append(sessionIDs, sessionID)
+				break
+			}
+		}
+	}
+	return sessionIDs
+}
+
+// Connected reports whether a session has a live connection
+func (h *Hub) Connected(sessionID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[sessionID]) > 0
+}
+
+// Disconnect closes every connection of a session
+func (h *Hub) Disconnect(sessionID string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients[sessionID] {
+		client.conn.Close()
+	}
+}
+
+// register adds a connection to the hub
+func (h *Hub) register(client *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[client.sessionID] == nil {
+		h.clients[client.sessionID] = make(map[*hubClient]bool)
+	}
+	h.clients[client.sessionID][client] = true
+}
+
+// unregister removes a connection from the hub and stops its writer
+func (h *Hub) unregister(client *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.clients[client.sessionID]
+	if !clients[client] {
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.clients, client.sessionID)
+	}
+	close(client.send)
+}
+
+// readPump reads client messages from the connection until it closes
+func (c *hubClient) readPump() {
+	defer func() {
+		c.cancel()
+		c.hub.unregister(c)
+		c.conn.Close()
+		c.hub.events.sessionDisconnected(c.sessionID)
+	}()
+
+	// A client that stops answering pings and sending heartbeats is dropped
+	// once pongWait passes
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.hub.events.sessionSeen(c.sessionID)
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				apiLog.Warn("WebSocket error", "session", c.sessionID, "error", err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.hub.events.sessionSeen(c.sessionID)
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			apiLog.Warn("Ignoring invalid message", "session", c.sessionID, "error", err)
+			continue
+		}
+		c.hub.events.sessionMessage(c, msg)
+	}
+}
+
+// reply sends an event to this connection only
+func (c *hubClient) reply(event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		apiLog.Error("Failed to encode event", "type", event.Type, "error", err)
+		return
+	}
+
+	// The hub lock keeps the send channel open while we queue
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if c.hub.clients[c.sessionID][c] {
+		c.enqueue(event.Type, data)
+	}
+}
+
+// enqueue queues an encoded event, dropping it if the client is too slow.
+// The caller holds the hub lock.
+func (c *hubClient) enqueue(eventType string, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		apiLog.Warn("Dropping event for slow client", "type", eventType, "session", c.sessionID)
+	}
+}
+
+// subscribe adds topics to the connection's subscriptions
+func (c *hubClient) subscribe(topics []string) {
+	c.topicsMu.Lock()
+	defer c.topicsMu.Unlock()
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+}
+
+// unsubscribe removes topics from the connection's subscriptions
+func (c *hubClient) unsubscribe(topics []string) {
+	c.topicsMu.Lock()
+	defer c.topicsMu.Unlock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+}
+
+// subscribed reports whether the connection subscribed to a topic
+func (c *hubClient) subscribed(topic string) bool {
+	c.topicsMu.Lock()
+	defer c.topicsMu.Unlock()
+	return c.topics[topic]
+}
+
+// writePump writes queued events and keepalive pings to the connection
+func (c *hubClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import "net/http"
+
+// getSceneItems returns the sources in a scene, including those in groups
+func (s *Server) getSceneItems(w http.ResponseWriter, r *http.Request) {
+	scene := r.URL.Query().Get("scene")
+	if scene == "" {
+		s.respondFailure(w, invalidParam("scene", "missing scene parameter"))
+		return
+	}
+
+	items, err := s.obsManager.SceneItems(scene)
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, items)
+}
+
+// getSourceFilters returns the filters on a scene or input
+func (s *Server) getSourceFilters(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		s.respondFailure(w, invalidParam("source", "missing source parameter"))
+		return
+	}
+
+	filters, err := s.obsManager.SourceFilters(source)
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, filters)
+}
+
+// getTransitions returns the scene transitions and the current one
+func (s *Server) getTransitions(w http.ResponseWriter, r *http.Request) {
+	transitions, err := s.obsManager.Transitions()
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, transitions)
+}
+
+// getInputKinds returns the kinds of inputs OBS can create
+func (s *Server) getInputKinds(w http.ResponseWriter, r *http.Request) {
+	kinds, err := s.obsManager.InputKinds()
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, kinds)
+}
+
+// getSpecialInputs returns the global desktop and mic inputs
+func (s *Server) getSpecialInputs(w http.ResponseWriter, r *http.Request) {
+	special, err := s.obsManager.SpecialInputs()
+	if err != nil {
+		s.respondFailure(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, special)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/robomon1/robo-stream/server/internal/logging"
+)
+
+// defaultLogLines is how many log lines are returned without a limit
+const defaultLogLines = 200
+
+// getLogs returns recent server log lines as newline-delimited JSON, oldest
+// first, optionally from a level up. With follow=true the response stays
+// open and new lines are streamed as they are logged.
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultLogLines
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			s.respondFailure(w, invalidParam("limit", "limit must be a positive number"))
+			return
+		}
+		limit = n
+	}
+
+	min := slog.LevelDebug
+	if value := query.Get("level"); value != "" {
+		l, err := logging.ParseLevel(value)
+		if err != nil {
+			s.respondFailure(w, invalidParam("level", err.Error()))
+			return
+		}
+		min = l
+	}
+
+	follow := query.Get("follow") == "true"
+	flusher, canFlush := w.(http.Flusher)
+	if follow && !canFlush {
+		s.respondError(w, http.StatusInternalServerError, CodeInternal, "streaming is not supported")
+		return
+	}
+
+	// Subscribe first so no line is missed between the two
+	var lines <-chan json.RawMessage
+	if follow {
+		var unsubscribe func()
+		lines, unsubscribe = logging.Subscribe()
+		defer unsubscribe()
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, line := range logging.Recent(limit, min) {
+		writeLogLine(w, line)
+	}
+	if !follow {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case line := <-lines:
+			if !logging.AtLeast(line, min) {
+				continue
+			}
+			if err := writeLogLine(w, line); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeLogLine writes one line of newline-delimited JSON
+func writeLogLine(w http.ResponseWriter, line json.RawMessage) error {
+	_, err := w.Write(append(line[:len(line):len(line)], '\n'))
+	return err
+}
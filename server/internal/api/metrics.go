@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// metricsNamespace prefixes every metric name
+const metricsNamespace = "robostream"
+
+// metrics holds the Prometheus metrics served at /metrics
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	actions         *prometheus.CounterVec
+	actionDuration  *prometheus.HistogramVec
+}
+
+// newMetrics registers the server's metrics
+func newMetrics(s *Server) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "API requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "API request latency by route and method, WebSocket connections excluded.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		actions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "actions_total",
+			Help:      "Button actions by type and result, which is ok or an error code.",
+		}, []string{"type", "result"}),
+		actionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "action_duration_seconds",
+			Help:      "Time from a button press reaching the server to OBS answering, by action type.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.actions,
+		m.actionDuration,
+		&serverCollector{s: s},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the metrics
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// actionDone records a finished action
+func (m *metrics) actionDone(actionType string, apiErr *apiError, elapsed time.Duration) {
+	switch {
+	case actionType == "":
+		actionType = "none"
+	case !manager.KnownAction(actionType):
+		// Keep clients from creating unbounded series
+		actionType = "other"
+	}
+	result := "ok"
+	if apiErr != nil {
+		result = apiErr.Code
+	}
+	m.actions.WithLabelValues(actionType, result).Inc()
+	m.actionDuration.WithLabelValues(actionType).Observe(elapsed.Seconds())
+}
+
+// instrument counts requests and measures their latency by route
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		code := strconv.Itoa(sw.status)
+		if sw.hijacked {
+			code = "101"
+		}
+		m.requests.WithLabelValues(route, r.Method, code).Inc()
+		if !sw.hijacked {
+			m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		}
+	})
+}
+
+// statusWriter records the status code of a response. It passes through
+// hijacking so WebSocket upgrades still work.
+type statusWriter struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.hijacked = true
+	return hijacker.Hijack()
+}
+
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Descriptions of the metrics read when scraped; see serverDescs
+var (
+	obsConnectedDesc       = metricDesc("obs_connected", "Whether the server is connected to OBS.")
+	obsConnectsDesc        = metricDesc("obs_connects_total", "Successful connections to OBS; more than one means OBS was reconnected.")
+	obsConnectFailuresDesc = metricDesc("obs_connect_failures_total", "Failed attempts to connect to OBS.")
+	sessionsDesc           = metricDesc("sessions", "Client sessions the server keeps.")
+	sessionsOnlineDesc     = metricDesc("sessions_online", "Client sessions with a connected client.")
+
+	obsCPUDesc              = metricDesc("obs_cpu_usage_percent", "CPU usage of OBS.")
+	obsMemoryDesc           = metricDesc("obs_memory_usage_bytes", "Memory used by OBS.")
+	obsDiskSpaceDesc        = metricDesc("obs_available_disk_space_bytes", "Free space on the recording disk.")
+	obsFPSDesc              = metricDesc("obs_active_fps", "Frames per second OBS is rendering.")
+	obsFrameRenderDesc      = metricDesc("obs_frame_render_seconds", "Average time OBS takes to render a frame.")
+	obsRenderSkippedDesc    = metricDesc("obs_render_skipped_frames_total", "Frames skipped by the render thread, from GPU lag.")
+	obsRenderFramesDesc     = metricDesc("obs_render_frames_total", "Frames rendered.")
+	obsEncoderSkippedDesc   = metricDesc("obs_encoder_skipped_frames_total", "Frames skipped by the output thread, from encoder lag.")
+	obsEncoderFramesDesc    = metricDesc("obs_encoder_frames_total", "Frames output.")
+	obsStreamingDesc        = metricDesc("obs_streaming", "Whether OBS is streaming.")
+	obsReconnectingDesc     = metricDesc("obs_stream_reconnecting", "Whether the stream is reconnecting.")
+	obsStreamBytesDesc      = metricDesc("obs_stream_output_bytes", "Bytes sent by the current stream.")
+	obsStreamBitrateDesc    = metricDesc("obs_stream_bitrate_bits_per_second", "Stream output bitrate since the previous scrape.")
+	obsStreamDroppedDesc    = metricDesc("obs_stream_dropped_frames", "Frames the current stream dropped, e.g. on a bad network.")
+	obsStreamFramesDesc     = metricDesc("obs_stream_frames", "Frames sent by the current stream.")
+	obsStreamCongestionDesc = metricDesc("obs_stream_congestion_ratio", "Congestion of the stream output, from 0 to 1.")
+	obsStreamDurationDesc   = metricDesc("obs_stream_duration_seconds", "How long the current stream has been live.")
+	obsRecordingDesc        = metricDesc("obs_recording", "Whether OBS is recording.")
+	obsRecordBytesDesc      = metricDesc("obs_record_output_bytes", "Bytes written by the current recording.")
+	obsRecordBitrateDesc    = metricDesc("obs_record_bitrate_bits_per_second", "Recording bitrate since the previous scrape.")
+	obsRecordDurationDesc   = metricDesc("obs_record_duration_seconds", "How long the current recording has been running.")
+)
+
+// serverDescs lists the metrics serverCollector reports. The OBS stats are
+// only there while connected, so they can't be described by collecting.
+var serverDescs = []*prometheus.Desc{
+	obsConnectedDesc, obsConnectsDesc, obsConnectFailuresDesc, sessionsDesc, sessionsOnlineDesc,
+	obsCPUDesc, obsMemoryDesc, obsDiskSpaceDesc, obsFPSDesc, obsFrameRenderDesc,
+	obsRenderSkippedDesc, obsRenderFramesDesc, obsEncoderSkippedDesc, obsEncoderFramesDesc,
+	obsStreamingDesc, obsReconnectingDesc, obsStreamBytesDesc, obsStreamBitrateDesc,
+	obsStreamDroppedDesc, obsStreamFramesDesc, obsStreamCongestionDesc, obsStreamDurationDesc,
+	obsRecordingDesc, obsRecordBytesDesc, obsRecordBitrateDesc, obsRecordDurationDesc,
+}
+
+// metricDesc describes an unlabelled metric
+func metricDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, nil, nil)
+}
+
+// serverCollector reads OBS and session state when scraped
+type serverCollector struct {
+	s *Server
+}
+
+func (c *serverCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range serverDescs {
+		ch <- desc
+	}
+}
+
+func (c *serverCollector) Collect(ch chan<- prometheus.Metric) {
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	connects, failures := c.s.obsManager.ConnectionCounts()
+	gauge(obsConnectedDesc, boolValue(c.s.obsManager.IsConnected()))
+	counter(obsConnectsDesc, float64(connects))
+	counter(obsConnectFailuresDesc, float64(failures))
+
+	sessions := c.s.sessionManager.List()
+	online := 0
+	for _, session := range sessions {
+		if c.s.Online(session.SessionID) {
+			online++
+		}
+	}
+	gauge(sessionsDesc, float64(len(sessions)))
+	gauge(sessionsOnlineDesc, float64(online))
+
+	stats, err := c.s.obsManager.Stats()
+	if errors.Is(err, manager.ErrOBSNotConnected) {
+		return
+	}
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(obsCPUDesc, err)
+		return
+	}
+	gauge(obsCPUDesc, stats.CPUUsage)
+	gauge(obsMemoryDesc, stats.MemoryMB*1024*1024)
+	gauge(obsDiskSpaceDesc, stats.AvailableDiskSpaceMB*1024*1024)
+	gauge(obsFPSDesc, stats.ActiveFPS)
+	gauge(obsFrameRenderDesc, stats.AverageFrameRenderMs/1000)
+	counter(obsRenderSkippedDesc, stats.RenderSkippedFrames)
+	counter(obsRenderFramesDesc, stats.RenderTotalFrames)
+	counter(obsEncoderSkippedDesc, stats.EncoderSkippedFrames)
+	counter(obsEncoderFramesDesc, stats.EncoderTotalFrames)
+	gauge(obsStreamingDesc, boolValue(stats.Streaming))
+	gauge(obsReconnectingDesc, boolValue(stats.StreamReconnecting))
+	gauge(obsStreamBytesDesc, stats.StreamBytes)
+	gauge(obsStreamBitrateDesc, stats.StreamBitrate)
+	gauge(obsStreamDroppedDesc, stats.StreamDroppedFrames)
+	gauge(obsStreamFramesDesc, stats.StreamTotalFrames)
+	gauge(obsStreamCongestionDesc, stats.StreamCongestion)
+	gauge(obsStreamDurationDesc, stats.StreamDurationSeconds)
+	gauge(obsRecordingDesc, boolValue(stats.Recording))
+	gauge(obsRecordBytesDesc, stats.RecordBytes)
+	gauge(obsRecordBitrateDesc, stats.RecordBitrate)
+	gauge(obsRecordDurationDesc, stats.RecordDurationSeconds)
+}
+
+// boolValue turns a flag into a gauge value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
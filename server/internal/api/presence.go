@@ -0,0 +1,27 @@
+package api
+
+// sessionConnected records that a session's client connected
+func (s *Server) sessionConnected(sessionID string) {
+	if err := s.sessionManager.MarkSeen(sessionID); err != nil {
+		sessionLog.Warn("Failed to record connection", "session", sessionID, "error", err)
+		return
+	}
+	sessionLog.Info("Session connected", "session", sessionID)
+}
+
+// sessionDisconnected records when a session's client was last there once
+// its last connection closes. The session and its configuration are kept.
+func (s *Server) sessionDisconnected(sessionID string) {
+	if s.hub.Connected(sessionID) {
+		return
+	}
+	if err := s.sessionManager.MarkSeen(sessionID); err != nil {
+		return // removed, e.g. by revoking its device
+	}
+	sessionLog.Info("Session disconnected", "session", sessionID)
+}
+
+// sessionSeen records a heartbeat from a session's client
+func (s *Server) sessionSeen(sessionID string) {
+	s.sessionManager.Heartbeat(sessionID)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// actionRequest is the body of an action request. Clients name the button
+// they pressed by its grid position (the resolved button ID) so per-button
+// restrictions can be enforced; the button's own action is then run instead
+// of the one sent.
+type actionRequest struct {
+	models.ButtonAction
+	ButtonID string `json:"button_id,omitempty"`
+}
+
+// sessionRole returns the role in effect for a session: its own, else its
+// configuration's, else operator, but never more than its device's role
+func (s *Server) sessionRole(session *models.ClientSession) (*models.Role, error) {
+	name := session.Role
+	if name == "" {
+		if cfg, err := s.configManager.Get(session.ConfigID); err == nil {
+			name = cfg.Role
+		}
+	}
+	if name == "" {
+		name = models.RoleOperator
+	}
+	role, err := s.roleManager.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := s.sessionManager.Device(session.DeviceID)
+	if err != nil || device.Role == "" || device.Role == role.Name {
+		return role, nil
+	}
+	deviceRole, err := s.roleManager.Get(device.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Within(deviceRole) {
+		return deviceRole, nil
+	}
+	return role, nil
+}
+
+// pinDeviceRole caps the session's device at the session's role, so other
+// client IDs or configurations on it get no more
+func (s *Server) pinDeviceRole(session *models.ClientSession) {
+	role, err := s.sessionRole(session)
+	if err != nil {
+		return
+	}
+	if err := s.sessionManager.PinDeviceRole(session.DeviceID, role.Name); err != nil {
+		sessionLog.Warn("Failed to save device role", "device", session.DeviceID, "error", err)
+	}
+}
+
+// checkSwitchRole refuses a client's switch to a configuration that would
+// give the session a broader role than it has
+func (s *Server) checkSwitchRole(session *models.ClientSession, configID string) *apiError {
+	before, err := s.sessionRole(session)
+	if err != nil {
+		return forbidden(err.Error())
+	}
+	switched := *session
+	switched.ConfigID = configID
+	after, err := s.sessionRole(&switched)
+	if err != nil {
+		return forbidden(err.Error())
+	}
+	if !after.Within(before) {
+		return forbidden(fmt.Sprintf("switching would change role %q to the broader %q", before.Name, after.Name))
+	}
+	return nil
+}
+
+// sessionRoleName returns the name of the session's role, so clients can
+// grey out what they may not use
+func (s *Server) sessionRoleName(session *models.ClientSession) string {
+	role, err := s.sessionRole(session)
+	if err != nil {
+		return ""
+	}
+	return role.Name
+}
+
+// authorizeAction checks an action request against the session's role and
+// returns the action to run
+func (s *Server) authorizeAction(session *models.ClientSession, req actionRequest) (models.ButtonAction, *apiError) {
+	role, err := s.sessionRole(session)
+	if err != nil {
+		return models.ButtonAction{}, forbidden(err.Error())
+	}
+
+	action := req.ButtonAction
+	libraryID := ""
+	if req.ButtonID != "" {
+		libraryID, action, err = s.sessionButton(session, req.ButtonID)
+		if err != nil {
+			apiErr := newError(http.StatusNotFound, CodeButtonNotFound, err.Error())
+			apiErr.Field = "button_id"
+			return models.ButtonAction{}, apiErr
+		}
+	}
+
+	if len(role.AllowedButtons) > 0 {
+		if req.ButtonID == "" {
+			return models.ButtonAction{}, forbidden(fmt.Sprintf("role %q only allows specific buttons, but no button_id was sent", role.Name))
+		}
+		if !role.AllowsButton(libraryID) {
+			return models.ButtonAction{}, forbidden(fmt.Sprintf("role %q may not use this button", role.Name))
+		}
+	}
+	if !role.AllowsAction(action.Type) {
+		return models.ButtonAction{}, forbidden(fmt.Sprintf("role %q may not run %s actions", role.Name, action.Type))
+	}
+	return action, nil
+}
+
+// forbidden returns an ACTION_FORBIDDEN error
+func forbidden(message string) *apiError {
+	return newError(http.StatusForbidden, CodeActionForbidden, message)
+}
+
+// sessionButton finds a button in the session's configuration by position and
+// returns its library button ID and action
+func (s *Server) sessionButton(session *models.ClientSession, position string) (string, models.ButtonAction, error) {
+	cfg, err := s.configManager.Get(session.ConfigID)
+	if err != nil {
+		return "", models.ButtonAction{}, err
+	}
+	resolved, err := s.configManager.Resolve(session.ConfigID)
+	if err != nil {
+		return "", models.ButtonAction{}, err
+	}
+	for _, button := range resolved.Buttons {
+		if button.ID == position {
+			return cfg.Buttons[position], button.Action, nil
+		}
+	}
+	return "", models.ButtonAction{}, fmt.Errorf("no button at %s in this session's configuration", position)
+}
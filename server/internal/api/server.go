@@ -1,66 +1,140 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/robomon1/robo-stream/server/internal/auth"
+	"github.com/robomon1/robo-stream/server/internal/logging"
 	"github.com/robomon1/robo-stream/server/internal/manager"
 	"github.com/robomon1/robo-stream/server/internal/models"
 )
 
+var (
+	apiLog     = logging.For(logging.API)
+	sessionLog = logging.For(logging.Session)
+)
+
 // Server provides HTTP API for clients
 type Server struct {
-	router         *mux.Router
-	configManager  *manager.ConfigManager
-	sessionManager *manager.SessionManager
-	obsManager     *manager.OBSManager
+	router            *mux.Router
+	configManager     *manager.ConfigManager
+	sessionManager    *manager.SessionManager
+	assignmentManager *manager.AssignmentManager
+	roleManager       *manager.RoleManager
+	obsManager        *manager.OBSManager
+	signer            *auth.Signer
+	hub               *Hub
+	metrics           *metrics
+
+	// pending holds commands waiting for the client to acknowledge them
+	pendingMu sync.Mutex
+	pending   map[string]pendingCommand
+
+	// publishOnce starts pushing status and state to subscribers
+	publishOnce sync.Once
+
+	httpMu     sync.Mutex
+	httpServer *http.Server
+
+	// diagnostics writes the archive served at /diagnostics
+	diagnosticsMu sync.Mutex
+	diagnostics   func(w io.Writer) error
 }
 
 // NewServer creates a new API server
 func NewServer(
 	cm *manager.ConfigManager,
 	sm *manager.SessionManager,
+	am *manager.AssignmentManager,
+	rm *manager.RoleManager,
 	om *manager.OBSManager,
+	signer *auth.Signer,
 ) *Server {
 	s := &Server{
-		router:         mux.NewRouter(),
-		configManager:  cm,
-		sessionManager: sm,
-		obsManager:     om,
+		router:            mux.NewRouter(),
+		configManager:     cm,
+		sessionManager:    sm,
+		assignmentManager: am,
+		roleManager:       rm,
+		obsManager:        om,
+		signer:            signer,
+		pending:           make(map[string]pendingCommand),
 	}
+	s.hub = NewHub(s)
+	s.metrics = newMetrics(s)
 	s.setupRoutes()
 	return s
 }
 
-// setupRoutes configures API routes
+// setupRoutes configures API routes. The API is served under /api/v1 and,
+// for clients from before it was versioned, under /api.
 func (s *Server) setupRoutes() {
 	// Enable CORS
 	s.router.Use(s.corsMiddleware)
+	s.router.Use(s.metrics.instrument)
+
+	// Prometheus metrics, open like the health check
+	s.router.Handle("/metrics", s.metrics.handler()).Methods("GET")
+
+	s.apiRoutes("/api/v1")
+	s.apiRoutes("/api")
+}
+
+// apiRoutes adds the API routes under a path prefix
+func (s *Server) apiRoutes(prefix string) {
+	// Open endpoints
+	s.router.HandleFunc(prefix+"/health", s.healthCheck).Methods("GET", "OPTIONS")
+	s.router.HandleFunc(prefix+"/pair", s.pairDevice).Methods("POST", "OPTIONS")
+
+	// WebSocket protocol, authenticated by its first message (see PROTOCOL.md)
+	s.router.HandleFunc(prefix+"/ws", s.clientWebSocket).Methods("GET")
+
+	// Everything else needs a paired device's token
+	api := s.router.PathPrefix(prefix).Subrouter()
+	api.Use(s.requireDevice)
 
 	// Configuration endpoints
-	s.router.HandleFunc("/api/configurations", s.listConfigurations).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/configurations/default", s.getDefaultConfiguration).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/configurations/{id}", s.getConfiguration).Methods("GET", "OPTIONS")
+	api.HandleFunc("/configurations", s.listConfigurations).Methods("GET", "OPTIONS")
+	api.HandleFunc("/configurations/default", s.getDefaultConfiguration).Methods("GET", "OPTIONS")
+	api.HandleFunc("/configurations/{id}", s.getConfiguration).Methods("GET", "OPTIONS")
 
 	// Client endpoints
-	s.router.HandleFunc("/api/client/register", s.registerClient).Methods("POST", "OPTIONS")
-	s.router.HandleFunc("/api/client/config", s.getClientConfig).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/client/config/{id}", s.switchClientConfig).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/client/register", s.registerClient).Methods("POST", "OPTIONS")
+	api.HandleFunc("/client/config", s.getClientConfig).Methods("GET", "OPTIONS")
+	api.HandleFunc("/client/config/{id}", s.switchClientConfig).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/client/state", s.getClientState).Methods("GET", "OPTIONS")
+	api.HandleFunc("/client/events", s.clientEvents).Methods("GET")
 
 	// Action endpoint
-	s.router.HandleFunc("/api/action", s.executeAction).Methods("POST", "OPTIONS")
+	api.HandleFunc("/action", s.executeAction).Methods("POST", "OPTIONS")
 
 	// OBS status endpoints
-	s.router.HandleFunc("/api/obs/status", s.getOBSStatus).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/obs/scenes", s.getScenes).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/obs/inputs", s.getInputs).Methods("GET", "OPTIONS")
-	s.router.HandleFunc("/api/obs/source-visibility", s.getSourceVisibility).Methods("GET", "OPTIONS")
-	// Health check
-	s.router.HandleFunc("/api/health", s.healthCheck).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/status", s.getOBSStatus).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/scenes", s.getScenes).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/inputs", s.getInputs).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/source-visibility", s.getSourceVisibility).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/scene-items", s.getSceneItems).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/filters", s.getSourceFilters).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/transitions", s.getTransitions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/input-kinds", s.getInputKinds).Methods("GET", "OPTIONS")
+	api.HandleFunc("/obs/special-inputs", s.getSpecialInputs).Methods("GET", "OPTIONS")
+
+	// Server logs and diagnostics, for troubleshooting. They show every
+	// session and device, so only operator sessions may read them.
+	admin := api.PathPrefix("").Subrouter()
+	admin.Use(s.requireOperator)
+	admin.HandleFunc("/logs", s.getLogs).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/diagnostics", s.getDiagnostics).Methods("GET", "OPTIONS")
 }
 
 // corsMiddleware handles CORS
@@ -68,7 +142,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Session-ID, X-Client-ID")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-ID, X-Client-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -79,25 +153,63 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Start starts the API server
-func (s *Server) Start(addr string) error {
-	log.Printf("API server listening on %s", addr)
-	// forcing to ipv4
-	listener, err := net.Listen("tcp4", addr)
+// Start listens on addr and serves the API in the background, over HTTPS
+// when tlsConfig is set
+func (s *Server) Start(addr string, tlsConfig *tls.Config) error {
+	// Listen on IPv4 or IPv6, whichever the listen address is
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+		scheme = "https"
 	}
-	return http.Serve(listener, s.router)
-	// return http.ListenAndServe(addr, s.router)
+
+	srv := &http.Server{Handler: s.router}
+	s.httpMu.Lock()
+	s.httpServer = srv
+	s.httpMu.Unlock()
+
+	s.publishOnce.Do(func() { go s.publishLoop() })
+
+	apiLog.Info("API server listening", "scheme", scheme, "addr", addr)
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			apiLog.Error("API server error", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops accepting requests and waits for running ones to finish.
+// Clients connected to the events stream stay connected.
+func (s *Server) Stop(ctx context.Context) error {
+	s.httpMu.Lock()
+	srv := s.httpServer
+	s.httpServer = nil
+	s.httpMu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
 }
 
 // ==================== HANDLERS ====================
 
-// healthCheck returns server health status
+// healthCheck returns server health status, and the version and
+// capabilities clients negotiate with
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status":        "ok",
-		"obs_connected": s.obsManager.IsConnected(),
+		"status":           "ok",
+		"obs_connected":    s.obsManager.IsConnected(),
+		"version":          Version,
+		"api_version":      APIVersion,
+		"min_api_version":  MinAPIVersion,
+		"protocol_version": ProtocolVersion,
+		"capabilities":     capabilities,
 	})
 }
 
@@ -111,13 +223,13 @@ func (s *Server) listConfigurations(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getDefaultConfiguration(w http.ResponseWriter, r *http.Request) {
 	config, err := s.configManager.GetDefault()
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, err.Error())
+		s.respondError(w, http.StatusNotFound, CodeConfigNotFound, err.Error())
 		return
 	}
 
 	resolved, err := s.configManager.Resolve(config.ID)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -131,7 +243,7 @@ func (s *Server) getConfiguration(w http.ResponseWriter, r *http.Request) {
 
 	resolved, err := s.configManager.Resolve(id)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, err.Error())
+		s.respondError(w, http.StatusNotFound, CodeConfigNotFound, err.Error())
 		return
 	}
 
@@ -146,32 +258,41 @@ func (s *Server) registerClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body")
+		s.respondError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	// Get client IP
 	ipAddress := s.getClientIP(r)
+	deviceID := requestDevice(r).ID
 
 	// Check if client already has a session
-	existingSession, err := s.sessionManager.GetByClientID(req.ClientID)
+	existingSession, err := s.sessionManager.GetByClientID(deviceID, req.ClientID)
+	if errors.Is(err, manager.ErrSessionForbidden) {
+		sessionLog.Warn("Refused registration for another device's client ID", "client_id", req.ClientID, "device", deviceID)
+		s.respondFailure(w, err)
+		return
+	}
 	if err == nil {
 		// Update existing session
 		session, err := s.sessionManager.RegisterOrUpdate(
+			deviceID,
 			req.ClientID,
 			req.ClientName,
 			existingSession.ConfigID,
 			ipAddress,
 		)
 		if err != nil {
-			s.respondError(w, http.StatusInternalServerError, err.Error())
+			s.respondFailure(w, err)
 			return
 		}
 
+		s.pinDeviceRole(session)
+
 		// Get resolved configuration
 		resolved, err := s.configManager.Resolve(session.ConfigID)
 		if err != nil {
-			s.respondError(w, http.StatusInternalServerError, err.Error())
+			s.respondFailure(w, err)
 			return
 		}
 
@@ -179,75 +300,108 @@ func (s *Server) registerClient(w http.ResponseWriter, r *http.Request) {
 			"session_id": session.SessionID,
 			"config_id":  session.ConfigID,
 			"config":     resolved,
+			"role":       s.sessionRoleName(session),
+			"locked":     session.Locked,
 		})
 		return
 	}
 
-	// New client - assign default configuration
-	defaultConfig, err := s.configManager.GetDefault()
-	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "no default configuration available")
-		return
+	// New client - assign by rule, falling back to the default configuration
+	configID, matched := s.assignmentManager.Match(req.ClientID, req.ClientName)
+	if matched {
+		if _, err := s.configManager.Get(configID); err != nil {
+			sessionLog.Warn("Assignment rule points at missing configuration, using default", "config", configID)
+			matched = false
+		}
+	}
+	if !matched {
+		defaultConfig, err := s.configManager.GetDefault()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, CodeConfigNotFound, "no default configuration available")
+			return
+		}
+		configID = defaultConfig.ID
 	}
 
 	// Create new session
 	session, err := s.sessionManager.RegisterOrUpdate(
+		deviceID,
 		req.ClientID,
 		req.ClientName,
-		defaultConfig.ID,
+		configID,
 		ipAddress,
 	)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
+	s.pinDeviceRole(session)
 
 	// Get resolved configuration
-	resolved, err := s.configManager.Resolve(defaultConfig.ID)
+	resolved, err := s.configManager.Resolve(configID)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"session_id": session.SessionID,
-		"config_id":  defaultConfig.ID,
+		"config_id":  configID,
 		"config":     resolved,
+		"role":       s.sessionRoleName(session),
+		"locked":     session.Locked,
 	})
 }
 
 // getClientConfig returns the current configuration for a client
 func (s *Server) getClientConfig(w http.ResponseWriter, r *http.Request) {
-	sessionID := r.Header.Get("X-Session-ID")
-	if sessionID == "" {
-		s.respondError(w, http.StatusBadRequest, "missing X-Session-ID header")
+	session, ok := s.requestSession(w, r)
+	if !ok {
 		return
 	}
 
-	session, err := s.sessionManager.Get(sessionID)
+	// Update activity
+	s.sessionManager.UpdateActivity(session.SessionID)
+
+	// Get resolved configuration
+	resolved, err := s.configManager.Resolve(session.ConfigID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "session not found")
+		s.respondFailure(w, err)
 		return
 	}
 
-	// Update activity
-	s.sessionManager.UpdateActivity(sessionID)
+	s.respondJSON(w, http.StatusOK, resolved)
+}
+
+// getClientState returns the state of every stateful button in the
+// session's configuration in one go
+func (s *Server) getClientState(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.requestSession(w, r)
+	if !ok {
+		return
+	}
 
-	// Get resolved configuration
 	resolved, err := s.configManager.Resolve(session.ConfigID)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
+		return
+	}
+	states, err := s.obsManager.ButtonStates(r.Context(), resolved.Buttons)
+	if err != nil {
+		s.respondFailure(w, err)
 		return
 	}
 
-	s.respondJSON(w, http.StatusOK, resolved)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"config_id": resolved.ID,
+		"buttons":   states,
+	})
 }
 
 // switchClientConfig switches a client to a different configuration
 func (s *Server) switchClientConfig(w http.ResponseWriter, r *http.Request) {
-	sessionID := r.Header.Get("X-Session-ID")
-	if sessionID == "" {
-		s.respondError(w, http.StatusBadRequest, "missing X-Session-ID header")
+	session, ok := s.requestSession(w, r)
+	if !ok {
 		return
 	}
 
@@ -257,46 +411,67 @@ func (s *Server) switchClientConfig(w http.ResponseWriter, r *http.Request) {
 	// Verify configuration exists
 	_, err := s.configManager.Get(configID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "configuration not found")
+		s.respondError(w, http.StatusNotFound, CodeConfigNotFound, "configuration not found")
+		return
+	}
+
+	// A locked client stays on the configuration the server gave it
+	if session.Locked && configID != session.ConfigID {
+		sessionLog.Warn("Refused configuration switch from locked client", "session", session.SessionID, "config", configID)
+		s.respondError(w, http.StatusForbidden, CodeClientLocked, "this client is locked")
+		return
+	}
+
+	// Switching may not get the session a broader role
+	if apiErr := s.checkSwitchRole(session, configID); apiErr != nil {
+		sessionLog.Warn("Refused configuration switch", "session", session.SessionID, "config", configID, "error", apiErr.Message)
+		s.respondFailure(w, apiErr)
 		return
 	}
 
 	// Update session
-	if err := s.sessionManager.UpdateConfig(sessionID, configID); err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+	if err := s.sessionManager.UpdateConfig(session.SessionID, configID); err != nil {
+		s.respondFailure(w, err)
 		return
 	}
 
 	// Get resolved configuration
 	resolved, err := s.configManager.Resolve(configID)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
 	s.respondJSON(w, http.StatusOK, resolved)
 }
 
+// clientEvents upgrades to a WebSocket that pushes events to the client.
+// Browsers can't set headers on WebSocket requests, so the session may also
+// be passed as the session_id query parameter.
+func (s *Server) clientEvents(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.requestSession(w, r)
+	if !ok {
+		return
+	}
+
+	s.hub.Serve(w, r, session.SessionID)
+}
+
 // executeAction executes an OBS action
 func (s *Server) executeAction(w http.ResponseWriter, r *http.Request) {
-	sessionID := r.Header.Get("X-Session-ID")
-	if sessionID == "" {
-		s.respondError(w, http.StatusBadRequest, "missing X-Session-ID header")
+	session, ok := s.requestSession(w, r)
+	if !ok {
 		return
 	}
 
-	var action models.ButtonAction
-	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body")
+	var req actionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body")
 		return
 	}
 
-	// Update activity
-	s.sessionManager.UpdateActivity(sessionID)
-
-	// Execute action
-	if err := s.obsManager.ExecuteAction(action); err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+	if err := s.runAction(r.Context(), session, req); err != nil {
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -305,11 +480,41 @@ func (s *Server) executeAction(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// runAction runs an action pressed on a session's client, until ctx is
+// done or the action's time limit passes
+func (s *Server) runAction(ctx context.Context, session *models.ClientSession, req actionRequest) (apiErr *apiError) {
+	start := time.Now()
+	actionType := req.Type
+	defer func() { s.metrics.actionDone(actionType, apiErr, time.Since(start)) }()
+
+	// Update activity
+	s.sessionManager.UpdateActivity(session.SessionID)
+
+	if session.Locked {
+		apiLog.Warn("Refused action from locked client", "action", req.Type, "client", session.ClientName)
+		return newError(http.StatusForbidden, CodeClientLocked, "this client is locked")
+	}
+
+	// Check the session's role
+	action, apiErr := s.authorizeAction(session, req)
+	if apiErr != nil {
+		apiLog.Warn("Refused action", "action", req.Type, "client", session.ClientName, "code", apiErr.Code, "error", apiErr.Message)
+		return apiErr
+	}
+
+	// Execute action
+	actionType = action.Type
+	if err := s.obsManager.ExecuteAction(ctx, action); err != nil {
+		return errorFor(err)
+	}
+	return nil
+}
+
 // getOBSStatus returns current OBS status
 func (s *Server) getOBSStatus(w http.ResponseWriter, r *http.Request) {
-	status, err := s.obsManager.GetStatus()
+	status, err := s.obsManager.GetStatus(r.Context())
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -320,7 +525,7 @@ func (s *Server) getOBSStatus(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getScenes(w http.ResponseWriter, r *http.Request) {
 	scenes, err := s.obsManager.GetScenes()
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -331,7 +536,7 @@ func (s *Server) getScenes(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getInputs(w http.ResponseWriter, r *http.Request) {
 	inputs, err := s.obsManager.GetInputs()
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -342,14 +547,21 @@ func (s *Server) getSourceVisibility(w http.ResponseWriter, r *http.Request) {
 	sceneName := r.URL.Query().Get("scene")
 	sourceName := r.URL.Query().Get("source")
 
-	if sceneName == "" || sourceName == "" {
-		s.respondError(w, http.StatusBadRequest, "missing scene or source parameter")
+	missing := ""
+	switch {
+	case sceneName == "":
+		missing = "scene"
+	case sourceName == "":
+		missing = "source"
+	}
+	if missing != "" {
+		s.respondFailure(w, invalidParam(missing, "missing "+missing+" parameter"))
 		return
 	}
 
-	visible, err := s.obsManager.GetSourceVisibility(sceneName, sourceName)
+	visible, err := s.obsManager.GetSourceVisibility(r.Context(), sceneName, sourceName)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
+		s.respondFailure(w, err)
 		return
 	}
 
@@ -358,6 +570,42 @@ func (s *Server) getSourceVisibility(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ==================== PUSH ====================
+
+// NotifyConfigChanged pushes the resolved configuration to every connected
+// session that uses one of the given configurations
+func (s *Server) NotifyConfigChanged(configIDs []string) {
+	for _, session := range s.sessionManager.UsingConfigs(configIDs) {
+		s.pushConfig(session)
+	}
+}
+
+// NotifySessionConfigChanged pushes the resolved configuration to a session
+// that was moved to another configuration
+func (s *Server) NotifySessionConfigChanged(sessionID string) {
+	session, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return
+	}
+	s.pushConfig(session)
+}
+
+// pushConfig sends a session its resolved configuration if it is connected
+func (s *Server) pushConfig(session *models.ClientSession) {
+	if !s.hub.Connected(session.SessionID) {
+		return
+	}
+
+	resolved, err := s.configManager.Resolve(session.ConfigID)
+	if err != nil {
+		sessionLog.Warn("Failed to resolve configuration", "config", session.ConfigID, "session", session.SessionID, "error", err)
+		return
+	}
+
+	s.hub.Send(session.SessionID, Event{Type: "config_updated", Data: resolved})
+	sessionLog.Info("Pushed configuration", "config", resolved.Name, "client", session.ClientName)
+}
+
 // ==================== HELPERS ====================
 
 // respondJSON writes a JSON response
@@ -367,11 +615,15 @@ func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}
 	json.NewEncoder(w).Encode(data)
 }
 
-// respondError writes an error response
-func (s *Server) respondError(w http.ResponseWriter, status int, message string) {
-	s.respondJSON(w, status, map[string]interface{}{
-		"error": message,
-	})
+// respondError writes an error response with a code from errors.go
+func (s *Server) respondError(w http.ResponseWriter, status int, code, message string) {
+	s.respondJSON(w, status, newError(status, code, message))
+}
+
+// respondFailure writes the error response for an error from the managers
+func (s *Server) respondFailure(w http.ResponseWriter, err error) {
+	apiErr := errorFor(err)
+	s.respondJSON(w, apiErr.status, apiErr)
 }
 
 // getClientIP extracts client IP from request
@@ -389,11 +641,10 @@ func (s *Server) getClientIP(r *http.Request) string {
 		return realIP
 	}
 
-	// Fall back to RemoteAddr
-	parts := strings.Split(r.RemoteAddr, ":")
-	if len(parts) > 0 {
-		return parts[0]
+	// Fall back to RemoteAddr, which may be an IPv6 address like [::1]:port
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-
-	return r.RemoteAddr
+	return host
 }
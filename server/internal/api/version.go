@@ -0,0 +1,39 @@
+package api
+
+// Version is the server's release, set at build time with
+// -ldflags "-X github.com/robomon1/robo-stream/server/internal/api.Version=..."
+var Version = "0.1.0"
+
+const (
+	// APIVersion is the version of the client API, served under /api/v1.
+	// The unversioned /api paths serve the same API for older clients.
+	APIVersion = 1
+
+	// MinAPIVersion is the oldest API version this server still serves
+	MinAPIVersion = 1
+)
+
+// Capabilities reported from /api/health, so clients can tell which
+// features a server has without probing for them
+const (
+	CapabilityPairing   = "pairing"   // device tokens from /pair
+	CapabilityWebSocket = "websocket" // the protocol at /ws (see PROTOCOL.md)
+	CapabilityButtonID  = "button_id" // actions name the pressed button
+	CapabilityRoles     = "roles"     // sessions have roles limiting their actions
+	CapabilityCommands  = "commands"  // remote commands over the events connection
+	CapabilityHeartbeat = "heartbeat" // clients send heartbeats to stay online
+	CapabilityInventory = "inventory" // OBS scene items, filters and transitions for editors
+	CapabilityState     = "state"     // the state of all of a session's buttons at once
+)
+
+// capabilities lists what this server supports
+var capabilities = []string{
+	CapabilityPairing,
+	CapabilityWebSocket,
+	CapabilityButtonID,
+	CapabilityRoles,
+	CapabilityCommands,
+	CapabilityHeartbeat,
+	CapabilityInventory,
+	CapabilityState,
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// ProtocolVersion is the version of the WebSocket protocol described in
+// PROTOCOL.md
+const ProtocolVersion = 1
+
+// Topics a connection can subscribe to
+const (
+	TopicStatus = "status" // OBS status, as from /api/obs/status
+	TopicState  = "state"  // state of the session's buttons and the sources they control
+)
+
+const (
+	// authTimeout is how long a new connection has to authenticate
+	authTimeout = 10 * time.Second
+
+	// publishInterval is how often subscribed status and state are checked
+	// for changes
+	publishInterval = time.Second
+)
+
+// authRequest is the data of the "auth" message that opens a connection
+type authRequest struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+}
+
+// topicsRequest is the data of "subscribe" and "unsubscribe" messages
+type topicsRequest struct {
+	Topics []string `json:"topics"`
+}
+
+// sourceState is the visibility of a source controlled by a button
+type sourceState struct {
+	SceneName  string `json:"scene_name"`
+	SourceName string `json:"source_name"`
+	Visible    bool   `json:"visible"`
+}
+
+// clientWebSocket serves the client protocol. The first message must be an
+// "auth" message carrying the device token and session; after that the
+// connection receives the session's events like /api/client/events and may
+// run actions and subscribe to topics.
+func (s *Server) clientWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		apiLog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	var msg clientMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		conn.Close()
+		return
+	}
+
+	// Nothing else writes to the connection until the hub takes it over
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	session, apiErr := s.authenticate(msg)
+	if apiErr != nil {
+		apiLog.Warn("Rejected WebSocket", "ip", s.getClientIP(r), "code", apiErr.Code, "error", apiErr.Message)
+		conn.WriteJSON(apiErr.event("auth_result", msg.ID))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, apiErr.Message))
+		conn.Close()
+		return
+	}
+	defaultTimeout, timeouts := manager.ActionTimeouts()
+	timeoutsMS := make(map[string]int64, len(timeouts))
+	for actionType, timeout := range timeouts {
+		timeoutsMS[actionType] = timeout.Milliseconds()
+	}
+	if err := conn.WriteJSON(Event{Type: "auth_result", ID: msg.ID, Data: map[string]interface{}{
+		"session_id":         session.SessionID,
+		"protocol":           ProtocolVersion,
+		"topics":             []string{TopicStatus, TopicState},
+		"action_timeout_ms":  defaultTimeout.Milliseconds(),
+		"action_timeouts_ms": timeoutsMS,
+	}}); err != nil {
+		conn.Close()
+		return
+	}
+
+	s.hub.serveConn(conn, session.SessionID)
+}
+
+// authenticate checks an "auth" message the way requireDevice and
+// requestSession check a request
+func (s *Server) authenticate(msg clientMessage) (*models.ClientSession, *apiError) {
+	if msg.Type != "auth" {
+		return nil, newError(http.StatusUnauthorized, CodeInvalidRequest, fmt.Sprintf("expected auth message, got %q", msg.Type))
+	}
+	var req authRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid auth message")
+	}
+	if req.Token == "" {
+		return nil, newError(http.StatusUnauthorized, CodePairingRequired, "pairing required")
+	}
+
+	deviceID, err := s.signer.Verify(req.Token)
+	if err != nil {
+		return nil, newError(http.StatusUnauthorized, CodePairingRequired, err.Error())
+	}
+	device, err := s.sessionManager.Device(deviceID)
+	if err != nil {
+		return nil, newError(http.StatusUnauthorized, CodeDeviceNotPaired, "device is not paired")
+	}
+	s.sessionManager.TouchDevice(device.ID)
+
+	session, err := s.sessionManager.Get(req.SessionID)
+	if err != nil {
+		return nil, newError(http.StatusNotFound, CodeSessionNotFound, "session not found")
+	}
+	if session.DeviceID != device.ID {
+		return nil, newError(http.StatusForbidden, CodeSessionForbidden, "session belongs to another device")
+	}
+	return session, nil
+}
+
+// sessionMessage handles a message sent by a client over its connection
+func (s *Server) sessionMessage(client *hubClient, msg clientMessage) {
+	switch msg.Type {
+	case "heartbeat":
+		// Already recorded by sessionSeen
+
+	case "ack":
+		s.commandAcked(client.sessionID, msg.Data)
+
+	case "action":
+		// Actions may wait on OBS; keep reading meanwhile
+		go s.wsAction(client, msg)
+
+	case "subscribe":
+		var req topicsRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			client.reply(newError(http.StatusBadRequest, CodeInvalidRequest, "invalid subscribe message").event("error", msg.ID))
+			return
+		}
+		for _, topic := range req.Topics {
+			if topic != TopicStatus && topic != TopicState {
+				client.reply(invalidParam("topics", fmt.Sprintf("unknown topic %q", topic)).event("error", msg.ID))
+				return
+			}
+		}
+		client.subscribe(req.Topics)
+		client.reply(Event{Type: "subscribed", ID: msg.ID, Data: req})
+		go s.sendSnapshot(client, req.Topics)
+
+	case "unsubscribe":
+		var req topicsRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			client.reply(newError(http.StatusBadRequest, CodeInvalidRequest, "invalid unsubscribe message").event("error", msg.ID))
+			return
+		}
+		client.unsubscribe(req.Topics)
+		client.reply(Event{Type: "unsubscribed", ID: msg.ID, Data: req})
+
+	default:
+		apiLog.Warn("Ignoring message", "type", msg.Type, "session", client.sessionID)
+		client.reply(newError(http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("unknown message type %q", msg.Type)).event("error", msg.ID))
+	}
+}
+
+// wsAction runs an "action" message and answers with an "action_result"
+func (s *Server) wsAction(client *hubClient, msg clientMessage) {
+	var req actionRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		client.reply(newError(http.StatusBadRequest, CodeInvalidRequest, "invalid action message").event("action_result", msg.ID))
+		return
+	}
+
+	// Re-read the session so locks and role changes apply right away
+	session, err := s.sessionManager.Get(client.sessionID)
+	if err != nil {
+		client.reply(newError(http.StatusNotFound, CodeSessionNotFound, "session not found").event("action_result", msg.ID))
+		return
+	}
+
+	if apiErr := s.runAction(client.ctx, session, req); apiErr != nil {
+		client.reply(apiErr.event("action_result", msg.ID))
+		return
+	}
+	client.reply(Event{Type: "action_result", ID: msg.ID, Data: map[string]interface{}{"success": true}})
+}
+
+// sendSnapshot sends a new subscriber the current value of its topics
+func (s *Server) sendSnapshot(client *hubClient, topics []string) {
+	for _, topic := range topics {
+		switch topic {
+		case TopicStatus:
+			if status, err := s.obsManager.GetStatus(client.ctx); err == nil {
+				client.reply(Event{Type: TopicStatus, Data: status})
+			}
+		case TopicState:
+			client.reply(Event{Type: TopicState, Data: s.sessionState(client.ctx, client.sessionID)})
+		}
+	}
+}
+
+// publishLoop pushes status and state to subscribers whenever they change
+func (s *Server) publishLoop() {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	// Status is pushed when its version changes, not for every tick of
+	// the timecodes
+	var lastStatus interface{}
+	lastState := make(map[string][]byte)
+	for range ticker.C {
+		if len(s.hub.Subscribers(TopicStatus)) > 0 {
+			if status, err := s.obsManager.GetStatus(context.Background()); err == nil && status["version"] != lastStatus {
+				s.hub.Publish(TopicStatus, "", Event{Type: TopicStatus, Data: status})
+				lastStatus = status["version"]
+			}
+		} else {
+			lastStatus = nil
+		}
+
+		subscribed := make(map[string]bool)
+		for _, sessionID := range s.hub.Subscribers(TopicState) {
+			subscribed[sessionID] = true
+			state := s.sessionState(context.Background(), sessionID)
+			data, _ := json.Marshal(state)
+			if !bytes.Equal(data, lastState[sessionID]) {
+				s.hub.Publish(TopicState, sessionID, Event{Type: TopicState, Data: state})
+				lastState[sessionID] = data
+			}
+		}
+		for sessionID := range lastState {
+			if !subscribed[sessionID] {
+				delete(lastState, sessionID)
+			}
+		}
+	}
+}
+
+// sessionState returns the state of the stateful buttons in a session's
+// configuration, and the visibility of the sources its buttons show and
+// hide. Buttons and sources OBS can't report on are left out.
+func (s *Server) sessionState(ctx context.Context, sessionID string) map[string]interface{} {
+	sources := []sourceState{}
+	buttons := []manager.ButtonState{}
+	state := func() map[string]interface{} {
+		return map[string]interface{}{"sources": sources, "buttons": buttons}
+	}
+
+	session, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return state()
+	}
+	resolved, err := s.configManager.Resolve(session.ConfigID)
+	if err != nil {
+		return state()
+	}
+	if buttons, err = s.obsManager.ButtonStates(ctx, resolved.Buttons); err != nil {
+		buttons = []manager.ButtonState{}
+		return state()
+	}
+
+	active := make(map[string]bool, len(buttons))
+	for _, button := range buttons {
+		active[button.ID] = button.Active
+	}
+	seen := make(map[[2]string]bool)
+	for _, button := range resolved.Buttons {
+		switch button.Action.Type {
+		case "toggle_source_visibility", "show_source", "hide_source":
+		default:
+			continue
+		}
+		visible, ok := active[button.ID]
+		sceneName, _ := button.Action.Params["scene_name"].(string)
+		sourceName, _ := button.Action.Params["source_name"].(string)
+		key := [2]string{sceneName, sourceName}
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		sources = append(sources, sourceState{SceneName: sceneName, SourceName: sourceName, Visible: visible})
+	}
+	return state()
+}
@@ -0,0 +1,128 @@
+// Package certs provides the certificate the client API serves HTTPS with.
+//
+// Either a certificate and key are provided, or a self-signed certificate is
+// created in the data directory on first use and kept from then on. Clients
+// can't verify a self-signed certificate against a CA, so they pin its
+// SHA-256 fingerprint instead: it is shown in the server UI and pairing QR
+// code, and clients trust it on first use.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// CertFile holds the self-signed certificate
+	CertFile = "tls.crt"
+
+	// KeyFile holds the self-signed certificate's private key
+	KeyFile = "tls.key"
+
+	// validFor is how long a self-signed certificate is valid. Clients pin
+	// it, so it should outlive the install.
+	validFor = 20 * 365 * 24 * time.Hour
+)
+
+// Load returns the certificate in certFile and keyFile, or when both are
+// empty the self-signed certificate in the data directory, created for hosts
+// on first use
+func Load(dataDir, certFile, keyFile string, hosts []string) (*tls.Certificate, error) {
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return &cert, nil
+	}
+
+	certFile = filepath.Join(dataDir, CertFile)
+	keyFile = filepath.Join(dataDir, KeyFile)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		return &cert, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load self-signed certificate: %w", err)
+	}
+
+	if err := generate(certFile, keyFile, hosts); err != nil {
+		return nil, fmt.Errorf("failed to create self-signed certificate: %w", err)
+	}
+	cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a certificate as colon
+// separated hex, the way browsers show it
+func Fingerprint(cert *tls.Certificate) string {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":")
+}
+
+// generate writes a self-signed certificate for hosts and its key
+func generate(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Robo-Stream Server", Organization: []string{"Robo-Stream"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range append([]string{"localhost", "127.0.0.1"}, hosts...) {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/api"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// Sessions returns the client sessions with their presence. A session is
+// online while its client holds an events connection, which the API server
+// drops when heartbeats stop, and idle when it hasn't been used for the idle
+// timeout.
+func (c *Core) Sessions() []*models.ClientSession {
+	idleTimeout := time.Duration(c.SettingsManager.Get().IdleTimeoutMinutes) * time.Minute
+
+	// The manager returns copies, so presence is set without its lock
+	sessions := c.SessionManager.List()
+	for _, session := range sessions {
+		session.Online = c.APIServer.Online(session.SessionID)
+		switch {
+		case !session.Online:
+			session.Presence = models.PresenceOffline
+		case time.Since(session.LastActive) > idleTimeout:
+			session.Presence = models.PresenceIdle
+		default:
+			session.Presence = models.PresenceOnline
+		}
+	}
+	return sessions
+}
+
+// SwitchClientConfig moves a connected client to another configuration. The
+// session is switched here, which pushes the configuration, so neither the
+// client's lock nor its role stops the server UI; the command only has the
+// client acknowledge it.
+func (c *Core) SwitchClientConfig(sessionID, configID string) error {
+	if _, err := c.ConfigManager.Get(configID); err != nil {
+		return err
+	}
+	if !c.APIServer.Online(sessionID) {
+		return api.ErrSessionOffline
+	}
+	if err := c.SessionManager.UpdateConfig(sessionID, configID); err != nil {
+		return err
+	}
+	return c.sendCommand(sessionID, api.CommandSwitchConfig, map[string]interface{}{
+		"config_id": configID,
+	})
+}
+
+// IdentifyClient makes a connected client flash its name on screen
+func (c *Core) IdentifyClient(sessionID string) error {
+	session, err := c.SessionManager.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	return c.sendCommand(sessionID, api.CommandIdentify, map[string]interface{}{
+		"name": session.ClientName,
+	})
+}
+
+// LockClient locks or unlocks a client's grid. The lock is enforced by the
+// API, so an offline client is locked as soon as it returns.
+func (c *Core) LockClient(sessionID string, locked bool) error {
+	if err := c.SessionManager.SetLocked(sessionID, locked); err != nil {
+		return err
+	}
+	err := c.sendCommand(sessionID, api.CommandLock, map[string]interface{}{
+		"locked": locked,
+	})
+	if errors.Is(err, api.ErrSessionOffline) {
+		return nil
+	}
+	return err
+}
+
+// RenameClient renames a connected client, which keeps the name for later
+// registrations
+func (c *Core) RenameClient(sessionID, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := c.sendCommand(sessionID, api.CommandRename, map[string]interface{}{
+		"name": name,
+	}); err != nil {
+		return err
+	}
+	return c.SessionManager.Rename(sessionID, name)
+}
+
+// ReloadClient makes a connected client reload its configuration
+func (c *Core) ReloadClient(sessionID string) error {
+	return c.sendCommand(sessionID, api.CommandReload, nil)
+}
+
+// DisconnectClient tells a client to disconnect and closes its connection
+// even if it doesn't answer
+func (c *Core) DisconnectClient(sessionID string) error {
+	err := c.sendCommand(sessionID, api.CommandDisconnect, nil)
+	c.APIServer.DisconnectSession(sessionID)
+	return err
+}
+
+// sendCommand sends a command and logs the outcome
+func (c *Core) sendCommand(sessionID, name string, params map[string]interface{}) error {
+	err := c.APIServer.SendCommand(sessionID, name, params)
+	if err != nil {
+		sessionLog.Warn("Client command failed", "command", name, "session", sessionID, "error", err)
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+// Package core wires storage, the managers, the OBS connection and the client
+// API server together. Both the desktop app and the headless server run on
+// top of it.
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+	"github.com/robomon1/robo-stream/server/internal/api"
+	"github.com/robomon1/robo-stream/server/internal/auth"
+	"github.com/robomon1/robo-stream/server/internal/logging"
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/secrets"
+	"github.com/robomon1/robo-stream/server/internal/storage"
+)
+
+const (
+	// maxReloadResults is how many data file reloads are kept for the UI
+	maxReloadResults = 20
+
+	// passphraseEnv optionally holds the passphrase that protects stored
+	// secrets instead of the key file
+	passphraseEnv = "ROBO_STREAM_PASSPHRASE"
+)
+
+var (
+	appLog     = logging.For(logging.App)
+	apiLog     = logging.For(logging.API)
+	obsLog     = logging.For(logging.OBS)
+	sessionLog = logging.For(logging.Session)
+	storageLog = logging.For(logging.Storage)
+)
+
+// Core is a running server
+type Core struct {
+	Storage           *storage.Storage
+	Secrets           *secrets.Box
+	Signer            *auth.Signer
+	ButtonManager     *manager.ButtonManager
+	ConfigManager     *manager.ConfigManager
+	SessionManager    *manager.SessionManager
+	AssignmentManager *manager.AssignmentManager
+	RoleManager       *manager.RoleManager
+	AuditManager      *manager.AuditManager
+	SettingsManager   *manager.SettingsManager
+	OBSManager        *manager.OBSManager
+	APIServer         *api.Server
+
+	dataWatcher *storage.Watcher
+
+	// planMu guards the latest plan of each layout file, which pruning
+	// applies must follow
+	planMu sync.Mutex
+	plans  map[string]plannedLayout
+
+	reloadMu      sync.Mutex
+	reloadResults []*manager.ReloadResult
+	onReload      func(*manager.ReloadResult)
+
+	// checkMu serializes checks of the buttons against OBS, buttonIssuesMu
+	// guards the result of the latest
+	checkMu        sync.Mutex
+	buttonIssuesMu sync.Mutex
+	buttonIssues   []manager.ButtonIssue
+	onButtonIssues func([]manager.ButtonIssue)
+
+	// settingsChanged wakes the session cleanup loop to pick up new timings
+	settingsChanged chan struct{}
+	// done stops the background loops
+	done chan struct{}
+
+	// listenMu guards where the API server is listening, its TLS config,
+	// the certificate's fingerprint and the mDNS announcement
+	listenMu    sync.Mutex
+	endpoint    apiEndpoint
+	listenTLS   *tls.Config
+	fingerprint string
+	mdns        *zeroconf.Server
+}
+
+// New opens the data directory and creates the managers and API server.
+// Overrides are settings given by flags or environment variables.
+func New(overrides map[string]string) (*Core, error) {
+	c := &Core{
+		settingsChanged: make(chan struct{}, 1),
+		done:            make(chan struct{}),
+		plans:           make(map[string]plannedLayout),
+	}
+
+	// Get data directory
+	dataDir, err := ResolveDataDir(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data directory: %w", err)
+	}
+
+	// Initialize storage
+	c.Storage, err = storage.New(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	// Write logs to the data directory from here on
+	if err := logging.Setup(dataDir); err != nil {
+		return nil, err
+	}
+	appLog.Info("Using data directory", "path", dataDir, "log", logging.Path())
+
+	// Load settings, with flags and environment variables on top
+	c.SettingsManager, err = manager.NewSettingsManager(c.Storage, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := logging.SetLevel(c.SettingsManager.Get().LogLevel); err != nil {
+		return nil, err
+	}
+
+	// Initialize encryption for stored secrets
+	c.Secrets, err = secrets.Open(dataDir, os.Getenv(passphraseEnv))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize secret storage: %w", err)
+	}
+	c.migrateOBSConfig()
+
+	// Initialize signing of paired device tokens
+	c.Signer, err = auth.Open(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize device tokens: %w", err)
+	}
+
+	// Initialize managers
+	c.ButtonManager = manager.NewButtonManager(c.Storage)
+	c.ConfigManager = manager.NewConfigManager(c.Storage, c.ButtonManager)
+	c.SessionManager = manager.NewSessionManager(c.Storage)
+	c.AssignmentManager = manager.NewAssignmentManager(c.Storage)
+	c.RoleManager, err = manager.NewRoleManager(c.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize roles: %w", err)
+	}
+	c.AuditManager = manager.NewAuditManager(c.Storage)
+	c.OBSManager = manager.NewOBSManager()
+
+	// Initialize with some default data if needed
+	c.initializeDefaults()
+
+	// API server for clients
+	c.APIServer = api.NewServer(c.ConfigManager, c.SessionManager, c.AssignmentManager, c.RoleManager, c.OBSManager, c.Signer)
+
+	// Push edits to the clients showing them
+	c.ConfigManager.OnChange(c.APIServer.NotifyConfigChanged)
+	c.ButtonManager.OnChange(func(ids []string) {
+		c.APIServer.NotifyConfigChanged(c.ConfigManager.ConfigsUsingButtons(ids))
+		go c.checkButtons()
+	})
+	c.SessionManager.OnConfigChange(c.APIServer.NotifySessionConfigChanged)
+
+	// Follow renames in OBS and flag buttons that won't work after connecting
+	c.OBSManager.OnRename(c.handleRename)
+	c.OBSManager.OnConnect(c.checkButtons)
+
+	c.APIServer.SetDiagnostics(c.CollectDiagnostics)
+
+	return c, nil
+}
+
+// Start starts session cleanup, the data file watcher, OBS auto-connect and
+// the API server, and advertises it on the LAN
+func (c *Core) Start() error {
+	settings := c.SettingsManager.Get()
+
+	// Start session retention routine
+	go c.sessionCleanupLoop()
+
+	// Auto-connect to OBS on startup
+	if settings.OBSAutoConnect {
+		go c.autoConnectOBS()
+	} else {
+		obsLog.Info("OBS auto-connect is disabled")
+	}
+
+	// Pick up edits made to the data files outside the app
+	c.ButtonManager.OnReload(c.handleReload)
+	c.ConfigManager.OnReload(c.handleReload)
+	var err error
+	c.dataWatcher, err = c.Storage.Watch(
+		[]string{manager.ButtonsFile, manager.ConfigsFile},
+		c.handleDataFileChange,
+	)
+	if err != nil {
+		storageLog.Warn("Failed to watch data directory, external edits need a restart", "error", err)
+	}
+
+	endpoint := endpointFor(settings)
+	tlsConfig, fingerprint, err := c.tlsConfig(endpoint)
+	if err != nil {
+		return err
+	}
+	apiLog.Info("Starting API server", "addr", endpoint.addr)
+	if err := c.APIServer.Start(endpoint.addr, tlsConfig); err != nil {
+		return fmt.Errorf("API server failed to start: %w", err)
+	}
+	c.listenMu.Lock()
+	c.endpoint = endpoint
+	c.listenTLS = tlsConfig
+	c.fingerprint = fingerprint
+	c.advertise()
+	c.listenMu.Unlock()
+
+	appLog.Info("Robo-Stream Server started")
+	return nil
+}
+
+// Stop shuts everything down, waiting for running API requests until ctx is
+// done
+func (c *Core) Stop(ctx context.Context) error {
+	close(c.done)
+	if c.dataWatcher != nil {
+		c.dataWatcher.Stop()
+	}
+	c.stopAdvertising()
+
+	var err error
+	if c.APIServer != nil {
+		err = c.APIServer.Stop(ctx)
+	}
+	if c.OBSManager != nil {
+		c.OBSManager.Disconnect()
+	}
+	appLog.Info("Robo-Stream Server shutdown complete")
+	return err
+}
+
+// autoConnectOBS connects to OBS with the saved connection settings
+func (c *Core) autoConnectOBS() {
+	obsLog.Info("Auto-connecting to OBS")
+	savedConfig := c.LoadOBSConfig()
+
+	// Try with saved config first
+	err := c.OBSManager.Connect(savedConfig.URL, savedConfig.Password)
+	if err != nil {
+		obsLog.Warn("Auto-connect to OBS failed, this is normal if OBS isn't running", "error", err)
+	} else {
+		obsLog.Info("Auto-connected to OBS")
+	}
+}
+
+// sessionCleanupLoop periodically removes sessions past the retention
+// policy, following changes to the retention and interval settings
+func (c *Core) sessionCleanupLoop() {
+	for {
+		settings := c.SettingsManager.Get()
+		retention := time.Duration(settings.SessionRetentionDays) * 24 * time.Hour
+		removed, err := c.SessionManager.ExpireSessions(retention, c.APIServer.Online)
+		if err != nil {
+			sessionLog.Warn("Failed to expire sessions", "error", err)
+		} else {
+			sessionLog.Info("Session cleanup complete", "expired", removed, "kept", len(c.SessionManager.List()))
+		}
+
+		timer := time.NewTimer(time.Duration(settings.CleanupIntervalMinutes) * time.Minute)
+		select {
+		case <-timer.C:
+		case <-c.settingsChanged:
+			timer.Stop()
+		case <-c.done:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+
+	"github.com/robomon1/robo-stream/server/internal/declarative"
+)
+
+// ErrPruneNotPlanned is returned when applying with prune set without first
+// reviewing a pruning plan of the same file
+var ErrPruneNotPlanned = errors.New("plan the layout file with prune first to review what will be deleted")
+
+// plannedLayout is what the latest plan of a layout file was made from
+type plannedLayout struct {
+	sum   [sha256.Size]byte
+	prune bool
+}
+
+// declarativeTarget returns the managers a layout file is applied to
+func (c *Core) declarativeTarget() declarative.Target {
+	return declarative.Target{
+		Buttons:     c.ButtonManager,
+		Configs:     c.ConfigManager,
+		Assignments: c.AssignmentManager,
+	}
+}
+
+// loadLayout reads a layout file and the checksum of its content
+func loadLayout(path string) (*declarative.Spec, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	spec, err := declarative.Parse(data)
+	return spec, sha256.Sum256(data), err
+}
+
+// PlanDeclarativeConfig diffs a layout file against the current buttons,
+// configurations and assignment rules without changing anything. With prune
+// set, the plan also lists what applying with prune would delete.
+func (c *Core) PlanDeclarativeConfig(path string, prune bool) (*declarative.Plan, error) {
+	spec, sum, err := loadLayout(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.planMu.Lock()
+	c.plans[path] = plannedLayout{sum: sum, prune: prune}
+	c.planMu.Unlock()
+
+	return declarative.NewPlan(spec, c.declarativeTarget(), prune), nil
+}
+
+// ApplyDeclarativeConfig applies a layout file. With prune set, buttons and
+// configurations missing from the file are deleted, which is refused unless
+// the latest plan of the unchanged file was made with prune too.
+func (c *Core) ApplyDeclarativeConfig(path string, prune bool) (*declarative.Plan, error) {
+	spec, sum, err := loadLayout(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.planMu.Lock()
+	planned, ok := c.plans[path]
+	if prune && (!ok || !planned.prune || planned.sum != sum) {
+		c.planMu.Unlock()
+		return nil, ErrPruneNotPlanned
+	}
+	delete(c.plans, path)
+	c.planMu.Unlock()
+
+	plan, err := declarative.Apply(spec, c.declarativeTarget(), prune)
+	if err != nil {
+		return plan, err
+	}
+	// Connected clients were sent the changes as they were applied
+	storageLog.Info("Applied declarative config", "file", path, "prune", prune, "changes", len(plan.Changes))
+	return plan, nil
+}
+
+// ExportDeclarativeConfig writes the current buttons, configurations and
+// assignment rules to a layout file
+func (c *Core) ExportDeclarativeConfig(path string) error {
+	spec, err := declarative.Export(c.declarativeTarget())
+	if err != nil {
+		return err
+	}
+	return spec.Save(path)
+}
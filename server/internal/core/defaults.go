@@ -0,0 +1,78 @@
+package core
+
+import (
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// initializeDefaults creates default configuration if none exist
+func (c *Core) initializeDefaults() {
+	// Check if we have any configurations
+	configs := c.ConfigManager.List()
+	if len(configs) > 0 {
+		return // Already initialized
+	}
+
+	storageLog.Info("Creating default configuration")
+
+	// Create some default buttons
+	defaultButtons := []struct {
+		name        string
+		description string
+		icon        string
+		color       string
+		actionType  string
+		params      map[string]interface{}
+	}{
+		{"Go Live", "Start streaming", "video", "#e74c3c", "start_stream", nil},
+		{"Stop Stream", "Stop streaming", "stop-circle", "#95a5a6", "stop_stream", nil},
+		{"Start Record", "Start recording", "circle", "#e74c3c", "start_record", nil},
+		{"Stop Record", "Stop recording", "stop-circle", "#95a5a6", "stop_record", nil},
+		{"Mute Mic", "Mute microphone", "mic-off", "#e67e22", "toggle_input_mute", map[string]interface{}{"input_name": "Mic/Aux"}},
+		{"Scene", "Switch to main scene", "layout", "#3498db", "switch_scene", map[string]interface{}{"scene_name": "Scene"}},
+	}
+
+	buttonIDs := make([]string, 0, len(defaultButtons))
+	for _, btn := range defaultButtons {
+		button := &models.Button{
+			Name:        btn.name,
+			Description: btn.description,
+			Icon:        btn.icon,
+			Color:       btn.color,
+			Action: models.ButtonAction{
+				Type:   btn.actionType,
+				Params: btn.params,
+			},
+		}
+		if err := c.ButtonManager.Create(button); err != nil {
+			storageLog.Warn("Failed to create default button", "button", btn.name, "error", err)
+			continue
+		}
+		buttonIDs = append(buttonIDs, button.ID)
+	}
+
+	// Create default configuration
+	defaultConfig := &models.Configuration{
+		Name:        "Default",
+		Description: "Default configuration",
+		Grid: models.GridConfig{
+			Rows: 3,
+			Cols: 4,
+		},
+		Buttons:   make(map[string]string),
+		IsDefault: true,
+	}
+
+	// Assign buttons to positions
+	positions := []string{"btn-0-0", "btn-0-1", "btn-1-0", "btn-1-1", "btn-2-0", "btn-2-1"}
+	for i, btnID := range buttonIDs {
+		if i < len(positions) {
+			defaultConfig.Buttons[positions[i]] = btnID
+		}
+	}
+
+	if err := c.ConfigManager.Create(defaultConfig); err != nil {
+		storageLog.Error("Failed to create default configuration", "error", err)
+	}
+
+	storageLog.Info("Default configuration created")
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/api"
+	"github.com/robomon1/robo-stream/server/internal/logging"
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// dataFiles are the JSON files checked for damage in the data directory
+var dataFiles = []string{
+	manager.SettingsFile,
+	manager.ButtonsFile,
+	manager.ConfigsFile,
+	manager.SessionsFile,
+	manager.DevicesFile,
+	manager.RolesFile,
+	manager.AssignmentsFile,
+	manager.AuditFile,
+	obsConfigFile,
+}
+
+// CollectDiagnostics writes a zip archive with what's needed to find out
+// what went wrong during a show: versions, settings with secrets left out,
+// sessions, counts, the OBS connection history, status and version, a check
+// of the stored data and the recent logs.
+func (c *Core) CollectDiagnostics(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	obsConfig := c.LoadOBSConfig()
+	obs := map[string]interface{}{
+		"url":                obsConfig.URL,
+		"has_password":       obsConfig.HasPassword,
+		"connected":          c.OBSManager.IsConnected(),
+		"connection_history": c.OBSManager.ConnectionHistory(),
+		"button_issues":      c.ButtonIssues(),
+	}
+	if status, err := c.OBSManager.GetStatus(context.Background()); err != nil {
+		obs["status_error"] = err.Error()
+	} else {
+		obs["status"] = status
+	}
+	if version, err := c.OBSManager.Version(); err != nil {
+		obs["version_error"] = err.Error()
+	} else {
+		obs["version"] = version
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"summary.json", map[string]interface{}{
+			"collected_at":     time.Now(),
+			"version":          api.Version,
+			"api_version":      api.APIVersion,
+			"protocol_version": api.ProtocolVersion,
+			"go_version":       runtime.Version(),
+			"platform":         runtime.GOOS + "/" + runtime.GOARCH,
+			"data_dir":         c.Storage.GetDataDir(),
+			"log_file":         logging.Path(),
+			"server":           c.ServerInfo(),
+			"counts": map[string]int{
+				"buttons":        len(c.ButtonManager.List()),
+				"configurations": len(c.ConfigManager.List()),
+				"sessions":       len(c.SessionManager.List()),
+				"devices":        len(c.SessionManager.Devices()),
+				"roles":          len(c.RoleManager.List()),
+				"assignments":    len(c.AssignmentManager.List()),
+			},
+		}},
+		{"settings.json", map[string]interface{}{
+			"settings":   c.SettingsManager.Get(),
+			"overridden": c.SettingsManager.Overridden(),
+			// Only whether a passphrase is used, never the passphrase
+			"secrets_passphrase": os.Getenv(passphraseEnv) != "",
+		}},
+		{"sessions.json", map[string]interface{}{
+			"sessions": c.Sessions(),
+			"devices":  c.SessionManager.Devices(),
+		}},
+		{"obs.json", obs},
+		{"consistency.json", c.CheckData()},
+	}
+	for _, file := range files {
+		if err := addJSON(archive, file.name, file.data); err != nil {
+			return err
+		}
+	}
+
+	if err := addLogs(archive); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// CheckData checks the data files can be read and haven't been changed
+// behind the server's back, and that the data in them fits together
+func (c *Core) CheckData() []manager.DataIssue {
+	issues := []manager.DataIssue{}
+	dataDir := c.Storage.GetDataDir()
+
+	for _, name := range dataFiles {
+		data, err := os.ReadFile(filepath.Join(dataDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			issues = append(issues, manager.DataIssue{File: name, Problem: err.Error()})
+			continue
+		}
+		if len(data) > 0 && !json.Valid(data) {
+			issues = append(issues, manager.DataIssue{File: name, Problem: "not valid JSON"})
+			continue
+		}
+		if modified, err := c.Storage.Modified(name); err == nil && modified {
+			issues = append(issues, manager.DataIssue{File: name, Problem: "changed on disk since it was loaded"})
+		}
+	}
+
+	// Saves write a temporary file first; one left over means a save failed
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		issues = append(issues, manager.DataIssue{Problem: err.Error()})
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") && strings.Contains(entry.Name(), ".tmp") {
+			issues = append(issues, manager.DataIssue{File: entry.Name(), Problem: "leftover from an interrupted save"})
+		}
+	}
+
+	return append(issues, manager.CheckConsistency(
+		c.ButtonManager, c.ConfigManager, c.SessionManager, c.AssignmentManager, c.RoleManager,
+	)...)
+}
+
+// addJSON adds a file with indented JSON to an archive
+func addJSON(archive *zip.Writer, name string, data interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// addLogs adds the recent log lines and the current log file to an archive
+func addLogs(archive *zip.Writer) error {
+	f, err := archive.Create("logs/recent.jsonl")
+	if err != nil {
+		return err
+	}
+	for _, line := range logging.Recent(0, slog.LevelDebug) {
+		if _, err := f.Write(append(line[:len(line):len(line)], '\n')); err != nil {
+			return err
+		}
+	}
+
+	path := logging.Path()
+	if path == "" {
+		return nil
+	}
+	logFile, err := os.Open(path)
+	if err != nil {
+		return nil // nothing logged yet
+	}
+	defer logFile.Close()
+	f, err = archive.Create("logs/" + filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, logFile)
+	return err
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/grandcat/zeroconf"
+	"github.com/robomon1/robo-stream/server/internal/api"
+)
+
+// ServiceType is the DNS-SD service clients browse for on the LAN
+const ServiceType = "_robostream._tcp"
+
+// advertise announces the API server on the LAN with mDNS/DNS-SD, replacing
+// an earlier announcement. Clients find it without typing an address and
+// learn from the TXT records whether to use HTTPS and which certificate to
+// expect. Callers hold listenMu.
+func (c *Core) advertise() {
+	if c.mdns != nil {
+		c.mdns.Shutdown()
+		c.mdns = nil
+	}
+
+	scheme := "http"
+	if c.endpoint.tls {
+		scheme = "https"
+	}
+	txt := []string{
+		"version=" + api.Version,
+		"api=" + strconv.Itoa(api.APIVersion),
+		"scheme=" + scheme,
+		"path=/api/v1",
+	}
+	if c.fingerprint != "" {
+		txt = append(txt, "fingerprint="+c.fingerprint)
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+	name := fmt.Sprintf("Robo-Stream on %s", hostname)
+
+	port := c.SettingsManager.Get().Port
+	server, err := zeroconf.Register(name, ServiceType, "local.", port, txt, nil)
+	if err != nil {
+		apiLog.Warn("Failed to advertise the server on the LAN", "error", err)
+		return
+	}
+	c.mdns = server
+	apiLog.Info("Advertising the server on the LAN", "name", name, "service", ServiceType, "port", port)
+}
+
+// stopAdvertising withdraws the mDNS announcement
+func (c *Core) stopAdvertising() {
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	if c.mdns != nil {
+		c.mdns.Shutdown()
+		c.mdns = nil
+	}
+}
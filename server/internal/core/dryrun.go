@@ -0,0 +1,55 @@
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// TestConfiguration checks every button of a configuration against what
+// exists in OBS, without running anything
+func (c *Core) TestConfiguration(configID string) (*manager.ConfigurationReport, error) {
+	cfg, err := c.ConfigManager.Get(configID)
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := c.OBSManager.DryRun()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &manager.ConfigurationReport{
+		ConfigID:   cfg.ID,
+		ConfigName: cfg.Name,
+		Buttons:    make([]manager.ButtonCheck, 0, len(cfg.Buttons)),
+		CheckedAt:  time.Now(),
+	}
+	for position, buttonID := range cfg.Buttons {
+		check := manager.ButtonCheck{Position: position, ButtonID: buttonID}
+		check.Row, check.Col, _ = manager.ParsePosition(position)
+		if button, err := c.ButtonManager.Get(buttonID); err != nil {
+			check.Problems = []string{"button does not exist in the library"}
+		} else {
+			check.ButtonName = button.Name
+			check.ActionType = button.Action.Type
+			check.Problems = dryRun.Check(button.Action)
+		}
+		check.OK = len(check.Problems) == 0
+		if !check.OK {
+			report.Failed++
+		}
+		report.Buttons = append(report.Buttons, check)
+	}
+	sort.Slice(report.Buttons, func(i, j int) bool {
+		a, b := report.Buttons[i], report.Buttons[j]
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	report.OK = report.Failed == 0
+
+	appLog.Info("Tested configuration", "config", cfg.Name, "buttons", len(report.Buttons), "failed", report.Failed)
+	return report, nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net"
+
+	"github.com/robomon1/robo-stream/server/internal/api"
+)
+
+// LocalIPs returns all non-loopback IPv4 addresses
+func LocalIPs() []string {
+	var ips []string
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		appLog.Warn("Failed to get network interfaces", "error", err)
+		return ips
+	}
+
+	for _, iface := range interfaces {
+		// Skip down interfaces
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		// Skip loopback
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+
+			// Skip loopback and IPv6
+			if ip == nil || ip.IsLoopback() || ip.To4() == nil {
+				continue
+			}
+
+			ips = append(ips, ip.String())
+		}
+	}
+
+	return ips
+}
+
+// ServerInfo summarises the server for the UI
+func (c *Core) ServerInfo() map[string]interface{} {
+	port := c.SettingsManager.Get().Port
+	ips := LocalIPs()
+	clientURLs := make([]string, len(ips))
+	for i, ip := range ips {
+		clientURLs[i] = c.clientURL(ip)
+	}
+
+	// Sessions are kept while clients are away, so count the connected ones
+	online := 0
+	for _, session := range c.SessionManager.List() {
+		if c.APIServer.Online(session.SessionID) {
+			online++
+		}
+	}
+
+	return map[string]interface{}{
+		"version":         api.Version,
+		"api_port":        port,
+		"ip_addresses":    ips,
+		"client_urls":     clientURLs,
+		"tls":             c.SettingsManager.Get().TLS,
+		"fingerprint":     c.Fingerprint(),
+		"obs_connected":   c.OBSManager.IsConnected(),
+		"active_sessions": online,
+		"configurations":  len(c.ConfigManager.List()),
+		"buttons":         len(c.ButtonManager.List()),
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// obsConfigFile stores the OBS connection settings
+const obsConfigFile = "obs_config.json"
+
+// ConnectOBS connects to OBS and saves the connection settings for the next
+// start. A blank password means the saved one.
+func (c *Core) ConnectOBS(url, password string) error {
+	if password == "" {
+		password = c.LoadOBSConfig().Password
+	}
+
+	obsLog.Info("Connecting to OBS", "url", url)
+	if err := c.OBSManager.Connect(url, password); err != nil {
+		return err
+	}
+
+	// Save credentials for next time
+	if err := c.SaveOBSConfig(url, password); err != nil {
+		obsLog.Warn("Failed to save OBS config", "error", err)
+	} else {
+		obsLog.Info("OBS config saved")
+	}
+	return nil
+}
+
+// LoadOBSConfig returns the OBS connection settings with the password
+// decrypted, preferring environment variables over the saved file
+func (c *Core) LoadOBSConfig() *models.OBSConfig {
+	// Check environment variables first
+	envURL := os.Getenv("OBS_WEBSOCKET_URL")
+	envPassword := os.Getenv("OBS_WEBSOCKET_PASSWORD")
+
+	if envURL != "" {
+		obsLog.Info("Using OBS config from environment variables")
+		return &models.OBSConfig{
+			URL:         envURL,
+			Password:    envPassword,
+			HasPassword: envPassword != "",
+		}
+	}
+
+	// Fall back to saved config file
+	var config models.OBSConfig
+	if err := c.Storage.LoadJSON(obsConfigFile, &config); err != nil || config.URL == "" {
+		obsLog.Info("No saved OBS config found, using defaults")
+		return &models.OBSConfig{
+			URL: "localhost:4455",
+		}
+	}
+
+	if config.EncryptedPassword != "" {
+		password, err := c.Secrets.Open(config.EncryptedPassword)
+		if err != nil {
+			obsLog.Warn("Failed to decrypt saved OBS password", "error", err)
+		}
+		config.Password = password
+	}
+	config.EncryptedPassword = ""
+	config.HasPassword = config.Password != ""
+
+	obsLog.Info("Loaded saved OBS config", "url", config.URL)
+	return &config
+}
+
+// SaveOBSConfig stores the OBS connection settings with the password encrypted
+func (c *Core) SaveOBSConfig(url, password string) error {
+	encrypted, err := c.Secrets.Seal(password)
+	if err != nil {
+		return err
+	}
+	return c.Storage.SaveJSON(obsConfigFile, &models.OBSConfig{
+		URL:               url,
+		EncryptedPassword: encrypted,
+		HasPassword:       password != "",
+	})
+}
+
+// migrateOBSConfig encrypts a plaintext password saved by an older version
+func (c *Core) migrateOBSConfig() {
+	var config models.OBSConfig
+	if err := c.Storage.LoadJSON(obsConfigFile, &config); err != nil || config.Password == "" {
+		return
+	}
+
+	if err := c.SaveOBSConfig(config.URL, config.Password); err != nil {
+		obsLog.Warn("Failed to encrypt saved OBS password", "error", err)
+		return
+	}
+	obsLog.Info("Encrypted the saved OBS password")
+}
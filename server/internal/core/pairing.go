@@ -0,0 +1,45 @@
+package core
+
+import (
+	"encoding/base64"
+	"net/url"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// pairingCodeTTL is how long a pairing code can be redeemed
+const pairingCodeTTL = 5 * time.Minute
+
+// StartPairing creates a pairing code for a new device. The QR code encodes
+// a robostream://pair link with the server address and the code, so a phone
+// can pair without typing either, and over TLS the certificate fingerprint
+// it should pin.
+func (c *Core) StartPairing() (*models.PairingCode, error) {
+	code, err := c.SessionManager.StartPairing(pairingCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	link := url.URL{Scheme: "robostream", Host: "pair"}
+	query := url.Values{"code": {code.Code}}
+	if ips := LocalIPs(); len(ips) > 0 {
+		query.Set("server", c.clientURL(ips[0]))
+	}
+	if fingerprint := c.Fingerprint(); fingerprint != "" {
+		code.Fingerprint = fingerprint
+		query.Set("fingerprint", fingerprint)
+	}
+	link.RawQuery = query.Encode()
+
+	png, err := qrcode.Encode(link.String(), qrcode.Medium, 256)
+	if err != nil {
+		apiLog.Warn("Failed to render pairing QR code", "error", err)
+	} else {
+		code.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+
+	apiLog.Info("Pairing code created", "expires", code.ExpiresAt)
+	return code, nil
+}
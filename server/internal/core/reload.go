@@ -0,0 +1,73 @@
+package core
+
+import (
+	"github.com/robomon1/robo-stream/server/internal/manager"
+)
+
+// OnReload registers a function told about every data file reload, e.g. to
+// show it in the UI. Call it before Start.
+func (c *Core) OnReload(fn func(*manager.ReloadResult)) {
+	c.onReload = fn
+}
+
+// ReloadResults returns the most recent reloads of externally edited data
+// files, including any conflicts with in-app edits
+func (c *Core) ReloadResults() []*manager.ReloadResult {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	results := make([]*manager.ReloadResult, len(c.reloadResults))
+	copy(results, c.reloadResults)
+	return results
+}
+
+// handleDataFileChange reloads a data file that was edited outside the app
+func (c *Core) handleDataFileChange(filename string) {
+	storageLog.Info("File changed on disk, reloading", "file", filename)
+
+	var err error
+	switch filename {
+	case manager.ButtonsFile:
+		_, err = c.ButtonManager.Reload()
+	case manager.ConfigsFile:
+		_, err = c.ConfigManager.Reload()
+	}
+	if err != nil {
+		storageLog.Error("Rejected external edit", "error", err)
+	}
+}
+
+// handleReload records a reload and pushes affected configurations to
+// connected clients
+func (c *Core) handleReload(result *manager.ReloadResult) {
+	for _, conflict := range result.Conflicts {
+		storageLog.Warn("Conflict in external edit", "file", result.File, "id", conflict.ID, "reason", conflict.Reason)
+	}
+
+	c.reloadMu.Lock()
+	c.reloadResults = append(c.reloadResults, result)
+	if len(c.reloadResults) > maxReloadResults {
+		c.reloadResults = c.reloadResults[len(c.reloadResults)-maxReloadResults:]
+	}
+	c.reloadMu.Unlock()
+
+	if c.onReload != nil {
+		c.onReload(result)
+	}
+
+	if result.Error != "" || len(result.Changed) == 0 {
+		return
+	}
+
+	var affected []string
+	switch result.File {
+	case manager.ButtonsFile:
+		affected = c.ConfigManager.ConfigsUsingButtons(result.Changed)
+		go c.checkButtons()
+	case manager.ConfigsFile:
+		affected = result.Changed
+	}
+	storageLog.Info("Reloaded file", "file", result.File, "changed", len(result.Changed), "configurations", len(affected))
+
+	c.APIServer.NotifyConfigChanged(affected)
+}
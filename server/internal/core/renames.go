@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// OnButtonIssues registers a function told whenever the library buttons are
+// checked against OBS, e.g. to flag broken ones in the UI. Call it before
+// Start.
+func (c *Core) OnButtonIssues(fn func([]manager.ButtonIssue)) {
+	c.onButtonIssues = fn
+}
+
+// ButtonIssues returns the library buttons that didn't work in OBS when they
+// were last checked
+func (c *Core) ButtonIssues() []manager.ButtonIssue {
+	c.buttonIssuesMu.Lock()
+	defer c.buttonIssuesMu.Unlock()
+	return append([]manager.ButtonIssue{}, c.buttonIssues...)
+}
+
+// checkButtons checks every library button against OBS and flags the broken
+// ones. It runs on every connect and after buttons change.
+func (c *Core) checkButtons() {
+	if !c.OBSManager.IsConnected() {
+		return
+	}
+
+	// One check at a time so an older result can't replace a newer one
+	c.checkMu.Lock()
+	defer c.checkMu.Unlock()
+
+	dryRun, err := c.OBSManager.DryRun()
+	if err != nil {
+		obsLog.Warn("Failed to check buttons against OBS", "error", err)
+		return
+	}
+	issues := dryRun.CheckButtons(c.ButtonManager.List())
+	for _, issue := range issues {
+		obsLog.Warn("Button won't work in OBS", "button", issue.ButtonName, "id", issue.ButtonID, "problems", issue.Problems)
+	}
+
+	c.buttonIssuesMu.Lock()
+	c.buttonIssues = issues
+	c.buttonIssuesMu.Unlock()
+
+	if c.onButtonIssues != nil {
+		c.onButtonIssues(issues)
+	}
+}
+
+// handleRename points the buttons using a scene, input or filter renamed in
+// OBS at the new name and records it in the audit log
+func (c *Core) handleRename(rename manager.Rename) {
+	ids, err := c.ButtonManager.ApplyRename(rename)
+	if err != nil {
+		storageLog.Error("Failed to save buttons after rename in OBS", "error", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%s %q renamed to %q in OBS, updated %d button(s)", rename.Kind, rename.OldName, rename.NewName, len(ids))
+	if rename.Kind == manager.RenameFilter {
+		message = fmt.Sprintf("filter %q on %q renamed to %q in OBS, updated %d button(s)", rename.OldName, rename.Source, rename.NewName, len(ids))
+	}
+	if err := c.AuditManager.Record(models.AuditOBSRename, message, ids); err != nil {
+		storageLog.Error("Failed to write audit log", "error", err)
+	}
+	appLog.Info("Followed rename in OBS", "kind", rename.Kind, "old", rename.OldName, "new", rename.NewName, "buttons", len(ids))
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/logging"
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// settingsEnvPrefix prefixes the environment variable of each setting, e.g.
+// ROBO_STREAM_PORT or ROBO_STREAM_DATA_DIR
+const settingsEnvPrefix = "ROBO_STREAM_"
+
+// ParseSettingOverrides collects settings given by environment variables and
+// command line flags. Flags win over the environment. Each setting has a flag
+// named after its key with dashes, e.g. --listen-address or --idle-timeout.
+// Callers may add flags of their own to flags first. On a flag error the
+// environment overrides are still returned.
+func ParseSettingOverrides(flags *flag.FlagSet, args []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, key := range manager.SettingKeys {
+		if value, ok := os.LookupEnv(settingsEnvPrefix + strings.ToUpper(key)); ok {
+			overrides[key] = value
+		}
+	}
+
+	settingFlags := make(map[string]bool, len(manager.SettingKeys))
+	for _, key := range manager.SettingKeys {
+		name := strings.ReplaceAll(key, "_", "-")
+		flags.String(name, "", "override the "+key+" setting")
+		settingFlags[name] = true
+	}
+	if err := flags.Parse(args); err != nil {
+		return overrides, err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if settingFlags[f.Name] {
+			overrides[strings.ReplaceAll(f.Name, "-", "_")] = f.Value.String()
+		}
+	})
+
+	return overrides, nil
+}
+
+// ResolveDataDir returns the data directory given by overrides, or the
+// platform default
+func ResolveDataDir(overrides map[string]string) (string, error) {
+	if dir := overrides[manager.SettingDataDir]; dir != "" {
+		return filepath.Abs(dir)
+	}
+	return defaultDataDir()
+}
+
+// defaultDataDir returns the platform's data directory for the server:
+// $XDG_DATA_HOME (or ~/.local/share) on Linux, Application Support on macOS
+// and %AppData% on Windows. Data left in ~/.robo-stream-server by older
+// versions keeps being used.
+func defaultDataDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS != "windows" {
+		legacy := filepath.Join(homeDir, ".robo-stream-server")
+		if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+			return legacy, nil
+		}
+	}
+
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(configDir, "RoboStreamServer"), nil
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" || !filepath.IsAbs(dataHome) {
+			dataHome = filepath.Join(homeDir, ".local", "share")
+		}
+		return filepath.Join(dataHome, "robo-stream-server"), nil
+	}
+}
+
+// UpdateSettings saves settings and applies them right away: the API server
+// moves to a new address or starts or stops using TLS, logging switches
+// level, session cleanup picks up new timings and OBS is connected if
+// auto-connect was turned on. The data directory can only be changed with a
+// flag or environment variable.
+func (c *Core) UpdateSettings(settings models.Settings) (models.Settings, error) {
+	old := c.SettingsManager.Get()
+	current, err := c.SettingsManager.Update(settings)
+	if err != nil {
+		return old, err
+	}
+
+	if err := logging.SetLevel(current.LogLevel); err != nil {
+		return current, err
+	}
+
+	select {
+	case c.settingsChanged <- struct{}{}:
+	default:
+	}
+
+	if err := c.moveAPIServer(endpointFor(current)); err != nil {
+		return current, err
+	}
+
+	if current.OBSAutoConnect && !old.OBSAutoConnect && !c.OBSManager.IsConnected() {
+		go c.autoConnectOBS()
+	}
+
+	appLog.Info("Settings updated")
+	return current, nil
+}
+
+// moveAPIServer restarts the API server on a new endpoint, going back to
+// the old one if the new endpoint can't be used
+func (c *Core) moveAPIServer(endpoint apiEndpoint) error {
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	if endpoint == c.endpoint {
+		return nil
+	}
+
+	tlsConfig, fingerprint, err := c.tlsConfig(endpoint)
+	if err != nil {
+		return fmt.Errorf("settings saved, but the API server could not use TLS: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.APIServer.Stop(ctx); err != nil {
+		apiLog.Warn("API server did not stop cleanly", "error", err)
+	}
+
+	if err := c.APIServer.Start(endpoint.addr, tlsConfig); err != nil {
+		if restartErr := c.APIServer.Start(c.endpoint.addr, c.listenTLS); restartErr != nil {
+			apiLog.Error("API server failed to restart", "addr", c.endpoint.addr, "error", restartErr)
+		}
+		return fmt.Errorf("settings saved, but the API server could not move to %s: %w", endpoint.addr, err)
+	}
+	c.endpoint = endpoint
+	c.listenTLS = tlsConfig
+	c.fingerprint = fingerprint
+	c.advertise()
+	return nil
+}
+
+// listenAddress returns the API server address for settings
+func listenAddress(settings models.Settings) string {
+	return net.JoinHostPort(settings.ListenAddress, strconv.Itoa(settings.Port))
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/robomon1/robo-stream/server/internal/certs"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// apiEndpoint is where and how the API server listens. The server restarts
+// when any of it changes.
+type apiEndpoint struct {
+	addr     string
+	tls      bool
+	certFile string
+	keyFile  string
+}
+
+// endpointFor returns the API server endpoint for settings
+func endpointFor(settings models.Settings) apiEndpoint {
+	endpoint := apiEndpoint{addr: listenAddress(settings), tls: settings.TLS}
+	if settings.TLS {
+		endpoint.certFile = settings.TLSCertFile
+		endpoint.keyFile = settings.TLSKeyFile
+	}
+	return endpoint
+}
+
+// tlsConfig loads the certificate for an endpoint, returning a nil config
+// for plain HTTP
+func (c *Core) tlsConfig(endpoint apiEndpoint) (*tls.Config, string, error) {
+	if !endpoint.tls {
+		return nil, "", nil
+	}
+	cert, err := certs.Load(c.Storage.GetDataDir(), endpoint.certFile, endpoint.keyFile, LocalIPs())
+	if err != nil {
+		return nil, "", err
+	}
+	fingerprint := certs.Fingerprint(cert)
+	apiLog.Info("TLS certificate loaded", "fingerprint", fingerprint)
+	return &tls.Config{Certificates: []tls.Certificate{*cert}, MinVersion: tls.VersionTLS12}, fingerprint, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate the API
+// server uses, or "" when it serves plain HTTP
+func (c *Core) Fingerprint() string {
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+	return c.fingerprint
+}
+
+// clientURL returns the URL clients reach the API server at on ip
+func (c *Core) clientURL(ip string) string {
+	settings := c.SettingsManager.Get()
+	scheme := "http"
+	if settings.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ip, strconv.Itoa(settings.Port)))
+}
@@ -0,0 +1,125 @@
+package declarative
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Export describes the current server state as a layout. Buttons and
+// configurations without a slug are given one derived from their name, and
+// the slug is saved so later plans match them instead of creating copies.
+func Export(t Target) (*Spec, error) {
+	if err := adoptButtons(t); err != nil {
+		return nil, err
+	}
+	if err := adoptConfigs(t); err != nil {
+		return nil, err
+	}
+
+	st := readState(t)
+	spec := &Spec{
+		Buttons:        make([]ButtonSpec, 0, len(st.buttons)),
+		Configurations: make([]ConfigurationSpec, 0, len(st.configs)),
+	}
+
+	for _, btn := range st.buttons {
+		spec.Buttons = append(spec.Buttons, ButtonSpec{
+			Slug:        btn.Slug,
+			Name:        btn.Name,
+			Description: btn.Description,
+			Icon:        btn.Icon,
+			Color:       btn.Color,
+			Action:      ActionSpec{Type: btn.Action.Type, Params: btn.Action.Params},
+		})
+	}
+	sort.Slice(spec.Buttons, func(i, j int) bool { return spec.Buttons[i].Slug < spec.Buttons[j].Slug })
+
+	for _, cfg := range st.configs {
+		buttons := make(map[string]string, len(cfg.Buttons))
+		for position, id := range cfg.Buttons {
+			if slug, ok := st.buttonSlugs[id]; ok {
+				buttons[position] = slug
+			}
+		}
+		spec.Configurations = append(spec.Configurations, ConfigurationSpec{
+			Slug:        cfg.Slug,
+			Name:        cfg.Name,
+			Description: cfg.Description,
+			Default:     cfg.IsDefault,
+			Role:        cfg.Role,
+			Grid:        GridSpec{Rows: cfg.Grid.Rows, Cols: cfg.Grid.Cols},
+			Buttons:     buttons,
+		})
+	}
+	sort.Slice(spec.Configurations, func(i, j int) bool { return spec.Configurations[i].Slug < spec.Configurations[j].Slug })
+
+	spec.Assignments = assignmentSpecs(t.Assignments.List(), st.configSlugs)
+	return spec, nil
+}
+
+// adoptButtons gives every button without a slug a unique one
+func adoptButtons(t Target) error {
+	buttons := t.Buttons.List()
+	sort.Slice(buttons, func(i, j int) bool { return buttons[i].CreatedAt.Before(buttons[j].CreatedAt) })
+
+	taken := make(map[string]bool, len(buttons))
+	for _, btn := range buttons {
+		taken[btn.Slug] = btn.Slug != ""
+	}
+	for _, btn := range buttons {
+		if btn.Slug != "" {
+			continue
+		}
+		updated := *btn
+		updated.Slug = uniqueSlug(btn.Name, "button", taken)
+		if err := t.Buttons.Update(&updated); err != nil {
+			return fmt.Errorf("button %s: %w", btn.ID, err)
+		}
+	}
+	return nil
+}
+
+// adoptConfigs gives every configuration without a slug a unique one
+func adoptConfigs(t Target) error {
+	configs := t.Configs.List()
+	sort.Slice(configs, func(i, j int) bool { return configs[i].CreatedAt.Before(configs[j].CreatedAt) })
+
+	taken := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		taken[cfg.Slug] = cfg.Slug != ""
+	}
+	for _, cfg := range configs {
+		if cfg.Slug != "" {
+			continue
+		}
+		updated := *cfg
+		updated.Slug = uniqueSlug(cfg.Name, "configuration", taken)
+		if err := t.Configs.Update(&updated); err != nil {
+			return fmt.Errorf("configuration %s: %w", cfg.ID, err)
+		}
+	}
+	return nil
+}
+
+// uniqueSlug derives a slug from a name, numbering it if already taken
+func uniqueSlug(name, fallback string, taken map[string]bool) string {
+	base := slugify(name)
+	if base == "" {
+		base = fallback
+	}
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	taken[slug] = true
+	return slug
+}
+
+// slugify lowercases a name and joins its words with dashes
+func slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(words, "-")
+}
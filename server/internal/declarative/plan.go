@@ -0,0 +1,426 @@
+package declarative
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/robomon1/robo-stream/server/internal/manager"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// Change actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change kinds
+const (
+	KindButton        = "button"
+	KindConfiguration = "configuration"
+	KindAssignments   = "assignments"
+)
+
+// Target is the server state a layout is planned against and applied to
+type Target struct {
+	Buttons     *manager.ButtonManager
+	Configs     *manager.ConfigManager
+	Assignments *manager.AssignmentManager
+}
+
+// Change is one difference between a layout file and the server state
+type Change struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Slug   string   `json:"slug,omitempty"`
+	Fields []string `json:"fields,omitempty"` // fields that differ, for updates
+}
+
+// Plan lists the changes needed to make the server match a layout file
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Prune is set if the plan includes deleting what the file doesn't
+	// describe
+	Prune bool `json:"prune"`
+}
+
+// Empty reports whether the server already matches the layout
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// state is the current server state indexed by slug
+type state struct {
+	buttons     map[string]*models.Button        // slug -> button
+	configs     map[string]*models.Configuration // slug -> configuration
+	buttonSlugs map[string]string                // button ID -> slug
+	configSlugs map[string]string                // configuration ID -> slug
+}
+
+// readState indexes the current buttons and configurations by slug
+func readState(t Target) *state {
+	st := &state{
+		buttons:     make(map[string]*models.Button),
+		configs:     make(map[string]*models.Configuration),
+		buttonSlugs: make(map[string]string),
+		configSlugs: make(map[string]string),
+	}
+	for _, btn := range t.Buttons.List() {
+		if btn.Slug == "" {
+			continue
+		}
+		st.buttons[btn.Slug] = btn
+		st.buttonSlugs[btn.ID] = btn.Slug
+	}
+	for _, cfg := range t.Configs.List() {
+		if cfg.Slug == "" {
+			continue
+		}
+		st.configs[cfg.Slug] = cfg
+		st.configSlugs[cfg.ID] = cfg.Slug
+	}
+	return st
+}
+
+// NewPlan diffs a layout against the server state. With prune set, buttons
+// and configurations missing from the layout are deleted; otherwise they are
+// left alone.
+func NewPlan(spec *Spec, t Target, prune bool) *Plan {
+	st := readState(t)
+	plan := &Plan{Changes: make([]Change, 0), Prune: prune}
+
+	for _, want := range spec.Buttons {
+		have, ok := st.buttons[want.Slug]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: KindButton, Slug: want.Slug})
+			continue
+		}
+		if fields := buttonDiff(want, have); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: KindButton, Slug: want.Slug, Fields: fields})
+		}
+	}
+
+	manageDefault := false
+	for _, want := range spec.Configurations {
+		manageDefault = manageDefault || want.Default
+	}
+	for _, want := range spec.Configurations {
+		have, ok := st.configs[want.Slug]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: KindConfiguration, Slug: want.Slug})
+			continue
+		}
+		if fields := configDiff(want, have, st.buttonSlugs, manageDefault); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: KindConfiguration, Slug: want.Slug, Fields: fields})
+		}
+	}
+
+	if !reflect.DeepEqual(assignmentSpecs(t.Assignments.List(), st.configSlugs), normalizeAssignments(spec.Assignments)) {
+		plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: KindAssignments})
+	}
+
+	if prune {
+		plan.Changes = append(plan.Changes, pruneChanges(spec, t)...)
+	}
+	return plan
+}
+
+// pruneChanges lists deletions of everything not described by the layout.
+// Items without a slug are named by ID.
+func pruneChanges(spec *Spec, t Target) []Change {
+	changes := make([]Change, 0)
+
+	wantConfigs := make(map[string]bool, len(spec.Configurations))
+	for _, cfg := range spec.Configurations {
+		wantConfigs[cfg.Slug] = true
+	}
+	for _, cfg := range t.Configs.List() {
+		if cfg.Slug == "" || !wantConfigs[cfg.Slug] {
+			changes = append(changes, Change{Action: ActionDelete, Kind: KindConfiguration, Slug: slugOrID(cfg.Slug, cfg.ID)})
+		}
+	}
+
+	wantButtons := make(map[string]bool, len(spec.Buttons))
+	for _, btn := range spec.Buttons {
+		wantButtons[btn.Slug] = true
+	}
+	for _, btn := range t.Buttons.List() {
+		if btn.Slug == "" || !wantButtons[btn.Slug] {
+			changes = append(changes, Change{Action: ActionDelete, Kind: KindButton, Slug: slugOrID(btn.Slug, btn.ID)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind == KindConfiguration
+		}
+		return changes[i].Slug < changes[j].Slug
+	})
+	return changes
+}
+
+// Apply makes the server match a layout and returns the changes it made.
+// The plan is recomputed against the current state rather than trusted from
+// an earlier call.
+func Apply(spec *Spec, t Target, prune bool) (*Plan, error) {
+	plan := NewPlan(spec, t, prune)
+	if plan.Empty() {
+		return plan, nil
+	}
+
+	st := readState(t)
+	buttonIDs := make(map[string]string, len(spec.Buttons)) // slug -> ID
+
+	for _, want := range spec.Buttons {
+		btn := &models.Button{}
+		if have, ok := st.buttons[want.Slug]; ok {
+			copied := *have
+			btn = &copied
+		}
+		btn.Slug = want.Slug
+		btn.Name = want.Name
+		btn.Description = want.Description
+		btn.Icon = want.Icon
+		btn.Color = want.Color
+		btn.Action = models.ButtonAction{Type: want.Action.Type, Params: normalizeParams(want.Action.Params)}
+
+		var err error
+		switch {
+		case btn.ID == "":
+			err = t.Buttons.Create(btn)
+		case len(buttonDiff(want, st.buttons[want.Slug])) > 0:
+			err = t.Buttons.Update(btn)
+		}
+		if err != nil {
+			return plan, fmt.Errorf("button %s: %w", want.Slug, err)
+		}
+		buttonIDs[want.Slug] = btn.ID
+	}
+
+	manageDefault := false
+	defaultID := ""
+	for _, want := range spec.Configurations {
+		cfg := &models.Configuration{}
+		have, exists := st.configs[want.Slug]
+		if exists {
+			copied := *have
+			cfg = &copied
+		}
+		cfg.Slug = want.Slug
+		cfg.Name = want.Name
+		cfg.Description = want.Description
+		cfg.Role = want.Role
+		cfg.Grid = models.GridConfig{Rows: want.Grid.Rows, Cols: want.Grid.Cols}
+		cfg.Buttons = make(map[string]string, len(want.Buttons))
+		for position, slug := range want.Buttons {
+			cfg.Buttons[position] = buttonIDs[slug]
+		}
+
+		var err error
+		switch {
+		case !exists:
+			cfg.IsDefault = false
+			err = t.Configs.Create(cfg)
+		case len(configDiff(want, have, st.buttonSlugs, false)) > 0 || buttonsRenumbered(cfg.Buttons, have.Buttons):
+			err = t.Configs.Update(cfg)
+		}
+		if err != nil {
+			return plan, fmt.Errorf("configuration %s: %w", want.Slug, err)
+		}
+		if want.Default {
+			manageDefault = true
+			defaultID = cfg.ID
+		}
+	}
+	if manageDefault {
+		if current, err := t.Configs.GetDefault(); err != nil || current.ID != defaultID {
+			if err := t.Configs.SetDefault(defaultID); err != nil {
+				return plan, fmt.Errorf("default configuration: %w", err)
+			}
+		}
+	}
+
+	configIDs := make(map[string]string, len(spec.Configurations))
+	for _, cfg := range t.Configs.List() {
+		if cfg.Slug != "" {
+			configIDs[cfg.Slug] = cfg.ID
+		}
+	}
+	rules := make([]models.AssignmentRule, 0, len(spec.Assignments))
+	for _, rule := range spec.Assignments {
+		rules = append(rules, models.AssignmentRule{
+			ClientID:   rule.ClientID,
+			ClientName: rule.ClientName,
+			ConfigID:   configIDs[rule.Configuration],
+		})
+	}
+	if !reflect.DeepEqual(rules, t.Assignments.List()) {
+		if err := t.Assignments.Replace(rules); err != nil {
+			return plan, fmt.Errorf("assignments: %w", err)
+		}
+	}
+
+	if prune {
+		if err := applyPrune(spec, t); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// applyPrune deletes configurations and buttons missing from the layout
+func applyPrune(spec *Spec, t Target) error {
+	for _, change := range pruneChanges(spec, t) {
+		var err error
+		switch change.Kind {
+		case KindConfiguration:
+			err = deleteConfig(t, change.Slug)
+		case KindButton:
+			err = deleteButton(t, change.Slug)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", change.Kind, change.Slug, err)
+		}
+	}
+	return nil
+}
+
+// deleteConfig deletes a configuration by slug, or by ID if it has none
+func deleteConfig(t Target, slugOrID string) error {
+	for _, cfg := range t.Configs.List() {
+		if cfg.Slug == slugOrID || (cfg.Slug == "" && cfg.ID == slugOrID) {
+			return t.Configs.Delete(cfg.ID)
+		}
+	}
+	return nil
+}
+
+// deleteButton deletes a button by slug, or by ID if it has none
+func deleteButton(t Target, slugOrID string) error {
+	for _, btn := range t.Buttons.List() {
+		if btn.Slug == slugOrID || (btn.Slug == "" && btn.ID == slugOrID) {
+			return t.Buttons.Delete(btn.ID)
+		}
+	}
+	return nil
+}
+
+// buttonDiff lists the fields of a button that differ from the layout
+func buttonDiff(want ButtonSpec, have *models.Button) []string {
+	fields := make([]string, 0)
+	if want.Name != have.Name {
+		fields = append(fields, "name")
+	}
+	if want.Description != have.Description {
+		fields = append(fields, "description")
+	}
+	if want.Icon != have.Icon {
+		fields = append(fields, "icon")
+	}
+	if want.Color != have.Color {
+		fields = append(fields, "color")
+	}
+	if want.Action.Type != have.Action.Type {
+		fields = append(fields, "action.type")
+	}
+	if !sameJSON(normalizeParams(want.Action.Params), normalizeParams(have.Action.Params)) {
+		fields = append(fields, "action.params")
+	}
+	return fields
+}
+
+// configDiff lists the fields of a configuration that differ from the layout
+func configDiff(want ConfigurationSpec, have *models.Configuration, buttonSlugs map[string]string, manageDefault bool) []string {
+	fields := make([]string, 0)
+	if want.Name != have.Name {
+		fields = append(fields, "name")
+	}
+	if want.Description != have.Description {
+		fields = append(fields, "description")
+	}
+	if want.Role != have.Role {
+		fields = append(fields, "role")
+	}
+	if want.Grid.Rows != have.Grid.Rows || want.Grid.Cols != have.Grid.Cols {
+		fields = append(fields, "grid")
+	}
+
+	haveButtons := make(map[string]string, len(have.Buttons))
+	for position, id := range have.Buttons {
+		haveButtons[position] = buttonSlugs[id]
+	}
+	wantButtons := want.Buttons
+	if wantButtons == nil {
+		wantButtons = map[string]string{}
+	}
+	if !reflect.DeepEqual(wantButtons, haveButtons) {
+		fields = append(fields, "buttons")
+	}
+
+	if manageDefault && want.Default != have.IsDefault {
+		fields = append(fields, "default")
+	}
+	return fields
+}
+
+// buttonsRenumbered reports whether position assignments point at different
+// button IDs, which happens when a referenced button was just recreated
+func buttonsRenumbered(want, have map[string]string) bool {
+	return !reflect.DeepEqual(want, have)
+}
+
+// assignmentSpecs converts stored rules to their layout form
+func assignmentSpecs(rules []models.AssignmentRule, configSlugs map[string]string) []AssignmentSpec {
+	specs := make([]AssignmentSpec, 0, len(rules))
+	for _, rule := range rules {
+		specs = append(specs, AssignmentSpec{
+			ClientID:      rule.ClientID,
+			ClientName:    rule.ClientName,
+			Configuration: configSlugs[rule.ConfigID],
+		})
+	}
+	return specs
+}
+
+// normalizeAssignments turns a nil rule list into an empty one
+func normalizeAssignments(rules []AssignmentSpec) []AssignmentSpec {
+	if rules == nil {
+		return make([]AssignmentSpec, 0)
+	}
+	return rules
+}
+
+// normalizeParams round-trips params through JSON so values decoded from
+// YAML compare and store the same way as values decoded from JSON
+func normalizeParams(params map[string]interface{}) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return params
+	}
+	return normalized
+}
+
+// sameJSON compares two values by their JSON encoding
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// slugOrID names an item by slug when it has one
+func slugOrID(slug, id string) string {
+	if slug != "" {
+		return slug
+	}
+	return id
+}
@@ -0,0 +1,180 @@
+// Package declarative reads show layouts from human-editable YAML files and
+// applies them to the button and configuration managers.
+//
+// A layout file names buttons and configurations by slug instead of UUID, so
+// it can be kept in git, reviewed in pull requests and applied to reproduce a
+// show machine:
+//
+//	buttons:
+//	  - slug: go-live
+//	    name: Go Live
+//	    color: "#e74c3c"
+//	    action:
+//	      type: start_stream
+//	configurations:
+//	  - slug: host
+//	    name: Host
+//	    default: true
+//	    grid: {rows: 3, cols: 4}
+//	    buttons:
+//	      btn-0-0: go-live
+//	assignments:
+//	  - client_name: "Host*"
+//	    configuration: host
+package declarative
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// slugPattern restricts slugs to lowercase words joined by dashes
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Spec is the content of a layout file
+type Spec struct {
+	Buttons        []ButtonSpec        `yaml:"buttons" json:"buttons"`
+	Configurations []ConfigurationSpec `yaml:"configurations" json:"configurations"`
+	Assignments    []AssignmentSpec    `yaml:"assignments,omitempty" json:"assignments,omitempty"`
+}
+
+// ButtonSpec describes a library button
+type ButtonSpec struct {
+	Slug        string     `yaml:"slug" json:"slug"`
+	Name        string     `yaml:"name" json:"name"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Icon        string     `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Color       string     `yaml:"color,omitempty" json:"color,omitempty"`
+	Action      ActionSpec `yaml:"action" json:"action"`
+}
+
+// ActionSpec describes what a button does
+type ActionSpec struct {
+	Type   string                 `yaml:"type" json:"type"`
+	Params map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// ConfigurationSpec describes a button layout. Buttons maps grid positions
+// (btn-row-col) to button slugs.
+type ConfigurationSpec struct {
+	Slug        string            `yaml:"slug" json:"slug"`
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     bool              `yaml:"default,omitempty" json:"default,omitempty"`
+	Role        string            `yaml:"role,omitempty" json:"role,omitempty"`
+	Grid        GridSpec          `yaml:"grid" json:"grid"`
+	Buttons     map[string]string `yaml:"buttons" json:"buttons"`
+}
+
+// GridSpec is the size of a configuration's grid
+type GridSpec struct {
+	Rows int `yaml:"rows" json:"rows"`
+	Cols int `yaml:"cols" json:"cols"`
+}
+
+// AssignmentSpec picks the configuration for newly registered clients whose
+// ID and name match the given globs
+type AssignmentSpec struct {
+	ClientID      string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	ClientName    string `yaml:"client_name,omitempty" json:"client_name,omitempty"`
+	Configuration string `yaml:"configuration" json:"configuration"`
+}
+
+// Load reads and validates a layout file
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates layout file content
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Save writes a layout file
+func (s *Spec) Save(path string) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Validate checks slugs, references and grid positions
+func (s *Spec) Validate() error {
+	buttons := make(map[string]bool, len(s.Buttons))
+	for i, btn := range s.Buttons {
+		if !slugPattern.MatchString(btn.Slug) {
+			return fmt.Errorf("button %d: invalid slug %q", i, btn.Slug)
+		}
+		if buttons[btn.Slug] {
+			return fmt.Errorf("duplicate button slug: %s", btn.Slug)
+		}
+		if btn.Action.Type == "" {
+			return fmt.Errorf("button %s: missing action type", btn.Slug)
+		}
+		buttons[btn.Slug] = true
+	}
+
+	configs := make(map[string]bool, len(s.Configurations))
+	defaults := 0
+	for i, cfg := range s.Configurations {
+		if !slugPattern.MatchString(cfg.Slug) {
+			return fmt.Errorf("configuration %d: invalid slug %q", i, cfg.Slug)
+		}
+		if configs[cfg.Slug] {
+			return fmt.Errorf("duplicate configuration slug: %s", cfg.Slug)
+		}
+		if cfg.Grid.Rows < 1 || cfg.Grid.Cols < 1 {
+			return fmt.Errorf("configuration %s: invalid grid %dx%d", cfg.Slug, cfg.Grid.Rows, cfg.Grid.Cols)
+		}
+		for position, slug := range cfg.Buttons {
+			var row, col int
+			if n, err := fmt.Sscanf(position, "btn-%d-%d", &row, &col); err != nil || n != 2 ||
+				position != fmt.Sprintf("btn-%d-%d", row, col) {
+				return fmt.Errorf("configuration %s: invalid position %q", cfg.Slug, position)
+			}
+			if row < 0 || col < 0 || row >= cfg.Grid.Rows || col >= cfg.Grid.Cols {
+				return fmt.Errorf("configuration %s: position %s is outside the %dx%d grid", cfg.Slug, position, cfg.Grid.Rows, cfg.Grid.Cols)
+			}
+			if !buttons[slug] {
+				return fmt.Errorf("configuration %s: unknown button %q at %s", cfg.Slug, slug, position)
+			}
+		}
+		if cfg.Default {
+			defaults++
+		}
+		configs[cfg.Slug] = true
+	}
+	if defaults > 1 {
+		return fmt.Errorf("%d configurations are marked as default", defaults)
+	}
+
+	for i, rule := range s.Assignments {
+		if !configs[rule.Configuration] {
+			return fmt.Errorf("assignment %d: unknown configuration %q", i, rule.Configuration)
+		}
+	}
+	return nil
+}
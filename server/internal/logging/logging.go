@@ -0,0 +1,179 @@
+// Package logging sets up the server's structured logs. Records are written
+// as JSON lines to a rotated file in the data directory, as readable text to
+// stderr, and into a buffer of recent lines the server UI shows.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Subsystems
+const (
+	OBS     = "obs"
+	API     = "api"
+	Storage = "storage"
+	Session = "session"
+	App     = "app"
+)
+
+// Log files are rotated at maxSizeMB, keeping maxBackups old files
+const (
+	Dir        = "logs"
+	File       = "robo-stream.log"
+	maxSizeMB  = 10
+	maxBackups = 5
+	maxAgeDays = 30
+)
+
+var (
+	level = new(slog.LevelVar)
+
+	sinksMu sync.RWMutex
+	sinks   = []slog.Handler{newConsoleHandler()}
+	file    *lumberjack.Logger
+)
+
+func init() {
+	// Route the standard logger, used by dependencies, through slog
+	log.SetFlags(0)
+	log.SetOutput(bridge{})
+}
+
+// For returns the logger of a subsystem. It can be used before Setup; records
+// go to stderr until then.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&handler{}).With("subsystem", subsystem)
+}
+
+// Setup starts writing the log file in dataDir and keeping recent lines
+func Setup(dataDir string) error {
+	dir := filepath.Join(dataDir, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = &lumberjack.Logger{
+		Filename:   filepath.Join(dir, File),
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	}
+	sinks = []slog.Handler{
+		newConsoleHandler(),
+		slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level}),
+		slog.NewJSONHandler(recent, &slog.HandlerOptions{Level: level}),
+	}
+	return nil
+}
+
+// Path returns the current log file, or "" before Setup
+func Path() string {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	if file == nil {
+		return ""
+	}
+	return file.Filename
+}
+
+// SetLevel sets the lowest level logged: debug, info, warn or error
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// ParseLevel reads a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return l, nil
+}
+
+// Level returns the lowest level logged
+func Level() slog.Level {
+	return level.Level()
+}
+
+func newConsoleHandler() slog.Handler {
+	return slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+}
+
+// handler passes records to the current sinks, so loggers made before Setup
+// pick up the log file
+type handler struct {
+	// wrap applies WithAttrs and WithGroup calls to each sink
+	wrap []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	sinksMu.RLock()
+	current := sinks
+	sinksMu.RUnlock()
+
+	var firstErr error
+	for _, sink := range current {
+		for _, wrap := range h.wrap {
+			sink = wrap(sink)
+		}
+		if err := sink.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(sink slog.Handler) slog.Handler { return sink.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(sink slog.Handler) slog.Handler { return sink.WithGroup(name) })
+}
+
+func (h *handler) with(wrap func(slog.Handler) slog.Handler) *handler {
+	return &handler{wrap: append(h.wrap[:len(h.wrap):len(h.wrap)], wrap)}
+}
+
+// bridge writes lines from the standard logger as app records, at a level
+// guessed from the line
+type bridge struct{}
+
+var bridgeLogger = For(App)
+
+func (bridge) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	lower := strings.ToLower(line)
+	l := slog.LevelInfo
+	switch {
+	case strings.Contains(lower, "error") || strings.Contains(lower, "fail"):
+		l = slog.LevelError
+	case strings.Contains(lower, "warn"):
+		l = slog.LevelWarn
+	}
+	bridgeLogger.Log(context.Background(), l, line)
+	return len(p), nil
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+)
+
+// recentLines is how many log lines are kept for the log viewer
+const recentLines = 1000
+
+// subscriberBuffer is how many lines a slow subscriber may fall behind
+// before lines are dropped for it
+const subscriberBuffer = 256
+
+var recent = &ring{
+	lines:       make([]json.RawMessage, 0, recentLines),
+	subscribers: make(map[chan json.RawMessage]struct{}),
+}
+
+// ring keeps the last log lines and passes new ones to subscribers. The JSON
+// handler writes each record with a single Write.
+type ring struct {
+	mu          sync.Mutex
+	lines       []json.RawMessage
+	next        int
+	subscribers map[chan json.RawMessage]struct{}
+}
+
+func (r *ring) Write(p []byte) (int, error) {
+	line := make(json.RawMessage, len(p))
+	copy(line, p)
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.lines) < recentLines {
+		r.lines = append(r.lines, line)
+	} else {
+		r.lines[r.next] = line
+		r.next = (r.next + 1) % recentLines
+	}
+	for ch := range r.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Recent returns up to limit of the latest log lines at level min or above,
+// oldest first. Each is a JSON object with time, level, msg and subsystem
+// fields plus the record's attributes.
+func Recent(limit int, min slog.Level) []json.RawMessage {
+	recent.mu.Lock()
+	ordered := make([]json.RawMessage, 0, len(recent.lines))
+	ordered = append(ordered, recent.lines[recent.next:]...)
+	ordered = append(ordered, recent.lines[:recent.next]...)
+	recent.mu.Unlock()
+
+	lines := make([]json.RawMessage, 0, len(ordered))
+	for _, line := range ordered {
+		if AtLeast(line, min) {
+			lines = append(lines, line)
+		}
+	}
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines
+}
+
+// AtLeast reports whether a log line is at level min or above
+func AtLeast(line json.RawMessage, min slog.Level) bool {
+	var entry struct {
+		Level slog.Level `json:"level"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return true
+	}
+	return entry.Level >= min
+}
+
+// Subscribe returns a channel receiving new log lines, and a function to
+// stop receiving them
+func Subscribe() (<-chan json.RawMessage, func()) {
+	ch := make(chan json.RawMessage, subscriberBuffer)
+	recent.mu.Lock()
+	recent.subscribers[ch] = struct{}{}
+	recent.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			recent.mu.Lock()
+			delete(recent.subscribers, ch)
+			recent.mu.Unlock()
+		})
+	}
+}
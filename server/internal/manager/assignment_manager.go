@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"path"
+	"sync"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+	"github.com/robomon1/robo-stream/server/internal/storage"
+)
+
+// AssignmentsFile is the data file holding the assignment rules
+const AssignmentsFile = "assignments.json"
+
+// AssignmentManager manages the rules that pick a new client's configuration
+type AssignmentManager struct {
+	storage *storage.Storage
+	rules   []models.AssignmentRule
+	mu      sync.RWMutex
+}
+
+// NewAssignmentManager creates a new AssignmentManager
+func NewAssignmentManager(storage *storage.Storage) *AssignmentManager {
+	am := &AssignmentManager{
+		storage: storage,
+		rules:   make([]models.AssignmentRule, 0),
+	}
+	am.load()
+	return am
+}
+
+// load reads rules from storage
+func (am *AssignmentManager) load() error {
+	var rules []models.AssignmentRule
+	if err := am.storage.LoadJSON(AssignmentsFile, &rules); err != nil {
+		return err
+	}
+	if rules != nil {
+		am.rules = rules
+	}
+	return nil
+}
+
+// List returns the rules in match order
+func (am *AssignmentManager) List() []models.AssignmentRule {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	rules := make([]models.AssignmentRule, len(am.rules))
+	copy(rules, am.rules)
+	return rules
+}
+
+// Replace replaces all rules
+func (am *AssignmentManager) Replace(rules []models.AssignmentRule) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if rules == nil {
+		rules = make([]models.AssignmentRule, 0)
+	}
+	am.rules = rules
+	return am.storage.SaveJSON(AssignmentsFile, am.rules)
+}
+
+// Match returns the configuration of the first rule matching the client.
+// Empty patterns match anything.
+func (am *AssignmentManager) Match(clientID, clientName string) (string, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	for _, rule := range am.rules {
+		if globMatch(rule.ClientID, clientID) && globMatch(rule.ClientName, clientName) {
+			return rule.ConfigID, true
+		}
+	}
+	return "", false
+}
+
+// globMatch matches a shell-style pattern. An empty pattern matches anything
+// and an invalid one matches nothing.
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+	"github.com/robomon1/robo-stream/server/internal/storage"
+)
+
+// AuditFile is the data file holding the audit log
+const AuditFile = "audit.json"
+
+// maxAuditEntries is how many audit entries are kept
+const maxAuditEntries = 500
+
+// AuditManager keeps a log of changes the server made to the data on its
+// own, like buttons updated after a rename in OBS
+type AuditManager struct {
+	storage *storage.Storage
+	entries []*models.AuditEntry
+	mu      sync.RWMutex
+}
+
+// NewAuditManager creates a new AuditManager
+func NewAuditManager(storage *storage.Storage) *AuditManager {
+	am := &AuditManager{storage: storage}
+	am.load()
+	return am
+}
+
+// load reads the audit log from storage
+func (am *AuditManager) load() error {
+	var entries []*models.AuditEntry
+	if err := am.storage.LoadJSON(AuditFile, &entries); err != nil {
+		return err
+	}
+	am.entries = entries
+	return nil
+}
+
+// Record adds an entry to the audit log
+func (am *AuditManager) Record(action, message string, buttonIDs []string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.entries = append(am.entries, &models.AuditEntry{
+		Time:      time.Now(),
+		Action:    action,
+		Message:   message,
+		ButtonIDs: buttonIDs,
+	})
+	if len(am.entries) > maxAuditEntries {
+		am.entries = am.entries[len(am.entries)-maxAuditEntries:]
+	}
+	return am.storage.SaveJSON(AuditFile, am.entries)
+}
+
+// List returns up to limit of the latest audit entries, newest first; a
+// limit of 0 returns all of them
+func (am *AuditManager) List(limit int) []*models.AuditEntry {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	n := len(am.entries)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	entries := make([]*models.AuditEntry, 0, n)
+	for i := len(am.entries) - 1; i >= 0 && len(entries) < n; i-- {
+		entries = append(entries, am.entries[i])
+	}
+	return entries
+}
@@ -2,6 +2,8 @@ package manager
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,10 +11,17 @@ import (
 	"github.com/robomon1/robo-stream/server/internal/storage"
 )
 
+// ButtonsFile is the data file holding the button library
+const ButtonsFile = "buttons.json"
+
 // ButtonManager manages the button library
 type ButtonManager struct {
-	storage *storage.Storage
-	buttons map[string]*models.Button
+	storage  *storage.Storage
+	buttons  map[string]*models.Button
+	synced   map[string][]byte // buttons as last synced with disk
+	onReload func(*ReloadResult)
+	onChange func(ids []string)
+	mu       sync.RWMutex
 }
 
 // NewButtonManager creates a new ButtonManager
@@ -20,34 +29,138 @@ func NewButtonManager(storage *storage.Storage) *ButtonManager {
 	bm := &ButtonManager{
 		storage: storage,
 		buttons: make(map[string]*models.Button),
+		synced:  make(map[string][]byte),
 	}
 	bm.load()
 	return bm
 }
 
+// OnReload registers a callback for changes picked up from disk
+func (bm *ButtonManager) OnReload(fn func(*ReloadResult)) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.onReload = fn
+}
+
+// OnChange registers a callback for buttons edited through the manager
+func (bm *ButtonManager) OnChange(fn func(ids []string)) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.onChange = fn
+}
+
+// changed tells the change callback about edited buttons; bm.mu must not be
+// held since the callback usually resolves configurations
+func (bm *ButtonManager) changed(ids ...string) {
+	bm.mu.RLock()
+	onChange := bm.onChange
+	bm.mu.RUnlock()
+
+	if onChange != nil {
+		onChange(ids)
+	}
+}
+
 // load reads buttons from storage
 func (bm *ButtonManager) load() error {
-	var buttons []*models.Button
-	if err := bm.storage.LoadJSON("buttons.json", &buttons); err != nil {
+	buttons, err := bm.readFile(false)
+	if err != nil {
 		return err
 	}
-	for _, btn := range buttons {
-		bm.buttons[btn.ID] = btn
-	}
+	bm.buttons = buttons
+	bm.synced = snapshotRecords(buttons)
 	return nil
 }
 
+// readFile reads the buttons file. With validate set, files with missing or
+// duplicate IDs are rejected.
+func (bm *ButtonManager) readFile(validate bool) (map[string]*models.Button, error) {
+	var list []*models.Button
+	if err := bm.storage.LoadJSON(ButtonsFile, &list); err != nil {
+		return nil, err
+	}
+
+	buttons := make(map[string]*models.Button, len(list))
+	for i, btn := range list {
+		if btn == nil {
+			continue
+		}
+		if validate {
+			if btn.ID == "" {
+				return nil, fmt.Errorf("button %d has no id", i)
+			}
+			if _, dup := buttons[btn.ID]; dup {
+				return nil, fmt.Errorf("duplicate button id: %s", btn.ID)
+			}
+			if btn.Action.Type == "" {
+				return nil, fmt.Errorf("button %s has no action type", btn.ID)
+			}
+		}
+		buttons[btn.ID] = btn
+	}
+	return buttons, nil
+}
+
+// Reload merges changes made to the buttons file outside the app
+func (bm *ButtonManager) Reload() (*ReloadResult, error) {
+	bm.mu.Lock()
+	result, err := bm.reloadLocked()
+	onReload := bm.onReload
+	bm.mu.Unlock()
+
+	if onReload != nil {
+		onReload(result)
+	}
+	return result, err
+}
+
+// reloadLocked merges the buttons file into memory; bm.mu must be held
+func (bm *ButtonManager) reloadLocked() (*ReloadResult, error) {
+	result := &ReloadResult{File: ButtonsFile}
+
+	remote, err := bm.readFile(true)
+	if err != nil {
+		result.Error = err.Error()
+		return result, fmt.Errorf("invalid %s: %w", ButtonsFile, err)
+	}
+
+	merged, changed, conflicts := mergeRecords(bm.synced, bm.buttons, remote)
+	bm.buttons = merged
+	bm.synced = snapshotRecords(remote)
+	result.Changed = changed
+	result.Conflicts = conflicts
+	return result, nil
+}
+
 // save writes buttons to storage
 func (bm *ButtonManager) save() error {
+	// Fold in edits made on disk since the last sync so we don't overwrite them
+	if modified, err := bm.storage.Modified(ButtonsFile); err == nil && modified {
+		result, err := bm.reloadLocked()
+		if err != nil {
+			storageLog.Warn("Overwriting invalid file", "file", ButtonsFile, "error", err)
+		}
+		if bm.onReload != nil {
+			go bm.onReload(result)
+		}
+	}
+
 	buttons := make([]*models.Button, 0, len(bm.buttons))
 	for _, btn := range bm.buttons {
 		buttons = append(buttons, btn)
 	}
-	return bm.storage.SaveJSON("buttons.json", buttons)
+	if err := bm.storage.SaveJSON(ButtonsFile, buttons); err != nil {
+		return err
+	}
+	bm.synced = snapshotRecords(bm.buttons)
+	return nil
 }
 
 // Create creates a new button
 func (bm *ButtonManager) Create(btn *models.Button) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
 	btn.ID = uuid.New().String()
 	btn.CreatedAt = time.Now()
 	btn.UpdatedAt = time.Now()
@@ -57,6 +170,9 @@ func (bm *ButtonManager) Create(btn *models.Button) error {
 
 // Get retrieves a button by ID
 func (bm *ButtonManager) Get(id string) (*models.Button, error) {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
 	btn, ok := bm.buttons[id]
 	if !ok {
 		return nil, fmt.Errorf("button not found: %s", id)
@@ -66,6 +182,9 @@ func (bm *ButtonManager) Get(id string) (*models.Button, error) {
 
 // List returns all buttons
 func (bm *ButtonManager) List() []*models.Button {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
 	buttons := make([]*models.Button, 0, len(bm.buttons))
 	for _, btn := range bm.buttons {
 		buttons = append(buttons, btn)
@@ -75,18 +194,100 @@ func (bm *ButtonManager) List() []*models.Button {
 
 // Update updates an existing button
 func (bm *ButtonManager) Update(btn *models.Button) error {
+	bm.mu.Lock()
 	if _, ok := bm.buttons[btn.ID]; !ok {
+		bm.mu.Unlock()
 		return fmt.Errorf("button not found: %s", btn.ID)
 	}
 	btn.UpdatedAt = time.Now()
 	bm.buttons[btn.ID] = btn
-	return bm.save()
+	err := bm.save()
+	bm.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	bm.changed(btn.ID)
+	return nil
 }
 
 // Delete removes a button
 func (bm *ButtonManager) Delete(id string) error {
+	bm.mu.Lock()
 	delete(bm.buttons, id)
-	return bm.save()
+	err := bm.save()
+	bm.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	bm.changed(id)
+	return nil
+}
+
+// ApplyRename points every button using a renamed scene, input or filter at
+// its new name. It returns the IDs of the updated buttons.
+func (bm *ButtonManager) ApplyRename(rename Rename) ([]string, error) {
+	bm.mu.Lock()
+	var ids []string
+	for id, btn := range bm.buttons {
+		params := renamedParams(btn.Action.Params, rename)
+		if params == nil {
+			continue
+		}
+		// Replace rather than edit the button, others may be reading it
+		updated := *btn
+		updated.Action.Params = params
+		updated.UpdatedAt = time.Now()
+		bm.buttons[id] = &updated
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		bm.mu.Unlock()
+		return nil, nil
+	}
+	err := bm.save()
+	bm.mu.Unlock()
+
+	sort.Strings(ids)
+	if err != nil {
+		return ids, err
+	}
+	bm.changed(ids...)
+	return ids, nil
+}
+
+// renamedParams returns a copy of action params with a rename applied, or nil
+// if they don't use the renamed name
+func renamedParams(params map[string]interface{}, rename Rename) map[string]interface{} {
+	var keys []string
+	switch rename.Kind {
+	case RenameScene:
+		// Scenes can also be sources in other scenes and have filters
+		keys = []string{"scene_name", "source_name"}
+	case RenameInput:
+		keys = []string{"input_name", "source_name"}
+	case RenameFilter:
+		if params["source_name"] != rename.Source {
+			return nil
+		}
+		keys = []string{"filter_name"}
+	}
+
+	var renamed map[string]interface{}
+	for _, key := range keys {
+		if params[key] != rename.OldName {
+			continue
+		}
+		if renamed == nil {
+			renamed = make(map[string]interface{}, len(params))
+			for k, v := range params {
+				renamed[k] = v
+			}
+		}
+		renamed[key] = rename.NewName
+	}
+	return renamed
 }
 
 // Search finds buttons matching a query
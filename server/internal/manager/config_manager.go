@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,11 +12,18 @@ import (
 	"github.com/robomon1/robo-stream/server/internal/storage"
 )
 
+// ConfigsFile is the data file holding the configurations
+const ConfigsFile = "configs.json"
+
 // ConfigManager manages button configurations
 type ConfigManager struct {
 	storage       *storage.Storage
 	buttonManager *ButtonManager
 	configs       map[string]*models.Configuration
+	synced        map[string][]byte // configurations as last synced with disk
+	onReload      func(*ReloadResult)
+	onChange      func(ids []string)
+	mu            sync.RWMutex
 }
 
 // NewConfigManager creates a new ConfigManager
@@ -24,34 +32,166 @@ func NewConfigManager(storage *storage.Storage, buttonManager *ButtonManager) *C
 		storage:       storage,
 		buttonManager: buttonManager,
 		configs:       make(map[string]*models.Configuration),
+		synced:        make(map[string][]byte),
 	}
 	cm.load()
 	return cm
 }
 
+// OnReload registers a callback for changes picked up from disk
+func (cm *ConfigManager) OnReload(fn func(*ReloadResult)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onReload = fn
+}
+
+// OnChange registers a callback for configurations edited through the
+// manager
+func (cm *ConfigManager) OnChange(fn func(ids []string)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onChange = fn
+}
+
+// changed tells the change callback about edited configurations; cm.mu must
+// not be held since the callback usually resolves them
+func (cm *ConfigManager) changed(ids ...string) {
+	cm.mu.RLock()
+	onChange := cm.onChange
+	cm.mu.RUnlock()
+
+	if onChange != nil {
+		onChange(ids)
+	}
+}
+
 // load reads configurations from storage
 func (cm *ConfigManager) load() error {
-	var configs []*models.Configuration
-	if err := cm.storage.LoadJSON("configs.json", &configs); err != nil {
+	configs, err := cm.readFile(false)
+	if err != nil {
 		return err
 	}
-	for _, cfg := range configs {
-		cm.configs[cfg.ID] = cfg
+	cm.configs = configs
+	cm.synced = snapshotRecords(configs)
+	return nil
+}
+
+// readFile reads the configurations file. With validate set, files with
+// duplicate IDs, bad grids or positions, or several defaults are rejected.
+func (cm *ConfigManager) readFile(validate bool) (map[string]*models.Configuration, error) {
+	var list []*models.Configuration
+	if err := cm.storage.LoadJSON(ConfigsFile, &list); err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]*models.Configuration, len(list))
+	defaults := 0
+	for i, cfg := range list {
+		if cfg == nil {
+			continue
+		}
+		if validate {
+			if cfg.ID == "" {
+				return nil, fmt.Errorf("configuration %d has no id", i)
+			}
+			if _, dup := configs[cfg.ID]; dup {
+				return nil, fmt.Errorf("duplicate configuration id: %s", cfg.ID)
+			}
+			if err := validateConfiguration(cfg); err != nil {
+				return nil, fmt.Errorf("configuration %s: %w", cfg.ID, err)
+			}
+		}
+		if cfg.IsDefault {
+			defaults++
+		}
+		if cfg.Buttons == nil {
+			cfg.Buttons = make(map[string]string)
+		}
+		configs[cfg.ID] = cfg
+	}
+	if validate && defaults > 1 {
+		return nil, fmt.Errorf("%d configurations are marked as default", defaults)
+	}
+	return configs, nil
+}
+
+// validateConfiguration checks the grid and button positions of a configuration
+func validateConfiguration(cfg *models.Configuration) error {
+	if cfg.Grid.Rows < 1 || cfg.Grid.Cols < 1 {
+		return fmt.Errorf("invalid grid %dx%d", cfg.Grid.Rows, cfg.Grid.Cols)
+	}
+	for position := range cfg.Buttons {
+		row, col, ok := ParsePosition(position)
+		if !ok {
+			return fmt.Errorf("invalid button position: %s", position)
+		}
+		if row >= cfg.Grid.Rows || col >= cfg.Grid.Cols {
+			return fmt.Errorf("button position %s is outside the %dx%d grid", position, cfg.Grid.Rows, cfg.Grid.Cols)
+		}
 	}
 	return nil
 }
 
+// Reload merges changes made to the configurations file outside the app
+func (cm *ConfigManager) Reload() (*ReloadResult, error) {
+	cm.mu.Lock()
+	result, err := cm.reloadLocked()
+	onReload := cm.onReload
+	cm.mu.Unlock()
+
+	if onReload != nil {
+		onReload(result)
+	}
+	return result, err
+}
+
+// reloadLocked merges the configurations file into memory; cm.mu must be held
+func (cm *ConfigManager) reloadLocked() (*ReloadResult, error) {
+	result := &ReloadResult{File: ConfigsFile}
+
+	remote, err := cm.readFile(true)
+	if err != nil {
+		result.Error = err.Error()
+		return result, fmt.Errorf("invalid %s: %w", ConfigsFile, err)
+	}
+
+	merged, changed, conflicts := mergeRecords(cm.synced, cm.configs, remote)
+	cm.configs = merged
+	cm.synced = snapshotRecords(remote)
+	result.Changed = changed
+	result.Conflicts = conflicts
+	return result, nil
+}
+
 // save writes configurations to storage
 func (cm *ConfigManager) save() error {
+	// Fold in edits made on disk since the last sync so we don't overwrite them
+	if modified, err := cm.storage.Modified(ConfigsFile); err == nil && modified {
+		result, err := cm.reloadLocked()
+		if err != nil {
+			storageLog.Warn("Overwriting invalid file", "file", ConfigsFile, "error", err)
+		}
+		if cm.onReload != nil {
+			go cm.onReload(result)
+		}
+	}
+
 	configs := make([]*models.Configuration, 0, len(cm.configs))
 	for _, cfg := range cm.configs {
 		configs = append(configs, cfg)
 	}
-	return cm.storage.SaveJSON("configs.json", configs)
+	if err := cm.storage.SaveJSON(ConfigsFile, configs); err != nil {
+		return err
+	}
+	cm.synced = snapshotRecords(cm.configs)
+	return nil
 }
 
 // Create creates a new configuration
 func (cm *ConfigManager) Create(config *models.Configuration) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	config.ID = uuid.New().String()
 	config.CreatedAt = time.Now()
 	config.UpdatedAt = time.Now()
@@ -64,6 +204,13 @@ func (cm *ConfigManager) Create(config *models.Configuration) error {
 
 // Get retrieves a configuration by ID
 func (cm *ConfigManager) Get(id string) (*models.Configuration, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.get(id)
+}
+
+// get retrieves a configuration by ID; cm.mu must be held
+func (cm *ConfigManager) get(id string) (*models.Configuration, error) {
 	cfg, ok := cm.configs[id]
 	if !ok {
 		return nil, fmt.Errorf("configuration not found: %s", id)
@@ -73,6 +220,9 @@ func (cm *ConfigManager) Get(id string) (*models.Configuration, error) {
 
 // List returns all configurations
 func (cm *ConfigManager) List() []*models.Configuration {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
 	configs := make([]*models.Configuration, 0, len(cm.configs))
 	for _, cfg := range cm.configs {
 		configs = append(configs, cfg)
@@ -82,22 +232,37 @@ func (cm *ConfigManager) List() []*models.Configuration {
 
 // Update updates an existing configuration
 func (cm *ConfigManager) Update(config *models.Configuration) error {
+	cm.mu.Lock()
 	if _, ok := cm.configs[config.ID]; !ok {
+		cm.mu.Unlock()
 		return fmt.Errorf("configuration not found: %s", config.ID)
 	}
 	config.UpdatedAt = time.Now()
 	cm.configs[config.ID] = config
-	return cm.save()
+	err := cm.save()
+	cm.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	cm.changed(config.ID)
+	return nil
 }
 
 // Delete removes a configuration
 func (cm *ConfigManager) Delete(id string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	delete(cm.configs, id)
 	return cm.save()
 }
 
 // SetDefault sets a configuration as the default
 func (cm *ConfigManager) SetDefault(id string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	// Clear default flag from all configs
 	for _, cfg := range cm.configs {
 		cfg.IsDefault = false
@@ -115,6 +280,9 @@ func (cm *ConfigManager) SetDefault(id string) error {
 
 // GetDefault returns the default configuration
 func (cm *ConfigManager) GetDefault() (*models.Configuration, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
 	for _, cfg := range cm.configs {
 		if cfg.IsDefault {
 			return cfg, nil
@@ -123,9 +291,35 @@ func (cm *ConfigManager) GetDefault() (*models.Configuration, error) {
 	return nil, fmt.Errorf("no default configuration set")
 }
 
+// ConfigsUsingButtons returns the IDs of configurations that place any of
+// the given buttons
+func (cm *ConfigManager) ConfigsUsingButtons(buttonIDs []string) []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(buttonIDs))
+	for _, id := range buttonIDs {
+		wanted[id] = true
+	}
+
+	ids := make([]string, 0)
+	for _, cfg := range cm.configs {
+		for _, buttonID := range cfg.Buttons {
+			if wanted[buttonID] {
+				ids = append(ids, cfg.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
 // Resolve converts a configuration to a resolved configuration with full button details
 func (cm *ConfigManager) Resolve(id string) (*models.ResolvedConfiguration, error) {
-	cfg, err := cm.Get(id)
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	cfg, err := cm.get(id)
 	if err != nil {
 		return nil, err
 	}
@@ -145,12 +339,10 @@ func (cm *ConfigManager) Resolve(id string) (*models.ResolvedConfiguration, erro
 		}
 
 		// Parse position (btn-0-0 -> row=0, col=0)
-		parts := strings.Split(position, "-")
-		if len(parts) != 3 {
+		row, col, ok := ParsePosition(position)
+		if !ok {
 			continue
 		}
-		row, _ := strconv.Atoi(parts[1])
-		col, _ := strconv.Atoi(parts[2])
 
 		resolvedBtn := models.ResolvedButton{
 			ID:     position,
@@ -167,3 +359,20 @@ func (cm *ConfigManager) Resolve(id string) (*models.ResolvedConfiguration, erro
 
 	return resolved, nil
 }
+
+// ParsePosition splits a grid position like btn-0-1 into row and column
+func ParsePosition(position string) (int, int, bool) {
+	parts := strings.Split(position, "-")
+	if len(parts) != 3 || parts[0] != "btn" {
+		return 0, 0, false
+	}
+	row, err := strconv.Atoi(parts[1])
+	if err != nil || row < 0 {
+		return 0, 0, false
+	}
+	col, err := strconv.Atoi(parts[2])
+	if err != nil || col < 0 {
+		return 0, 0, false
+	}
+	return row, col, true
+}
@@ -0,0 +1,81 @@
+package manager
+
+import "fmt"
+
+// DataIssue is a problem found in the stored data, like a reference to
+// something that no longer exists
+type DataIssue struct {
+	File    string `json:"file"`
+	ID      string `json:"id,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// CheckConsistency looks for broken references between buttons,
+// configurations, roles, sessions, paired devices and assignment rules
+func CheckConsistency(bm *ButtonManager, cm *ConfigManager, sm *SessionManager, am *AssignmentManager, rm *RoleManager) []DataIssue {
+	var issues []DataIssue
+	add := func(file, id, format string, args ...interface{}) {
+		issues = append(issues, DataIssue{File: file, ID: id, Problem: fmt.Sprintf(format, args...)})
+	}
+	roleExists := func(name string) bool {
+		_, err := rm.Get(name)
+		return err == nil
+	}
+	configExists := func(id string) bool {
+		_, err := cm.Get(id)
+		return err == nil
+	}
+
+	for _, button := range bm.List() {
+		if !KnownAction(button.Action.Type) {
+			add(ButtonsFile, button.ID, "unknown action type %q", button.Action.Type)
+		}
+	}
+
+	defaults := 0
+	for _, cfg := range cm.List() {
+		if cfg.IsDefault {
+			defaults++
+		}
+		if cfg.Role != "" && !roleExists(cfg.Role) {
+			add(ConfigsFile, cfg.ID, "role %q does not exist", cfg.Role)
+		}
+		for position, buttonID := range cfg.Buttons {
+			row, col, ok := ParsePosition(position)
+			switch {
+			case !ok:
+				add(ConfigsFile, cfg.ID, "invalid button position %q", position)
+			case row >= cfg.Grid.Rows || col >= cfg.Grid.Cols:
+				add(ConfigsFile, cfg.ID, "button position %q is outside the %dx%d grid", position, cfg.Grid.Rows, cfg.Grid.Cols)
+			}
+			if _, err := bm.Get(buttonID); err != nil {
+				add(ConfigsFile, cfg.ID, "button %s at %s does not exist", buttonID, position)
+			}
+		}
+	}
+	if defaults != 1 {
+		add(ConfigsFile, "", "%d configurations are marked as default, expected 1", defaults)
+	}
+
+	for _, session := range sm.List() {
+		if session.ConfigID != "" && !configExists(session.ConfigID) {
+			add(SessionsFile, session.SessionID, "configuration %s does not exist", session.ConfigID)
+		}
+		if session.Role != "" && !roleExists(session.Role) {
+			add(SessionsFile, session.SessionID, "role %q does not exist", session.Role)
+		}
+		if session.DeviceID != "" {
+			if _, err := sm.Device(session.DeviceID); err != nil {
+				add(SessionsFile, session.SessionID, "paired device %s does not exist", session.DeviceID)
+			}
+		}
+	}
+
+	for i, rule := range am.List() {
+		if !configExists(rule.ConfigID) {
+			add(AssignmentsFile, fmt.Sprintf("rule %d", i+1), "configuration %s does not exist", rule.ConfigID)
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Default time limits for talking to OBS; a caller's earlier deadline wins
+const (
+	defaultActionTimeout = 5 * time.Second
+	statusTimeout        = 3 * time.Second
+)
+
+// actionTimeouts are the limits for action types OBS is slow to answer,
+// e.g. while it sets up an output
+var actionTimeouts = map[string]time.Duration{
+	"start_stream":  15 * time.Second,
+	"stop_stream":   15 * time.Second,
+	"toggle_stream": 15 * time.Second,
+	"start_record":  10 * time.Second,
+	"stop_record":   10 * time.Second,
+	"toggle_record": 10 * time.Second,
+}
+
+// actionTimeout returns how long an action type may wait on OBS
+func actionTimeout(actionType string) time.Duration {
+	if timeout, ok := actionTimeouts[actionType]; ok {
+		return timeout
+	}
+	return defaultActionTimeout
+}
+
+// ActionTimeouts returns the default time limit for actions and the limits
+// of the action types that differ, so clients can wait as long
+func ActionTimeouts() (time.Duration, map[string]time.Duration) {
+	byType := make(map[string]time.Duration, len(actionTimeouts))
+	for actionType, timeout := range actionTimeouts {
+		byType[actionType] = timeout
+	}
+	return defaultActionTimeout, byType
+}
+
+// withContext runs fn, which talks to OBS, until ctx is done. goobs
+// requests can't be cancelled, so fn is left to finish in the background;
+// it should check ctx before each further request.
+func withContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return contextError(ctx.Err())
+	}
+}
+
+// contextError returns ErrOBSTimeout for a deadline that passed, and
+// cancellation as it is
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrOBSTimeout
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWithContext runs a stub two-step action whose first request answers
+// late, the way toggles talk to OBS, and checks that an action given up on
+// never reaches its second step
+func TestWithContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		cancel     bool
+		firstStep  time.Duration
+		wantErr    error
+		wantStart  bool
+		wantSecond bool
+	}{
+		{name: "in time", timeout: time.Second, wantStart: true, wantSecond: true},
+		{name: "deadline", timeout: 20 * time.Millisecond, firstStep: 200 * time.Millisecond, wantErr: ErrOBSTimeout, wantStart: true},
+		{name: "canceled", timeout: time.Second, cancel: true, firstStep: 200 * time.Millisecond, wantErr: context.Canceled, wantStart: true},
+		{name: "done before start", timeout: time.Second, cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if tt.cancel {
+				if tt.firstStep == 0 {
+					cancel()
+				} else {
+					time.AfterFunc(20*time.Millisecond, cancel)
+				}
+			}
+
+			var started, second atomic.Bool
+			finished := make(chan struct{})
+			begin := time.Now()
+			err := withContext(ctx, func() error {
+				defer close(finished)
+				started.Store(true)
+				time.Sleep(tt.firstStep)
+				if err := ctx.Err(); err != nil {
+					return contextError(err)
+				}
+				second.Store(true)
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.firstStep > 0 && time.Since(begin) >= tt.firstStep {
+				t.Errorf("returned after %v, not when ctx was done", time.Since(begin))
+			}
+
+			// Let a stub left running in the background finish
+			if tt.wantStart {
+				<-finished
+			}
+			if started.Load() != tt.wantStart {
+				t.Errorf("started = %v, want %v", started.Load(), tt.wantStart)
+			}
+			if second.Load() != tt.wantSecond {
+				t.Errorf("second step ran = %v, want %v", second.Load(), tt.wantSecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// Ranges accepted for number parameters
+const (
+	minVolume             = 0
+	maxVolume             = 100   // percent
+	minTransitionDuration = 50    // ms, as limited by OBS
+	maxTransitionDuration = 20000 // ms
+)
+
+// ButtonCheck is the dry-run result for the button at one position
+type ButtonCheck struct {
+	Position   string   `json:"position"`
+	Row        int      `json:"row"`
+	Col        int      `json:"col"`
+	ButtonID   string   `json:"button_id"`
+	ButtonName string   `json:"button_name,omitempty"`
+	ActionType string   `json:"action_type,omitempty"`
+	OK         bool     `json:"ok"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+// ConfigurationReport is the dry-run result for every button of a
+// configuration
+type ConfigurationReport struct {
+	ConfigID   string        `json:"config_id"`
+	ConfigName string        `json:"config_name"`
+	OK         bool          `json:"ok"`
+	Failed     int           `json:"failed"`
+	Buttons    []ButtonCheck `json:"buttons"`
+	CheckedAt  time.Time     `json:"checked_at"`
+}
+
+// DryRun checks actions against what exists in OBS without running them.
+// Only read requests are sent; scene items and filters come from the
+// inventory cache.
+type DryRun struct {
+	om          *OBSManager
+	scenes      map[string]bool
+	inputs      map[string]bool
+	transitions map[string]bool
+}
+
+// ButtonIssue is a library button that wouldn't work in OBS
+type ButtonIssue struct {
+	ButtonID   string   `json:"button_id"`
+	ButtonName string   `json:"button_name"`
+	Problems   []string `json:"problems"`
+}
+
+// DryRun reads the scenes, inputs and transitions from OBS to check actions
+// against
+func (om *OBSManager) DryRun() (*DryRun, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+
+	d := &DryRun{
+		om:          om,
+		scenes:      make(map[string]bool),
+		inputs:      make(map[string]bool),
+		transitions: make(map[string]bool),
+	}
+
+	sceneList, err := client.Scenes.GetSceneList()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	for _, scene := range sceneList.Scenes {
+		d.scenes[scene.SceneName] = true
+	}
+
+	inputList, err := client.Inputs.GetInputList()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	for _, input := range inputList.Inputs {
+		d.inputs[input.InputName] = true
+	}
+
+	transitionList, err := client.Transitions.GetSceneTransitionList()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	for _, transition := range transitionList.Transitions {
+		d.transitions[transition.TransitionName] = true
+	}
+
+	return d, nil
+}
+
+// Check returns what would keep an action from working, or nothing if it
+// would run
+func (d *DryRun) Check(action models.ButtonAction) []string {
+	var problems []string
+	fail := func(err error) []string {
+		return append(problems, err.Error())
+	}
+
+	switch action.Type {
+	case "switch_scene", "set_preview_scene":
+		scene, err := stringParam(action.Params, "scene_name")
+		if err != nil {
+			return fail(err)
+		}
+		if !d.scenes[scene] {
+			problems = append(problems, fmt.Sprintf("scene %q does not exist", scene))
+		}
+
+	case "toggle_source_visibility", "show_source", "hide_source":
+		scene, err := stringParam(action.Params, "scene_name")
+		if err != nil {
+			return fail(err)
+		}
+		source, _ := action.Params["source_name"].(string)
+		itemID, err := numberParam(action.Params, "scene_item_id")
+		if err != nil && action.Params["scene_item_id"] != nil {
+			return fail(err)
+		}
+		if source == "" && itemID == 0 {
+			return fail(missingParam("source_name"))
+		}
+		if !d.scenes[scene] {
+			return append(problems, fmt.Sprintf("scene %q does not exist", scene))
+		}
+		// Finds sources in groups and nested scenes too
+		if _, _, err := d.om.resolveSceneItem(scene, source, int(itemID)); err != nil {
+			var paramErr *ParamError
+			if errors.As(err, &paramErr) {
+				return append(problems, paramErr.Reason)
+			}
+			return fail(err)
+		}
+
+	case "toggle_input_mute", "mute_input", "unmute_input":
+		input, err := stringParam(action.Params, "input_name")
+		if err != nil {
+			return fail(err)
+		}
+		if !d.inputs[input] {
+			problems = append(problems, fmt.Sprintf("input %q does not exist", input))
+		}
+
+	case "set_input_volume":
+		input, err := stringParam(action.Params, "input_name")
+		if err != nil {
+			return fail(err)
+		}
+		if !d.inputs[input] {
+			problems = append(problems, fmt.Sprintf("input %q does not exist", input))
+		}
+		volume, err := numberParam(action.Params, "volume")
+		if err != nil {
+			return fail(err)
+		}
+		if volume < minVolume || volume > maxVolume {
+			problems = append(problems, fmt.Sprintf("volume %g is outside %d to %d", volume, minVolume, maxVolume))
+		}
+
+	case "toggle_source_filter", "enable_source_filter", "disable_source_filter":
+		source, err := stringParam(action.Params, "source_name")
+		if err != nil {
+			return fail(err)
+		}
+		filter, err := stringParam(action.Params, "filter_name")
+		if err != nil {
+			return fail(err)
+		}
+		// Filters can be on inputs and on scenes
+		if !d.inputs[source] && !d.scenes[source] {
+			return append(problems, fmt.Sprintf("source %q does not exist", source))
+		}
+		names, err := d.filterNames(source)
+		if err != nil {
+			return fail(err)
+		}
+		if !names[filter] {
+			problems = append(problems, fmt.Sprintf("filter %q is not on source %q", filter, source))
+		}
+
+	case "set_current_transition":
+		transition, err := stringParam(action.Params, "transition_name")
+		if err != nil {
+			return fail(err)
+		}
+		if !d.transitions[transition] {
+			problems = append(problems, fmt.Sprintf("transition %q does not exist", transition))
+		}
+
+	case "set_transition_duration":
+		duration, err := numberParam(action.Params, "duration")
+		if err != nil {
+			return fail(err)
+		}
+		if duration < minTransitionDuration || duration > maxTransitionDuration {
+			problems = append(problems, fmt.Sprintf("duration %gms is outside %d to %dms", duration, minTransitionDuration, maxTransitionDuration))
+		}
+
+	case "play_pause_media", "restart_media", "stop_media", "next_media", "previous_media":
+		problems = append(problems, fmt.Sprintf("%s is not supported by this server", action.Type))
+
+	default:
+		if !KnownAction(action.Type) {
+			problems = append(problems, fmt.Sprintf("unknown action type %q", action.Type))
+		}
+	}
+	return problems
+}
+
+// CheckButtons returns the buttons whose actions wouldn't work, sorted by
+// name
+func (d *DryRun) CheckButtons(buttons []*models.Button) []ButtonIssue {
+	issues := []ButtonIssue{}
+	for _, btn := range buttons {
+		if problems := d.Check(btn.Action); len(problems) > 0 {
+			issues = append(issues, ButtonIssue{ButtonID: btn.ID, ButtonName: btn.Name, Problems: problems})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].ButtonName < issues[j].ButtonName })
+	return issues
+}
+
+// filterNames returns the names of the filters on a source
+func (d *DryRun) filterNames(source string) (map[string]bool, error) {
+	filters, err := d.om.SourceFilters(source)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(filters))
+	for _, filter := range filters {
+		names[filter.Name] = true
+	}
+	return names, nil
+}
+
+// stringParam reads a text parameter that must be set
+func stringParam(params map[string]interface{}, name string) (string, error) {
+	value, ok := params[name].(string)
+	if !ok || value == "" {
+		return "", missingParam(name)
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrOBSNotConnected is returned while there's no connection to OBS
+	ErrOBSNotConnected = errors.New("not connected to OBS")
+
+	// ErrUnknownAction is returned for action types the server doesn't know
+	ErrUnknownAction = errors.New("unknown action type")
+
+	// ErrUnsupportedAction is returned for known actions this build can't run
+	ErrUnsupportedAction = errors.New("unsupported action")
+
+	// errConnectionClosed is recorded when OBS closes the connection
+	errConnectionClosed = errors.New("OBS closed the connection")
+
+	// ErrOBSTimeout is returned when OBS doesn't answer within the time
+	// allowed
+	ErrOBSTimeout = errors.New("OBS did not answer in time")
+)
+
+// ParamError is an action parameter that is missing or can't be used
+type ParamError struct {
+	Param  string
+	Reason string // empty if the parameter is missing
+}
+
+func (e *ParamError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("missing %s parameter", e.Param)
+	}
+	return fmt.Sprintf("invalid %s parameter: %s", e.Param, e.Reason)
+}
+
+// missingParam returns the error for a missing action parameter
+func missingParam(param string) error {
+	return &ParamError{Param: param}
+}
+
+// invalidParam returns the error for an action parameter that can't be used
+func invalidParam(param, reason string) error {
+	return &ParamError{Param: param, Reason: reason}
+}
+
+// OBSRequestError is a request OBS failed or didn't answer
+type OBSRequestError struct {
+	// Status is the OBS WebSocket request status code, or 0 if OBS didn't
+	// answer
+	Status int
+	Err    error
+}
+
+func (e *OBSRequestError) Error() string { return e.Err.Error() }
+func (e *OBSRequestError) Unwrap() error { return e.Err }
+
+// obsStatusPattern finds the request status code in goobs errors, which read
+// "request <Name>: <status> (<code>)[: <comment>]"
+var obsStatusPattern = regexp.MustCompile(`^request \w+: .*?\((\d+)\)`)
+
+// obsTimeoutMessage is how goobs reports a request OBS didn't answer
+const obsTimeoutMessage = "timeout waiting for response"
+
+// obsError classifies an error from talking to OBS, leaving our own errors
+// and cancellation as they are
+func obsError(err error) error {
+	var paramErr *ParamError
+	var requestErr *OBSRequestError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &paramErr), errors.As(err, &requestErr),
+		errors.Is(err, ErrOBSNotConnected), errors.Is(err, ErrUnknownAction), errors.Is(err, ErrUnsupportedAction),
+		errors.Is(err, ErrOBSTimeout), errors.Is(err, context.Canceled):
+		return err
+	case strings.Contains(err.Error(), obsTimeoutMessage):
+		return fmt.Errorf("%w: %v", ErrOBSTimeout, err)
+	}
+
+	requestErr = &OBSRequestError{Err: err}
+	if m := obsStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		requestErr.Status, _ = strconv.Atoi(m[1])
+	}
+	return requestErr
+}
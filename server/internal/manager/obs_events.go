@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/events"
+)
+
+// Kinds of renames in OBS
+const (
+	RenameScene  = "scene"
+	RenameInput  = "input"
+	RenameFilter = "filter"
+)
+
+// Rename is a scene, input or filter renamed in OBS
+type Rename struct {
+	Kind    string `json:"kind"`
+	Source  string `json:"source,omitempty"` // the source a renamed filter is on
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+}
+
+// OnRename registers a callback for scenes, inputs and filters renamed in
+// OBS
+func (om *OBSManager) OnRename(fn func(Rename)) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.onRename = fn
+}
+
+// OnConnect registers a callback for every successful connect to OBS
+func (om *OBSManager) OnConnect(fn func()) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.onConnect = fn
+}
+
+// listen handles events from OBS until the client disconnects, then marks
+// the connection down if OBS went away on its own
+func (om *OBSManager) listen(client *goobs.Client) {
+	defer om.connectionLost(client)
+
+	client.Listen(func(event any) {
+		om.inventory.handleEvent(event)
+		om.updateStatus(event)
+
+		var rename Rename
+		switch e := event.(type) {
+		case *events.SceneNameChanged:
+			rename = Rename{Kind: RenameScene, OldName: e.OldSceneName, NewName: e.SceneName}
+		case *events.InputNameChanged:
+			rename = Rename{Kind: RenameInput, OldName: e.OldInputName, NewName: e.InputName}
+		case *events.SourceFilterNameChanged:
+			rename = Rename{Kind: RenameFilter, Source: e.SourceName, OldName: e.OldFilterName, NewName: e.FilterName}
+		default:
+			return
+		}
+
+		obsLog.Info("Renamed in OBS", "kind", rename.Kind, "old", rename.OldName, "new", rename.NewName)
+		om.mu.RLock()
+		onRename := om.onRename
+		om.mu.RUnlock()
+		if onRename != nil {
+			onRename(rename)
+		}
+	})
+}
+
+// connectionLost forgets a client whose connection closed without
+// Disconnect, e.g. because OBS quit, so status and metrics stop reporting it
+// as connected
+func (om *OBSManager) connectionLost(client *goobs.Client) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	if om.client != client {
+		return
+	}
+	om.client = nil
+	om.recordConnection(ConnectionDisconnected, om.url, errConnectionClosed)
+	om.inventory.invalidate()
+	om.resetStatus()
+	obsLog.Warn("Lost connection to OBS", "url", om.url)
+}
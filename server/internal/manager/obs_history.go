@@ -0,0 +1,75 @@
+package manager
+
+import "time"
+
+// maxConnectionEvents is how many connection events are kept
+const maxConnectionEvents = 50
+
+// Connection event types
+const (
+	ConnectionConnected    = "connected"
+	ConnectionFailed       = "failed"
+	ConnectionDisconnected = "disconnected"
+)
+
+// ConnectionEvent is a change of the OBS connection, for diagnostics
+type ConnectionEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	URL   string    `json:"url"`
+	Error string    `json:"error,omitempty"`
+}
+
+// OBSVersion describes the OBS instance the server is connected to
+type OBSVersion struct {
+	OBSVersion          string   `json:"obs_version"`
+	OBSWebSocketVersion string   `json:"obs_websocket_version"`
+	RPCVersion          int      `json:"rpc_version"`
+	Platform            string   `json:"platform"`
+	PlatformDescription string   `json:"platform_description"`
+	AvailableRequests   []string `json:"available_requests"`
+}
+
+// recordConnection adds a connection event. Callers hold om.mu.
+func (om *OBSManager) recordConnection(event, url string, err error) {
+	entry := ConnectionEvent{Time: time.Now(), Event: event, URL: url}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	om.history = append(om.history, entry)
+	if len(om.history) > maxConnectionEvents {
+		om.history = om.history[len(om.history)-maxConnectionEvents:]
+	}
+}
+
+// ConnectionHistory returns the latest connects, failed attempts and
+// disconnects, oldest first
+func (om *OBSManager) ConnectionHistory() []ConnectionEvent {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	return append([]ConnectionEvent(nil), om.history...)
+}
+
+// Version returns the version of OBS and its WebSocket server
+func (om *OBSManager) Version() (*OBSVersion, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+
+	resp, err := client.General.GetVersion()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	return &OBSVersion{
+		OBSVersion:          resp.ObsVersion,
+		OBSWebSocketVersion: resp.ObsWebSocketVersion,
+		RPCVersion:          int(resp.RpcVersion),
+		Platform:            resp.Platform,
+		PlatformDescription: resp.PlatformDescription,
+		AvailableRequests:   resp.AvailableRequests,
+	}, nil
+}
@@ -0,0 +1,362 @@
+package manager
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/andreykaipov/goobs/api/events"
+	"github.com/andreykaipov/goobs/api/requests/filters"
+	"github.com/andreykaipov/goobs/api/requests/inputs"
+	"github.com/andreykaipov/goobs/api/requests/sceneitems"
+	"github.com/andreykaipov/goobs/api/typedefs"
+)
+
+// Inventory cache keys; scene items, filters and mute states are per scene,
+// source or input
+const (
+	inventorySceneItems    = "items:"
+	inventoryFilters       = "filters:"
+	inventoryMutes         = "mute:"
+	inventoryTransitions   = "transitions"
+	inventoryInputKinds    = "input_kinds"
+	inventorySpecialInputs = "special_inputs"
+)
+
+// SceneItem is a source in a scene. Sources in a group are listed after the
+// group with Group set.
+type SceneItem struct {
+	ID         int    `json:"id"`
+	SourceName string `json:"source_name"`
+	SourceType string `json:"source_type"`
+	InputKind  string `json:"input_kind,omitempty"`
+	Enabled    bool   `json:"enabled"`
+	Index      int    `json:"index"`
+	IsGroup    bool   `json:"is_group"`
+	Group      string `json:"group,omitempty"`
+}
+
+// SourceFilter is a filter on a scene or input
+type SourceFilter struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+	Index   int    `json:"index"`
+}
+
+// Transition is a scene transition
+type Transition struct {
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Fixed        bool   `json:"fixed"` // has no adjustable duration
+	Configurable bool   `json:"configurable"`
+}
+
+// Transitions are the scene transitions and the one currently used
+type Transitions struct {
+	Current     string       `json:"current"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// SpecialInputs are the global audio inputs, empty where not set up
+type SpecialInputs struct {
+	Desktop1 string `json:"desktop1"`
+	Desktop2 string `json:"desktop2"`
+	Mic1     string `json:"mic1"`
+	Mic2     string `json:"mic2"`
+	Mic3     string `json:"mic3"`
+	Mic4     string `json:"mic4"`
+}
+
+// inventory caches what editors pick from in OBS until OBS reports a
+// change to it
+type inventory struct {
+	mu      sync.Mutex
+	entries map[string]interface{}
+	// generation counts invalidations, so a lookup that raced with one
+	// isn't stored
+	generation int
+}
+
+// get returns a cached entry and the generation to store a fresh one with
+func (inv *inventory) get(key string) (interface{}, int, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	value, ok := inv.entries[key]
+	return value, inv.generation, ok
+}
+
+// put caches an entry unless the cache was invalidated since generation
+func (inv *inventory) put(generation int, key string, value interface{}) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if generation != inv.generation {
+		return
+	}
+	if inv.entries == nil {
+		inv.entries = make(map[string]interface{})
+	}
+	inv.entries[key] = value
+}
+
+// set stores what an event reported, which is newer than any lookup still
+// running
+func (inv *inventory) set(key string, value interface{}) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.generation++
+	if inv.entries == nil {
+		inv.entries = make(map[string]interface{})
+	}
+	inv.entries[key] = value
+}
+
+// invalidate drops the entries whose keys start with one of the prefixes,
+// or every entry if none are given
+func (inv *inventory) invalidate(prefixes ...string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.generation++
+	if len(prefixes) == 0 {
+		inv.entries = nil
+		return
+	}
+	for key := range inv.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(inv.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// handleEvent drops what an event from OBS changed
+func (inv *inventory) handleEvent(event any) {
+	switch e := event.(type) {
+	case *events.SceneItemCreated, *events.SceneItemRemoved,
+		*events.SceneItemListReindexed, *events.SceneItemEnableStateChanged:
+		// Groups are listed in the scenes they're in, so drop every scene
+		inv.invalidate(inventorySceneItems)
+	case *events.SceneCreated, *events.SceneRemoved, *events.SceneNameChanged:
+		inv.invalidate(inventorySceneItems, inventoryFilters)
+	case *events.InputCreated, *events.InputRemoved, *events.InputNameChanged:
+		inv.invalidate(inventorySceneItems, inventoryFilters, inventoryMutes, inventorySpecialInputs)
+	case *events.InputMuteStateChanged:
+		inv.set(inventoryMutes+e.InputName, e.InputMuted)
+	case *events.SourceFilterCreated:
+		inv.invalidate(inventoryFilters + e.SourceName)
+	case *events.SourceFilterRemoved:
+		inv.invalidate(inventoryFilters + e.SourceName)
+	case *events.SourceFilterNameChanged:
+		inv.invalidate(inventoryFilters + e.SourceName)
+	case *events.SourceFilterListReindexed:
+		inv.invalidate(inventoryFilters + e.SourceName)
+	case *events.SourceFilterEnableStateChanged:
+		inv.invalidate(inventoryFilters + e.SourceName)
+	case *events.CurrentSceneTransitionChanged:
+		inv.invalidate(inventoryTransitions)
+	case *events.CurrentSceneCollectionChanged, *events.CurrentProfileChanged:
+		inv.invalidate()
+	}
+}
+
+// SceneItems returns the sources in a scene, including those in its groups
+func (om *OBSManager) SceneItems(scene string) ([]SceneItem, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+	key := inventorySceneItems + scene
+	cached, generation, ok := om.inventory.get(key)
+	if ok {
+		return cached.([]SceneItem), nil
+	}
+
+	resp, err := client.SceneItems.GetSceneItemList(&sceneitems.GetSceneItemListParams{SceneName: &scene})
+	if err != nil {
+		return nil, obsError(err)
+	}
+	items := make([]SceneItem, 0, len(resp.SceneItems))
+	for _, item := range resp.SceneItems {
+		items = append(items, newSceneItem(item, ""))
+		if !item.IsGroup {
+			continue
+		}
+		group := item.SourceName
+		groupResp, err := client.SceneItems.GetGroupSceneItemList(&sceneitems.GetGroupSceneItemListParams{SceneName: &group})
+		if err != nil {
+			return nil, obsError(err)
+		}
+		for _, child := range groupResp.SceneItems {
+			items = append(items, newSceneItem(child, group))
+		}
+	}
+
+	om.inventory.put(generation, key, items)
+	return items, nil
+}
+
+// newSceneItem converts a scene item from OBS
+func newSceneItem(item *typedefs.SceneItem, group string) SceneItem {
+	return SceneItem{
+		ID:         item.SceneItemID,
+		SourceName: item.SourceName,
+		SourceType: item.SourceType,
+		InputKind:  item.InputKind,
+		Enabled:    item.SceneItemEnabled,
+		Index:      item.SceneItemIndex,
+		IsGroup:    item.IsGroup,
+		Group:      group,
+	}
+}
+
+// SourceFilters returns the filters on a scene or input
+func (om *OBSManager) SourceFilters(source string) ([]SourceFilter, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+	key := inventoryFilters + source
+	cached, generation, ok := om.inventory.get(key)
+	if ok {
+		return cached.([]SourceFilter), nil
+	}
+
+	resp, err := client.Filters.GetSourceFilterList(&filters.GetSourceFilterListParams{SourceName: &source})
+	if err != nil {
+		return nil, obsError(err)
+	}
+	list := make([]SourceFilter, 0, len(resp.Filters))
+	for _, filter := range resp.Filters {
+		list = append(list, SourceFilter{
+			Name:    filter.FilterName,
+			Kind:    filter.FilterKind,
+			Enabled: filter.FilterEnabled,
+			Index:   filter.FilterIndex,
+		})
+	}
+
+	om.inventory.put(generation, key, list)
+	return list, nil
+}
+
+// InputMuted returns whether an input is muted
+func (om *OBSManager) InputMuted(input string) (bool, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return false, ErrOBSNotConnected
+	}
+	key := inventoryMutes + input
+	cached, generation, ok := om.inventory.get(key)
+	if ok {
+		return cached.(bool), nil
+	}
+
+	resp, err := client.Inputs.GetInputMute(&inputs.GetInputMuteParams{InputName: &input})
+	if err != nil {
+		return false, obsError(err)
+	}
+
+	om.inventory.put(generation, key, resp.InputMuted)
+	return resp.InputMuted, nil
+}
+
+// Transitions returns the scene transitions and the current one
+func (om *OBSManager) Transitions() (*Transitions, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+	cached, generation, ok := om.inventory.get(inventoryTransitions)
+	if ok {
+		return cached.(*Transitions), nil
+	}
+
+	resp, err := client.Transitions.GetSceneTransitionList()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	result := &Transitions{
+		Current:     resp.CurrentSceneTransitionName,
+		Transitions: make([]Transition, 0, len(resp.Transitions)),
+	}
+	for _, transition := range resp.Transitions {
+		result.Transitions = append(result.Transitions, Transition{
+			Name:         transition.TransitionName,
+			Kind:         transition.TransitionKind,
+			Fixed:        transition.TransitionFixed,
+			Configurable: transition.TransitionConfigurable,
+		})
+	}
+
+	om.inventory.put(generation, inventoryTransitions, result)
+	return result, nil
+}
+
+// InputKinds returns the kinds of inputs OBS can create, without versions
+func (om *OBSManager) InputKinds() ([]string, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+	cached, generation, ok := om.inventory.get(inventoryInputKinds)
+	if ok {
+		return cached.([]string), nil
+	}
+
+	unversioned := true
+	resp, err := client.Inputs.GetInputKindList(&inputs.GetInputKindListParams{Unversioned: &unversioned})
+	if err != nil {
+		return nil, obsError(err)
+	}
+	kinds := append([]string{}, resp.InputKinds...)
+
+	om.inventory.put(generation, inventoryInputKinds, kinds)
+	return kinds, nil
+}
+
+// SpecialInputs returns the names of the global desktop and mic inputs
+func (om *OBSManager) SpecialInputs() (*SpecialInputs, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+	cached, generation, ok := om.inventory.get(inventorySpecialInputs)
+	if ok {
+		return cached.(*SpecialInputs), nil
+	}
+
+	resp, err := client.Inputs.GetSpecialInputs()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	special := &SpecialInputs{
+		Desktop1: resp.Desktop1,
+		Desktop2: resp.Desktop2,
+		Mic1:     resp.Mic1,
+		Mic2:     resp.Mic2,
+		Mic3:     resp.Mic3,
+		Mic4:     resp.Mic4,
+	}
+
+	om.inventory.put(generation, inventorySpecialInputs, special)
+	return special, nil
+}
@@ -1,26 +1,57 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"sync"
 
 	"github.com/andreykaipov/goobs"
 	"github.com/andreykaipov/goobs/api/requests/filters"
 	"github.com/andreykaipov/goobs/api/requests/inputs"
-	"github.com/andreykaipov/goobs/api/requests/sceneitems"
 	"github.com/andreykaipov/goobs/api/requests/scenes"
 	"github.com/andreykaipov/goobs/api/requests/transitions"
 	"github.com/andreykaipov/goobs/api/requests/ui"
+	"github.com/robomon1/robo-stream/server/internal/logging"
 	"github.com/robomon1/robo-stream/server/internal/models"
 )
 
+var (
+	obsLog     = logging.For(logging.OBS)
+	storageLog = logging.For(logging.Storage)
+)
+
 // OBSManager manages OBS WebSocket connection
 type OBSManager struct {
 	client *goobs.Client
 	url    string
 	mu     sync.RWMutex
+
+	// connects and connectFailures count connection attempts, for metrics
+	connects        int
+	connectFailures int
+	// history keeps the latest connection events, for diagnostics
+	history []ConnectionEvent
+
+	onRename  func(Rename)
+	onConnect func()
+
+	// inventory caches scene items, filters, transitions and input kinds
+	// for editors
+	inventory inventory
+
+	// statusMu guards the status snapshot kept from events, the count of
+	// events applied to it and its version
+	statusMu      sync.Mutex
+	status        statusSnapshot
+	statusEvents  int
+	statusVersion uint64
+
+	// statsMu guards the samples output bitrates are computed from
+	statsMu      sync.Mutex
+	streamSample outputSample
+	recordSample outputSample
 }
 
 // NewOBSManager creates a new OBSManager
@@ -35,15 +66,31 @@ func (om *OBSManager) Connect(url, password string) error {
 
 	if om.client != nil {
 		om.client.Disconnect()
+		om.client = nil
+		om.recordConnection(ConnectionDisconnected, om.url, nil)
+		om.resetStatus()
 	}
 
 	client, err := goobs.New(url, goobs.WithPassword(password))
 	if err != nil {
+		om.connectFailures++
+		om.recordConnection(ConnectionFailed, url, err)
 		return fmt.Errorf("failed to connect to OBS: %w", err)
 	}
 
+	om.connects++
+	om.inventory.invalidate()
+	om.resetStatus()
 	om.client = client
 	om.url = url
+	om.recordConnection(ConnectionConnected, url, nil)
+	obsLog.Info("Connected to OBS", "url", url)
+
+	go om.listen(client)
+	go om.statusLoop(client)
+	if om.onConnect != nil {
+		go om.onConnect()
+	}
 	return nil
 }
 
@@ -55,6 +102,10 @@ func (om *OBSManager) Disconnect() error {
 	if om.client != nil {
 		om.client.Disconnect()
 		om.client = nil
+		om.recordConnection(ConnectionDisconnected, om.url, nil)
+		om.inventory.invalidate()
+		om.resetStatus()
+		obsLog.Info("Disconnected from OBS", "url", om.url)
 	}
 	return nil
 }
@@ -80,12 +131,12 @@ func (om *OBSManager) GetScenes() ([]string, error) {
 	om.mu.RUnlock()
 
 	if client == nil {
-		return nil, fmt.Errorf("not connected to OBS")
+		return nil, ErrOBSNotConnected
 	}
 
 	resp, err := client.Scenes.GetSceneList()
 	if err != nil {
-		return nil, err
+		return nil, obsError(err)
 	}
 
 	sceneNames := make([]string, len(resp.Scenes))
@@ -93,7 +144,7 @@ func (om *OBSManager) GetScenes() ([]string, error) {
 		sceneNames[i] = scene.SceneName
 	}
 
-	log.Printf("🎬 GetScenes returning %d scenes: %v", len(sceneNames), sceneNames)
+	obsLog.Debug("Listed scenes", "scenes", sceneNames)
 	return sceneNames, nil
 }
 
@@ -104,12 +155,12 @@ func (om *OBSManager) GetInputs() ([]string, error) {
 	om.mu.RUnlock()
 
 	if client == nil {
-		return nil, fmt.Errorf("not connected to OBS")
+		return nil, ErrOBSNotConnected
 	}
 
 	resp, err := client.Inputs.GetInputList(&inputs.GetInputListParams{})
 	if err != nil {
-		return nil, err
+		return nil, obsError(err)
 	}
 
 	inputNames := make([]string, len(resp.Inputs))
@@ -117,26 +168,43 @@ func (om *OBSManager) GetInputs() ([]string, error) {
 		inputNames[i] = input.InputName
 	}
 
-	log.Printf("🎤 GetInputs returning %d inputs: %v", len(inputNames), inputNames)
+	obsLog.Debug("Listed inputs", "inputs", inputNames)
 	return inputNames, nil
 }
 
-// ExecuteAction executes a button action
-func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
+// ExecuteAction executes a button action, giving up when ctx is done or
+// the action type's time limit passes. Failed requests to OBS are returned
+// as *OBSRequestError, bad parameters as *ParamError and timeouts as
+// ErrOBSTimeout.
+func (om *OBSManager) ExecuteAction(ctx context.Context, action models.ButtonAction) error {
 	om.mu.RLock()
 	client := om.client
 	om.mu.RUnlock()
 
 	if client == nil {
-		return fmt.Errorf("not connected to OBS")
+		return ErrOBSNotConnected
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout(action.Type))
+	defer cancel()
+	err := obsError(withContext(ctx, func() error {
+		return om.runAction(ctx, client, action)
+	}))
+	if errors.Is(err, ErrOBSTimeout) {
+		obsLog.Warn("OBS did not answer an action in time", "action", action.Type, "timeout", actionTimeout(action.Type))
+	}
+	return err
+}
+
+// runAction runs a button action with a connected client. Actions taking
+// several requests stop early once ctx is done.
+func (om *OBSManager) runAction(ctx context.Context, client *goobs.Client, action models.ButtonAction) error {
 	switch action.Type {
 	// ===== SCENES =====
 	case "switch_scene":
 		sceneName, ok := action.Params["scene_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing scene_name parameter")
+			return missingParam("scene_name")
 		}
 		_, err := client.Scenes.SetCurrentProgramScene(&scenes.SetCurrentProgramSceneParams{
 			SceneName: &sceneName,
@@ -178,104 +246,14 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 		return err
 
 	// ===== SOURCE VISIBILITY =====
-	case "toggle_source_visibility":
-		sceneName, ok := action.Params["scene_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing scene_name parameter")
-		}
-		sourceName, ok := action.Params["source_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing source_name parameter")
-		}
-
-		itemResp, err := client.SceneItems.GetSceneItemId(&sceneitems.GetSceneItemIdParams{
-			SceneName:  &sceneName,
-			SourceName: &sourceName,
-		})
-		if err != nil {
-			return err
-		}
-
-		itemID := itemResp.SceneItemId
-
-		stateResp, err := client.SceneItems.GetSceneItemEnabled(&sceneitems.GetSceneItemEnabledParams{
-			SceneName:   &sceneName,
-			SceneItemId: &itemID,
-		})
-		if err != nil {
-			return err
-		}
-
-		newState := !stateResp.SceneItemEnabled
-
-		_, err = client.SceneItems.SetSceneItemEnabled(&sceneitems.SetSceneItemEnabledParams{
-			SceneName:        &sceneName,
-			SceneItemId:      &itemID,
-			SceneItemEnabled: &newState,
-		})
-		return err
-
-	case "show_source":
-		sceneName, ok := action.Params["scene_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing scene_name parameter")
-		}
-		sourceName, ok := action.Params["source_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing source_name parameter")
-		}
-
-		itemResp, err := client.SceneItems.GetSceneItemId(&sceneitems.GetSceneItemIdParams{
-			SceneName:  &sceneName,
-			SourceName: &sourceName,
-		})
-		if err != nil {
-			return err
-		}
-
-		itemID := itemResp.SceneItemId
-		enabled := true
-
-		_, err = client.SceneItems.SetSceneItemEnabled(&sceneitems.SetSceneItemEnabledParams{
-			SceneName:        &sceneName,
-			SceneItemId:      &itemID,
-			SceneItemEnabled: &enabled,
-		})
-		return err
-
-	case "hide_source":
-		sceneName, ok := action.Params["scene_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing scene_name parameter")
-		}
-		sourceName, ok := action.Params["source_name"].(string)
-		if !ok {
-			return fmt.Errorf("missing source_name parameter")
-		}
-
-		itemResp, err := client.SceneItems.GetSceneItemId(&sceneitems.GetSceneItemIdParams{
-			SceneName:  &sceneName,
-			SourceName: &sourceName,
-		})
-		if err != nil {
-			return err
-		}
-
-		itemID := itemResp.SceneItemId
-		enabled := false
-
-		_, err = client.SceneItems.SetSceneItemEnabled(&sceneitems.SetSceneItemEnabledParams{
-			SceneName:        &sceneName,
-			SceneItemId:      &itemID,
-			SceneItemEnabled: &enabled,
-		})
-		return err
+	case "toggle_source_visibility", "show_source", "hide_source":
+		return om.setSourceVisibility(ctx, client, action)
 
 	// ===== AUDIO INPUTS =====
 	case "toggle_input_mute":
 		inputName, ok := action.Params["input_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing input_name parameter")
+			return missingParam("input_name")
 		}
 		_, err := client.Inputs.ToggleInputMute(&inputs.ToggleInputMuteParams{
 			InputName: &inputName,
@@ -285,7 +263,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "mute_input":
 		inputName, ok := action.Params["input_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing input_name parameter")
+			return missingParam("input_name")
 		}
 		muted := true
 		_, err := client.Inputs.SetInputMute(&inputs.SetInputMuteParams{
@@ -297,7 +275,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "unmute_input":
 		inputName, ok := action.Params["input_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing input_name parameter")
+			return missingParam("input_name")
 		}
 		muted := false
 		_, err := client.Inputs.SetInputMute(&inputs.SetInputMuteParams{
@@ -309,22 +287,12 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "set_input_volume":
 		inputName, ok := action.Params["input_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing input_name parameter")
+			return missingParam("input_name")
 		}
 
-		// Volume can come as float64 or string
-		var volumePercent float64
-		switch v := action.Params["volume"].(type) {
-		case float64:
-			volumePercent = v
-		case string:
-			var err error
-			volumePercent, err = strconv.ParseFloat(v, 64)
-			if err != nil {
-				return fmt.Errorf("invalid volume parameter: %s", v)
-			}
-		default:
-			return fmt.Errorf("invalid volume parameter type")
+		volumePercent, err := numberParam(action.Params, "volume")
+		if err != nil {
+			return err
 		}
 
 		// Convert percentage (0-100) to multiplier (0.0-1.0)
@@ -336,7 +304,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 			volumeMultiplier = 1
 		}
 
-		_, err := client.Inputs.SetInputVolume(&inputs.SetInputVolumeParams{
+		_, err = client.Inputs.SetInputVolume(&inputs.SetInputVolumeParams{
 			InputName:      &inputName,
 			InputVolumeMul: &volumeMultiplier,
 		})
@@ -376,11 +344,11 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "toggle_source_filter":
 		sourceName, ok := action.Params["source_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing source_name parameter")
+			return missingParam("source_name")
 		}
 		filterName, ok := action.Params["filter_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing filter_name parameter")
+			return missingParam("filter_name")
 		}
 
 		// Get current state
@@ -391,6 +359,9 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return contextError(err)
+		}
 
 		// Toggle state
 		newState := !stateResp.FilterEnabled
@@ -404,11 +375,11 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "enable_source_filter":
 		sourceName, ok := action.Params["source_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing source_name parameter")
+			return missingParam("source_name")
 		}
 		filterName, ok := action.Params["filter_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing filter_name parameter")
+			return missingParam("filter_name")
 		}
 
 		enabled := true
@@ -422,11 +393,11 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "disable_source_filter":
 		sourceName, ok := action.Params["source_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing source_name parameter")
+			return missingParam("source_name")
 		}
 		filterName, ok := action.Params["filter_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing filter_name parameter")
+			return missingParam("filter_name")
 		}
 
 		enabled := false
@@ -441,7 +412,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	// NOTE: Media controls are not available in goobs v1.3.0
 	// Upgrade to goobs v1.4+ to enable these features
 	case "play_pause_media", "restart_media", "stop_media", "next_media", "previous_media":
-		return fmt.Errorf("media controls require goobs v1.4+, currently using v1.3.0")
+		return fmt.Errorf("%w: media controls require goobs v1.4+, currently using v1.3.0", ErrUnsupportedAction)
 
 	// ===== TRANSITIONS =====
 	case "trigger_transition":
@@ -451,7 +422,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "set_current_transition":
 		transitionName, ok := action.Params["transition_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing transition_name parameter")
+			return missingParam("transition_name")
 		}
 		_, err := client.Transitions.SetCurrentSceneTransition(&transitions.SetCurrentSceneTransitionParams{
 			TransitionName: &transitionName,
@@ -459,22 +430,12 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 		return err
 
 	case "set_transition_duration":
-		// Duration can come as float64 or string
-		var duration float64 // ← Changed from int to float64
-		switch d := action.Params["duration"].(type) {
-		case float64:
-			duration = d // ← No conversion needed now
-		case string:
-			var err error
-			duration, err = strconv.ParseFloat(d, 64) // ← Changed from Atoi to ParseFloat
-			if err != nil {
-				return fmt.Errorf("invalid duration parameter: %s", d)
-			}
-		default:
-			return fmt.Errorf("invalid duration parameter type")
+		duration, err := numberParam(action.Params, "duration")
+		if err != nil {
+			return err
 		}
 
-		_, err := client.Transitions.SetCurrentSceneTransitionDuration(&transitions.SetCurrentSceneTransitionDurationParams{
+		_, err = client.Transitions.SetCurrentSceneTransitionDuration(&transitions.SetCurrentSceneTransitionDurationParams{
 			TransitionDuration: &duration,
 		})
 		return err
@@ -486,6 +447,9 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return contextError(err)
+		}
 
 		// Toggle state
 		newState := !stateResp.StudioModeEnabled
@@ -511,7 +475,7 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 	case "set_preview_scene":
 		sceneName, ok := action.Params["scene_name"].(string)
 		if !ok {
-			return fmt.Errorf("missing scene_name parameter")
+			return missingParam("scene_name")
 		}
 		_, err := client.Scenes.SetCurrentPreviewScene(&scenes.SetCurrentPreviewSceneParams{
 			SceneName: &sceneName,
@@ -519,101 +483,74 @@ func (om *OBSManager) ExecuteAction(action models.ButtonAction) error {
 		return err
 
 	default:
-		return fmt.Errorf("unknown action type: %s", action.Type)
+		return fmt.Errorf("%w: %s", ErrUnknownAction, action.Type)
 	}
 }
 
-// GetStatus returns current OBS status
-func (om *OBSManager) GetStatus() (map[string]interface{}, error) {
-	om.mu.RLock()
-	client := om.client
-	om.mu.RUnlock()
-
-	if client == nil {
-		return map[string]interface{}{
-			"connected": false,
-		}, nil
-	}
-
-	// Get stream status
-	streamResp, err := client.Stream.GetStreamStatus()
-	if err != nil {
-		return nil, err
-	}
-
-	// Get record status
-	recordResp, err := client.Record.GetRecordStatus()
-	if err != nil {
-		return nil, err
-	}
-
-	// Get current scene
-	sceneResp, err := client.Scenes.GetCurrentProgramScene()
-	if err != nil {
-		return nil, err
-	}
-
-	// Get virtual camera status
-	virtualCamResp, err := client.Outputs.GetVirtualCamStatus()
-	virtualCamActive := false
-	if err == nil {
-		virtualCamActive = virtualCamResp.OutputActive
-	}
-
-	// Get replay buffer status
-	replayBufferResp, err := client.Outputs.GetReplayBufferStatus()
-	replayBufferActive := false
-	if err == nil {
-		replayBufferActive = replayBufferResp.OutputActive
+// numberParam reads a number parameter, which clients send as a number or a
+// string
+func numberParam(params map[string]interface{}, name string) (float64, error) {
+	switch v := params[name].(type) {
+	case nil:
+		return 0, missingParam(name)
+	case float64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, invalidParam(name, fmt.Sprintf("%q is not a number", v))
+		}
+		return n, nil
+	default:
+		return 0, invalidParam(name, "must be a number")
 	}
+}
 
-	// Get studio mode status
-	studioModeResp, err := client.Ui.GetStudioModeEnabled()
-	studioModeActive := false
-	if err == nil {
-		studioModeActive = studioModeResp.StudioModeEnabled
-	}
+// actionTypes are the action types runAction knows
+var actionTypes = map[string]bool{
+	"disable_source_filter": true, "disable_studio_mode": true,
+	"enable_source_filter": true, "enable_studio_mode": true,
+	"hide_source": true, "mute_input": true, "next_media": true,
+	"pause_record": true, "play_pause_media": true, "previous_media": true,
+	"restart_media": true, "resume_record": true, "save_replay_buffer": true,
+	"set_current_transition": true, "set_input_volume": true,
+	"set_preview_scene": true, "set_transition_duration": true,
+	"show_source": true, "start_record": true, "start_replay_buffer": true,
+	"start_stream": true, "start_virtual_cam": true, "stop_media": true,
+	"stop_record": true, "stop_replay_buffer": true, "stop_stream": true,
+	"stop_virtual_cam": true, "switch_scene": true, "toggle_input_mute": true,
+	"toggle_record": true, "toggle_replay_buffer": true,
+	"toggle_source_filter": true, "toggle_source_visibility": true,
+	"toggle_stream": true, "toggle_studio_mode": true,
+	"toggle_virtual_cam": true, "trigger_transition": true,
+	"unmute_input": true,
+}
 
-	return map[string]interface{}{
-		"connected":            true,
-		"streaming":            streamResp.OutputActive,
-		"recording":            recordResp.OutputActive,
-		"current_scene":        sceneResp.CurrentProgramSceneName,
-		"virtual_cam_active":   virtualCamActive,
-		"replay_buffer_active": replayBufferActive,
-		"studio_mode_active":   studioModeActive,
-	}, nil
+// KnownAction reports whether an action type is one the server can run
+func KnownAction(actionType string) bool {
+	return actionTypes[actionType]
 }
 
-// GetSourceVisibility checks if a source is visible in a scene
-func (om *OBSManager) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
+// GetSourceVisibility checks if a source is visible in a scene, including
+// sources in groups and nested scenes
+func (om *OBSManager) GetSourceVisibility(ctx context.Context, sceneName, sourceName string) (bool, error) {
 	om.mu.RLock()
 	client := om.client
 	om.mu.RUnlock()
 
 	if client == nil {
-		return false, fmt.Errorf("not connected to OBS")
+		return false, ErrOBSNotConnected
 	}
 
-	// Get the scene item ID
-	itemResp, err := client.SceneItems.GetSceneItemId(&sceneitems.GetSceneItemIdParams{
-		SceneName:  &sceneName,
-		SourceName: &sourceName,
-	})
-	if err != nil {
-		return false, err
-	}
-
-	itemID := itemResp.SceneItemId
-
-	// Get visibility state
-	stateResp, err := client.SceneItems.GetSceneItemEnabled(&sceneitems.GetSceneItemEnabledParams{
-		SceneName:   &sceneName,
-		SceneItemId: &itemID,
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	var item SceneItem
+	err := withContext(ctx, func() (err error) {
+		_, item, err = om.resolveSceneItem(sceneName, sourceName, 0)
+		return err
 	})
 	if err != nil {
-		return false, err
+		return false, obsError(err)
 	}
-
-	return stateResp.SceneItemEnabled, nil
+	return item.Enabled, nil
 }
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/requests/sceneitems"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// sourceTypeScene is the source type of scenes nested in other scenes
+const sourceTypeScene = "OBS_SOURCE_TYPE_SCENE"
+
+// maxSceneNesting is how deep nested scenes are searched for a source
+const maxSceneNesting = 5
+
+// resolveSceneItem finds a source in a scene from the cached scene items:
+// directly in the scene, in one of its groups or in a nested scene. It
+// returns the scene or group holding the item, which is where OBS expects
+// requests about it. A scene item ID, if not 0, picks the item directly
+// as long as it still shows the named source.
+func (om *OBSManager) resolveSceneItem(scene, source string, itemID int) (string, SceneItem, error) {
+	items, err := om.SceneItems(scene)
+	if err != nil {
+		return "", SceneItem{}, err
+	}
+	if itemID != 0 {
+		for _, item := range items {
+			if item.ID == itemID && item.Group == "" && (source == "" || item.SourceName == source) {
+				return scene, item, nil
+			}
+		}
+		if source == "" {
+			return "", SceneItem{}, invalidParam("scene_item_id", fmt.Sprintf("scene %q has no item %d", scene, itemID))
+		}
+	}
+
+	if owner, item, ok := om.findInScene(scene, source, items, map[string]bool{scene: true}, 0); ok {
+		return owner, item, nil
+	}
+	return "", SceneItem{}, invalidParam("source_name", fmt.Sprintf("source %q is not in scene %q", source, scene))
+}
+
+// findInScene looks for a source in a scene's items, then in the scenes
+// nested in it
+func (om *OBSManager) findInScene(scene, source string, items []SceneItem, visited map[string]bool, depth int) (string, SceneItem, bool) {
+	if item, ok := findSceneItem(items, source); ok {
+		if item.Group != "" {
+			return item.Group, item, true
+		}
+		return scene, item, true
+	}
+	if depth >= maxSceneNesting {
+		return "", SceneItem{}, false
+	}
+
+	for _, item := range items {
+		if item.SourceType != sourceTypeScene || item.IsGroup || visited[item.SourceName] {
+			continue
+		}
+		visited[item.SourceName] = true
+		nested, err := om.SceneItems(item.SourceName)
+		if err != nil {
+			continue
+		}
+		if owner, found, ok := om.findInScene(item.SourceName, source, nested, visited, depth+1); ok {
+			return owner, found, true
+		}
+	}
+	return "", SceneItem{}, false
+}
+
+// setSourceVisibility runs the toggle_source_visibility, show_source and
+// hide_source actions
+func (om *OBSManager) setSourceVisibility(ctx context.Context, client *goobs.Client, action models.ButtonAction) error {
+	sceneName, ok := action.Params["scene_name"].(string)
+	if !ok {
+		return missingParam("scene_name")
+	}
+	sourceName, _ := action.Params["source_name"].(string)
+	itemID := 0
+	if _, set := action.Params["scene_item_id"]; set {
+		id, err := numberParam(action.Params, "scene_item_id")
+		if err != nil {
+			return err
+		}
+		itemID = int(id)
+	}
+	if sourceName == "" && itemID == 0 {
+		return missingParam("source_name")
+	}
+
+	owner, item, err := om.resolveSceneItem(sceneName, sourceName, itemID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	var enabled bool
+	switch action.Type {
+	case "show_source":
+		enabled = true
+	case "hide_source":
+		enabled = false
+	default:
+		// Ask OBS rather than trust the cache, which lags behind quick presses
+		state, err := client.SceneItems.GetSceneItemEnabled(&sceneitems.GetSceneItemEnabledParams{
+			SceneName:   &owner,
+			SceneItemId: &item.ID,
+		})
+		if err != nil {
+			return err
+		}
+		enabled = !state.SceneItemEnabled
+		if err := ctx.Err(); err != nil {
+			return contextError(err)
+		}
+	}
+
+	_, err = client.SceneItems.SetSceneItemEnabled(&sceneitems.SetSceneItemEnabledParams{
+		SceneName:        &owner,
+		SceneItemId:      &item.ID,
+		SceneItemEnabled: &enabled,
+	})
+	return err
+}
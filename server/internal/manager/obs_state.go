@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// statusActions maps actions whose state is part of the OBS status to the
+// status field holding it
+var statusActions = map[string]string{
+	"start_stream":         "streaming",
+	"stop_stream":          "streaming",
+	"toggle_stream":        "streaming",
+	"start_record":         "recording",
+	"stop_record":          "recording",
+	"toggle_record":        "recording",
+	"start_virtual_cam":    "virtual_cam_active",
+	"stop_virtual_cam":     "virtual_cam_active",
+	"toggle_virtual_cam":   "virtual_cam_active",
+	"start_replay_buffer":  "replay_buffer_active",
+	"stop_replay_buffer":   "replay_buffer_active",
+	"toggle_replay_buffer": "replay_buffer_active",
+	"enable_studio_mode":   "studio_mode_active",
+	"disable_studio_mode":  "studio_mode_active",
+	"toggle_studio_mode":   "studio_mode_active",
+}
+
+// ButtonState is whether what a button controls is on: the output running,
+// studio mode enabled, the scene live, the source visible, the input muted
+// or the filter enabled
+type ButtonState struct {
+	ID         string `json:"id"` // the button's position, as in ResolvedButton
+	ActionType string `json:"action_type"`
+	Active     bool   `json:"active"`
+}
+
+// ButtonStates returns the state of the stateful buttons of a resolved
+// configuration. The status is read once; visibility, filters and mute
+// states come from the inventory cache, which OBS events keep up to date.
+// Buttons whose state OBS can't report are left out.
+func (om *OBSManager) ButtonStates(ctx context.Context, buttons []models.ResolvedButton) ([]ButtonState, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	var states []ButtonState
+	err := withContext(ctx, func() (err error) {
+		states, err = om.buttonStates(ctx, buttons)
+		return err
+	})
+	if err != nil {
+		return nil, obsError(err)
+	}
+	return states, nil
+}
+
+// buttonStates reads the button states, stopping once ctx is done
+func (om *OBSManager) buttonStates(ctx context.Context, buttons []models.ResolvedButton) ([]ButtonState, error) {
+	var status map[string]interface{}
+	var statusErr error
+
+	states := []ButtonState{}
+	for _, button := range buttons {
+		if err := ctx.Err(); err != nil {
+			return nil, contextError(err)
+		}
+		action := button.Action
+		var active, ok bool
+
+		switch action.Type {
+		case "switch_scene":
+			if status == nil && statusErr == nil {
+				status, statusErr = om.GetStatus(ctx)
+			}
+			scene, _ := action.Params["scene_name"].(string)
+			current, isString := status["current_scene"].(string)
+			active, ok = current == scene, isString && scene != ""
+
+		case "toggle_source_visibility", "show_source", "hide_source":
+			scene, _ := action.Params["scene_name"].(string)
+			source, _ := action.Params["source_name"].(string)
+			itemID, _ := numberParam(action.Params, "scene_item_id")
+			if scene == "" || (source == "" && itemID == 0) {
+				continue
+			}
+			_, item, err := om.resolveSceneItem(scene, source, int(itemID))
+			if err != nil {
+				continue
+			}
+			active, ok = item.Enabled, true
+
+		case "toggle_input_mute", "mute_input", "unmute_input":
+			input, _ := action.Params["input_name"].(string)
+			if input == "" {
+				continue
+			}
+			muted, err := om.InputMuted(input)
+			if err != nil {
+				continue
+			}
+			active, ok = muted, true
+
+		case "toggle_source_filter", "enable_source_filter", "disable_source_filter":
+			source, _ := action.Params["source_name"].(string)
+			filter, _ := action.Params["filter_name"].(string)
+			if source == "" || filter == "" {
+				continue
+			}
+			filters, err := om.SourceFilters(source)
+			if err != nil {
+				continue
+			}
+			for _, f := range filters {
+				if f.Name == filter {
+					active, ok = f.Enabled, true
+					break
+				}
+			}
+
+		default:
+			field, stateful := statusActions[action.Type]
+			if !stateful {
+				continue
+			}
+			if status == nil && statusErr == nil {
+				status, statusErr = om.GetStatus(ctx)
+			}
+			active, ok = status[field].(bool)
+		}
+
+		if ok {
+			states = append(states, ButtonState{ID: button.ID, ActionType: action.Type, Active: active})
+		}
+	}
+	return states, nil
+}
+
+// findSceneItem finds a source in a scene's items, preferring one directly
+// in the scene over one in a group
+func findSceneItem(items []SceneItem, source string) (SceneItem, bool) {
+	var found SceneItem
+	ok := false
+	for _, item := range items {
+		if item.SourceName != source {
+			continue
+		}
+		if item.Group == "" {
+			return item, true
+		}
+		if !ok {
+			found, ok = item, true
+		}
+	}
+	return found, ok
+}
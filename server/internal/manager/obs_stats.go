@@ -0,0 +1,121 @@
+package manager
+
+import "time"
+
+// OBSStats is a snapshot of OBS performance and output health, for metrics
+type OBSStats struct {
+	CPUUsage              float64 // percent
+	MemoryMB              float64
+	ActiveFPS             float64
+	AverageFrameRenderMs  float64
+	RenderSkippedFrames   float64 // frames missed by the render thread (GPU lag)
+	RenderTotalFrames     float64
+	EncoderSkippedFrames  float64 // frames missed by the output thread (encoder lag)
+	EncoderTotalFrames    float64
+	AvailableDiskSpaceMB  float64
+	Streaming             bool
+	StreamReconnecting    bool
+	StreamBytes           float64
+	StreamBitrate         float64 // bits per second since the previous snapshot
+	StreamDroppedFrames   float64 // frames the stream dropped, e.g. on a bad network
+	StreamTotalFrames     float64
+	StreamCongestion      float64 // 0 to 1
+	StreamDurationSeconds float64
+	Recording             bool
+	RecordBytes           float64
+	RecordBitrate         float64 // bits per second since the previous snapshot
+	RecordDurationSeconds float64
+}
+
+// minBitrateInterval is the shortest time bitrates are measured over, so
+// scrapes close together don't produce noise
+const minBitrateInterval = time.Second
+
+// outputSample remembers an output's byte count to compute its bitrate
+type outputSample struct {
+	bytes   float64
+	at      time.Time
+	bitrate float64
+}
+
+// update returns the bits per second written since the previous sample
+func (s *outputSample) update(active bool, bytes float64, now time.Time) float64 {
+	if !active {
+		*s = outputSample{}
+		return 0
+	}
+	if s.at.IsZero() || bytes < s.bytes {
+		*s = outputSample{bytes: bytes, at: now}
+		return 0
+	}
+	elapsed := now.Sub(s.at)
+	if elapsed < minBitrateInterval {
+		return s.bitrate
+	}
+	s.bitrate = (bytes - s.bytes) * 8 / elapsed.Seconds()
+	s.bytes = bytes
+	s.at = now
+	return s.bitrate
+}
+
+// Stats returns OBS performance and the health of the stream and recording
+func (om *OBSManager) Stats() (*OBSStats, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOBSNotConnected
+	}
+
+	general, err := client.General.GetStats()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	stream, err := client.Stream.GetStreamStatus()
+	if err != nil {
+		return nil, obsError(err)
+	}
+	record, err := client.Record.GetRecordStatus()
+	if err != nil {
+		return nil, obsError(err)
+	}
+
+	stats := &OBSStats{
+		CPUUsage:              general.CpuUsage,
+		MemoryMB:              general.MemoryUsage,
+		ActiveFPS:             general.ActiveFps,
+		AverageFrameRenderMs:  general.AverageFrameRenderTime,
+		RenderSkippedFrames:   general.RenderSkippedFrames,
+		RenderTotalFrames:     general.RenderTotalFrames,
+		EncoderSkippedFrames:  general.OutputSkippedFrames,
+		EncoderTotalFrames:    general.OutputTotalFrames,
+		AvailableDiskSpaceMB:  general.AvailableDiskSpace,
+		Streaming:             stream.OutputActive,
+		StreamReconnecting:    stream.OutputReconnecting,
+		StreamBytes:           stream.OutputBytes,
+		StreamDroppedFrames:   stream.OutputSkippedFrames,
+		StreamTotalFrames:     stream.OutputTotalFrames,
+		StreamCongestion:      stream.OutputCongestion,
+		StreamDurationSeconds: stream.OutputDuration / 1000,
+		Recording:             record.OutputActive,
+		RecordBytes:           record.OutputBytes,
+		RecordDurationSeconds: record.OutputDuration / 1000,
+	}
+
+	now := time.Now()
+	om.statsMu.Lock()
+	stats.StreamBitrate = om.streamSample.update(stream.OutputActive, stream.OutputBytes, now)
+	stats.RecordBitrate = om.recordSample.update(record.OutputActive, record.OutputBytes, now)
+	om.statsMu.Unlock()
+
+	return stats, nil
+}
+
+// ConnectionCounts returns how often connecting to OBS succeeded and failed
+// since the server started
+func (om *OBSManager) ConnectionCounts() (connects, failures int) {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	return om.connects, om.connectFailures
+}
@@ -0,0 +1,281 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/events"
+)
+
+// statusReconcileInterval is how often the status snapshot is checked
+// against OBS, in case an event was missed
+const statusReconcileInterval = 30 * time.Second
+
+// Output states in OBS events
+const (
+	outputStarted = "OBS_WEBSOCKET_OUTPUT_STARTED"
+	outputStopped = "OBS_WEBSOCKET_OUTPUT_STOPPED"
+	outputPaused  = "OBS_WEBSOCKET_OUTPUT_PAUSED"
+	outputResumed = "OBS_WEBSOCKET_OUTPUT_RESUMED"
+)
+
+// statusSnapshot is the OBS status, kept up to date from events
+type statusSnapshot struct {
+	loaded       bool
+	streaming    bool
+	recording    bool
+	recordPaused bool
+	currentScene string
+	virtualCam   bool
+	replayBuffer bool
+	studioMode   bool
+
+	// The timecodes are worked out when read: the stream has run since
+	// streamStarted, the recording for recordElapsed plus the time since
+	// recordStarted unless paused
+	streamStarted time.Time
+	recordStarted time.Time
+	recordElapsed time.Duration
+}
+
+// sameState reports whether two snapshots differ in more than timecodes
+func (s statusSnapshot) sameState(o statusSnapshot) bool {
+	return s.loaded == o.loaded &&
+		s.streaming == o.streaming &&
+		s.recording == o.recording &&
+		s.recordPaused == o.recordPaused &&
+		s.currentScene == o.currentScene &&
+		s.virtualCam == o.virtualCam &&
+		s.replayBuffer == o.replayBuffer &&
+		s.studioMode == o.studioMode
+}
+
+// GetStatus returns the current OBS status from the snapshot kept up to
+// date by OBS events. Version goes up whenever anything but the timecodes
+// changes, so pollers can skip snapshots they've seen. Only the first call
+// after connecting waits on OBS, until ctx is done at the latest.
+func (om *OBSManager) GetStatus(ctx context.Context) (map[string]interface{}, error) {
+	om.mu.RLock()
+	client := om.client
+	om.mu.RUnlock()
+
+	if client == nil {
+		om.statusMu.Lock()
+		defer om.statusMu.Unlock()
+		return map[string]interface{}{
+			"connected": false,
+			"version":   om.statusVersion,
+		}, nil
+	}
+
+	om.statusMu.Lock()
+	loaded := om.status.loaded
+	om.statusMu.Unlock()
+	if !loaded {
+		ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+		defer cancel()
+		err := withContext(ctx, func() error { return om.reconcileStatus(client) })
+		if err != nil {
+			return nil, obsError(err)
+		}
+	}
+
+	om.statusMu.Lock()
+	defer om.statusMu.Unlock()
+	s := om.status
+	now := time.Now()
+
+	var streamTime, recordTime time.Duration
+	if s.streaming {
+		streamTime = now.Sub(s.streamStarted)
+	}
+	if s.recording {
+		recordTime = s.recordElapsed
+		if !s.recordPaused {
+			recordTime += now.Sub(s.recordStarted)
+		}
+	}
+
+	return map[string]interface{}{
+		"connected":            true,
+		"streaming":            s.streaming,
+		"recording":            s.recording,
+		"record_paused":        s.recordPaused,
+		"current_scene":        s.currentScene,
+		"virtual_cam_active":   s.virtualCam,
+		"replay_buffer_active": s.replayBuffer,
+		"studio_mode_active":   s.studioMode,
+		"stream_timecode":      formatTimecode(streamTime),
+		"record_timecode":      formatTimecode(recordTime),
+		"version":              om.statusVersion,
+	}, nil
+}
+
+// formatTimecode formats a duration as OBS does, HH:MM:SS.mmm
+func formatTimecode(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// resetStatus forgets the snapshot when the connection changes. Callers
+// hold om.mu.
+func (om *OBSManager) resetStatus() {
+	om.statusMu.Lock()
+	defer om.statusMu.Unlock()
+	om.status = statusSnapshot{}
+	om.statusEvents++
+	om.statusVersion++
+}
+
+// statusLoop reconciles the snapshot with OBS right away and then
+// periodically, until the client is replaced or disconnected
+func (om *OBSManager) statusLoop(client *goobs.Client) {
+	ticker := time.NewTicker(statusReconcileInterval)
+	defer ticker.Stop()
+	for {
+		om.mu.RLock()
+		current := om.client
+		om.mu.RUnlock()
+		if current != client {
+			return
+		}
+
+		if err := om.reconcileStatus(client); err != nil {
+			obsLog.Warn("Failed to read OBS status", "error", err)
+			// The next read tries again and reports the error rather than
+			// serving a snapshot that may be stale
+			om.statusMu.Lock()
+			om.status.loaded = false
+			om.statusMu.Unlock()
+		}
+		<-ticker.C
+	}
+}
+
+// reconcileStatus reads the whole status from OBS into the snapshot. A
+// result that raced with an event is dropped, the event being newer.
+func (om *OBSManager) reconcileStatus(client *goobs.Client) error {
+	om.statusMu.Lock()
+	eventsBefore := om.statusEvents
+	om.statusMu.Unlock()
+
+	stream, err := client.Stream.GetStreamStatus()
+	if err != nil {
+		return obsError(err)
+	}
+	record, err := client.Record.GetRecordStatus()
+	if err != nil {
+		return obsError(err)
+	}
+	scene, err := client.Scenes.GetCurrentProgramScene()
+	if err != nil {
+		return obsError(err)
+	}
+
+	now := time.Now()
+	fresh := statusSnapshot{
+		loaded:        true,
+		streaming:     stream.OutputActive,
+		recording:     record.OutputActive,
+		recordPaused:  record.OutputActive && record.OutputPaused,
+		currentScene:  scene.CurrentProgramSceneName,
+		streamStarted: now.Add(-time.Duration(stream.OutputDuration) * time.Millisecond),
+		recordElapsed: time.Duration(record.OutputDuration) * time.Millisecond,
+		recordStarted: now,
+	}
+	// Not every OBS has these outputs
+	if resp, err := client.Outputs.GetVirtualCamStatus(); err == nil {
+		fresh.virtualCam = resp.OutputActive
+	}
+	if resp, err := client.Outputs.GetReplayBufferStatus(); err == nil {
+		fresh.replayBuffer = resp.OutputActive
+	}
+	if resp, err := client.Ui.GetStudioModeEnabled(); err == nil {
+		fresh.studioMode = resp.StudioModeEnabled
+	}
+
+	om.statusMu.Lock()
+	defer om.statusMu.Unlock()
+	if om.status.loaded && om.statusEvents != eventsBefore {
+		return nil
+	}
+	if !fresh.sameState(om.status) {
+		if om.status.loaded {
+			obsLog.Debug("Status snapshot was out of date")
+		}
+		om.statusVersion++
+	}
+	om.status = fresh
+	return nil
+}
+
+// updateStatus applies an OBS event to the status snapshot
+func (om *OBSManager) updateStatus(event any) {
+	om.statusMu.Lock()
+	defer om.statusMu.Unlock()
+
+	before := om.status
+	s := &om.status
+	now := time.Now()
+	switch e := event.(type) {
+	case *events.StreamStateChanged:
+		switch e.OutputState {
+		case outputStarted:
+			s.streaming, s.streamStarted = true, now
+		case outputStopped:
+			s.streaming = false
+		default:
+			return
+		}
+	case *events.RecordStateChanged:
+		switch e.OutputState {
+		case outputStarted:
+			s.recording, s.recordPaused = true, false
+			s.recordStarted, s.recordElapsed = now, 0
+		case outputStopped:
+			s.recording, s.recordPaused = false, false
+		case outputPaused:
+			if !s.recordPaused {
+				s.recordElapsed += now.Sub(s.recordStarted)
+			}
+			s.recordPaused = true
+		case outputResumed:
+			s.recordPaused, s.recordStarted = false, now
+		default:
+			return
+		}
+	case *events.VirtualcamStateChanged:
+		s.virtualCam = outputRunning(e.OutputState, s.virtualCam)
+	case *events.ReplayBufferStateChanged:
+		s.replayBuffer = outputRunning(e.OutputState, s.replayBuffer)
+	case *events.CurrentProgramSceneChanged:
+		s.currentScene = e.SceneName
+	case *events.SceneNameChanged:
+		if e.OldSceneName != s.currentScene {
+			return
+		}
+		s.currentScene = e.SceneName
+	case *events.StudioModeStateChanged:
+		s.studioMode = e.StudioModeEnabled
+	default:
+		return
+	}
+
+	om.statusEvents++
+	if !s.sameState(before) {
+		om.statusVersion++
+	}
+}
+
+// outputRunning returns whether an output runs after a state change event
+func outputRunning(state string, running bool) bool {
+	switch state {
+	case outputStarted:
+		return true
+	case outputStopped:
+		return false
+	}
+	return running
+}
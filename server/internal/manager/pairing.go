@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robomon1/robo-stream/server/internal/auth"
+	"github.com/robomon1/robo-stream/server/internal/models"
+)
+
+// DevicesFile is the data file holding paired devices
+const DevicesFile = "devices.json"
+
+// maxPairingAttempts is how many wrong codes invalidate the current one
+const maxPairingAttempts = 5
+
+// ErrInvalidPairingCode is returned when a code is wrong, used or expired
+var ErrInvalidPairingCode = errors.New("invalid or expired pairing code")
+
+// pendingPairing is the code currently shown in the server UI
+type pendingPairing struct {
+	code      string
+	expiresAt time.Time
+	attempts  int
+}
+
+// loadDevices reads paired devices from storage
+func (sm *SessionManager) loadDevices() error {
+	var devices []*models.PairedDevice
+	if err := sm.storage.LoadJSON(DevicesFile, &devices); err != nil {
+		return err
+	}
+	for _, device := range devices {
+		sm.devices[device.ID] = device
+	}
+	return nil
+}
+
+// saveDevices writes paired devices to storage
+func (sm *SessionManager) saveDevices() error {
+	devices := make([]*models.PairedDevice, 0, len(sm.devices))
+	for _, device := range sm.devices {
+		devices = append(devices, device)
+	}
+	return sm.storage.SaveJSON(DevicesFile, devices)
+}
+
+// StartPairing creates a new pairing code valid for ttl, replacing any
+// previous one
+func (sm *SessionManager) StartPairing(ttl time.Duration) (*models.PairingCode, error) {
+	code, err := auth.NewPairingCode()
+	if err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.pairing = &pendingPairing{
+		code:      code,
+		expiresAt: time.Now().Add(ttl),
+	}
+	return &models.PairingCode{Code: code, ExpiresAt: sm.pairing.expiresAt}, nil
+}
+
+// Pair redeems a pairing code and records the client as a paired device.
+// The code can only be used once, and too many wrong guesses invalidate it.
+func (sm *SessionManager) Pair(code, clientID, name string) (*models.PairedDevice, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	pending := sm.pairing
+	if pending == nil || time.Now().After(pending.expiresAt) {
+		sm.pairing = nil
+		return nil, ErrInvalidPairingCode
+	}
+	if code != pending.code {
+		pending.attempts++
+		if pending.attempts >= maxPairingAttempts {
+			sm.pairing = nil
+		}
+		return nil, ErrInvalidPairingCode
+	}
+	sm.pairing = nil
+
+	now := time.Now()
+	device := &models.PairedDevice{
+		ID:       uuid.New().String(),
+		Name:     name,
+		ClientID: clientID,
+		PairedAt: now,
+		LastSeen: now,
+	}
+	sm.devices[device.ID] = device
+	if err := sm.saveDevices(); err != nil {
+		delete(sm.devices, device.ID)
+		return nil, err
+	}
+	copied := *device
+	return &copied, nil
+}
+
+// Device returns a copy of a paired device
+func (sm *SessionManager) Device(deviceID string) (*models.PairedDevice, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	device, ok := sm.devices[deviceID]
+	if !ok {
+		return nil, fmt.Errorf("device not paired: %s", deviceID)
+	}
+	copied := *device
+	return &copied, nil
+}
+
+// Devices returns copies of all paired devices
+func (sm *SessionManager) Devices() []*models.PairedDevice {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	devices := make([]*models.PairedDevice, 0, len(sm.devices))
+	for _, device := range sm.devices {
+		copied := *device
+		devices = append(devices, &copied)
+	}
+	return devices
+}
+
+// PinDeviceRole caps a device's sessions at a role, unless it already has
+// one
+func (sm *SessionManager) PinDeviceRole(deviceID, role string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	device, ok := sm.devices[deviceID]
+	if !ok || device.Role != "" {
+		return nil
+	}
+	device.Role = role
+	return sm.saveDevices()
+}
+
+// TouchDevice records that a device used its token
+func (sm *SessionManager) TouchDevice(deviceID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if device, ok := sm.devices[deviceID]; ok {
+		device.LastSeen = time.Now()
+	}
+}
+
+// RevokeDevice unpairs a device so its token stops working, and removes its
+// sessions. It returns the removed session IDs.
+func (sm *SessionManager) RevokeDevice(deviceID string) ([]string, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, ok := sm.devices[deviceID]; !ok {
+		return nil, fmt.Errorf("device not paired: %s", deviceID)
+	}
+	delete(sm.devices, deviceID)
+	if err := sm.saveDevices(); err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for sessionID, sess := range sm.sessions {
+		if sess.DeviceID == deviceID {
+			delete(sm.sessions, sessionID)
+			removed = append(removed, sessionID)
+		}
+	}
+	return removed, sm.save()
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// ReloadResult describes how an externally edited data file was applied
+type ReloadResult struct {
+	File      string     `json:"file"`
+	Changed   []string   `json:"changed"`   // IDs added, updated or removed from disk
+	Conflicts []Conflict `json:"conflicts"` // records edited both in the app and on disk
+	Error     string     `json:"error,omitempty"`
+}
+
+// Conflict is a record that changed both in the app and on disk since the
+// last sync. The in-app version is always kept.
+type Conflict struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// snapshotRecords encodes records so later merges can tell which side
+// changed them
+func snapshotRecords[T any](records map[string]T) map[string][]byte {
+	snapshot := make(map[string][]byte, len(records))
+	for id, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			continue
+		}
+		snapshot[id] = data
+	}
+	return snapshot
+}
+
+// mergeRecords three-way merges records keyed by ID. base is the state last
+// synced with disk, local is the in-memory state and remote is what is on
+// disk now. Changes from either side are kept; when both sides changed the
+// same record differently the local version wins and a conflict is reported.
+func mergeRecords[T any](base map[string][]byte, local, remote map[string]T) (map[string]T, []string, []Conflict) {
+	localSnap := snapshotRecords(local)
+	remoteSnap := snapshotRecords(remote)
+
+	ids := make(map[string]bool)
+	for id := range base {
+		ids[id] = true
+	}
+	for id := range local {
+		ids[id] = true
+	}
+	for id := range remote {
+		ids[id] = true
+	}
+
+	merged := make(map[string]T, len(ids))
+	changed := make([]string, 0)
+	conflicts := make([]Conflict, 0)
+
+	for id := range ids {
+		b := base[id]
+		l, inLocal := localSnap[id]
+		r, inRemote := remoteSnap[id]
+
+		localChanged := !sameRecord(b, l)
+		remoteChanged := !sameRecord(b, r)
+
+		switch {
+		case !remoteChanged || sameRecord(l, r):
+			if inLocal {
+				merged[id] = local[id]
+			}
+		case !localChanged:
+			if inRemote {
+				merged[id] = remote[id]
+			}
+			changed = append(changed, id)
+		default:
+			if inLocal {
+				merged[id] = local[id]
+			}
+			conflicts = append(conflicts, Conflict{ID: id, Reason: conflictReason(inLocal, inRemote)})
+		}
+	}
+
+	sort.Strings(changed)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].ID < conflicts[j].ID })
+	return merged, changed, conflicts
+}
+
+// sameRecord compares two encoded records; nil means the record is absent
+func sameRecord(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return bytes.Equal(a, b)
+}
+
+// conflictReason explains which side won a conflicting change
+func conflictReason(inLocal, inRemote bool) string {
+	switch {
+	case !inLocal:
+		return "edited on disk but deleted in the app; kept the deletion"
+	case !inRemote:
+		return "deleted on disk but edited in the app; kept the in-app version"
+	default:
+		return "edited both on disk and in the app; kept the in-app version"
+	}
+}
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+	"github.com/robomon1/robo-stream/server/internal/storage"
+)
+
+// RolesFile is the data file holding the roles
+const RolesFile = "roles.json"
+
+// roleNamePattern restricts role names to lowercase words joined by dashes
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// RoleManager manages the roles that limit what client sessions may do
+type RoleManager struct {
+	storage *storage.Storage
+	roles   map[string]*models.Role
+	mu      sync.RWMutex
+}
+
+// NewRoleManager creates a new RoleManager, adding the built-in roles the
+// first time. A roles file that can't be read is an error rather than a
+// reason to replace it with the built-in roles.
+func NewRoleManager(storage *storage.Storage) (*RoleManager, error) {
+	rm := &RoleManager{
+		storage: storage,
+		roles:   make(map[string]*models.Role),
+	}
+	if err := rm.load(); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", RolesFile, err)
+	}
+	if len(rm.roles) == 0 {
+		for _, role := range models.DefaultRoles() {
+			rm.roles[role.Name] = role
+		}
+		if err := rm.save(); err != nil {
+			return nil, fmt.Errorf("failed to save %s: %w", RolesFile, err)
+		}
+	}
+	return rm, nil
+}
+
+// load reads roles from storage
+func (rm *RoleManager) load() error {
+	var roles []*models.Role
+	if err := rm.storage.LoadJSON(RolesFile, &roles); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role != nil {
+			rm.roles[role.Name] = role
+		}
+	}
+	return nil
+}
+
+// copyRole returns a copy of a role that shares nothing with it
+func copyRole(role *models.Role) *models.Role {
+	copied := *role
+	copied.AllowedActions = append(make([]string, 0, len(role.AllowedActions)), role.AllowedActions...)
+	if role.AllowedButtons != nil {
+		copied.AllowedButtons = append(make([]string, 0, len(role.AllowedButtons)), role.AllowedButtons...)
+	}
+	return &copied
+}
+
+// save writes roles to storage
+func (rm *RoleManager) save() error {
+	roles := make([]*models.Role, 0, len(rm.roles))
+	for _, role := range rm.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return rm.storage.SaveJSON(RolesFile, roles)
+}
+
+// List returns copies of all roles
+func (rm *RoleManager) List() []*models.Role {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	roles := make([]*models.Role, 0, len(rm.roles))
+	for _, role := range rm.roles {
+		roles = append(roles, copyRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+// Get retrieves a copy of a role by name
+func (rm *RoleManager) Get(name string) (*models.Role, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	role, ok := rm.roles[name]
+	if !ok {
+		return nil, fmt.Errorf("role not found: %s", name)
+	}
+	return copyRole(role), nil
+}
+
+// Save creates or replaces a role
+func (rm *RoleManager) Save(role *models.Role) error {
+	if !roleNamePattern.MatchString(role.Name) {
+		return fmt.Errorf("invalid role name %q", role.Name)
+	}
+	if role.AllowedActions == nil {
+		role.AllowedActions = []string{}
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.roles[role.Name] = copyRole(role)
+	return rm.save()
+}
+
+// Delete removes a role. The operator role can't be removed since sessions
+// without a role fall back to it.
+func (rm *RoleManager) Delete(name string) error {
+	if name == models.RoleOperator {
+		return fmt.Errorf("the %s role can't be deleted", models.RoleOperator)
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, ok := rm.roles[name]; !ok {
+		return fmt.Errorf("role not found: %s", name)
+	}
+	delete(rm.roles, name)
+	return rm.save()
+}
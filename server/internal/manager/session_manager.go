@@ -1,7 +1,9 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,10 +11,23 @@ import (
 	"github.com/robomon1/robo-stream/server/internal/storage"
 )
 
+// SessionsFile stores client sessions in the data directory
+const SessionsFile = "sessions.json"
+
+// ErrSessionForbidden is returned when a client ID's session belongs to
+// another paired device
+var ErrSessionForbidden = errors.New("session belongs to another device")
+
 // SessionManager manages client sessions
 type SessionManager struct {
 	storage  *storage.Storage
 	sessions map[string]*models.ClientSession
+	devices  map[string]*models.PairedDevice
+	pairing  *pendingPairing
+	mu       sync.RWMutex
+
+	// onConfigChange is told when a session is moved to another configuration
+	onConfigChange func(sessionID string)
 }
 
 // NewSessionManager creates a new SessionManager
@@ -20,15 +35,17 @@ func NewSessionManager(storage *storage.Storage) *SessionManager {
 	sm := &SessionManager{
 		storage:  storage,
 		sessions: make(map[string]*models.ClientSession),
+		devices:  make(map[string]*models.PairedDevice),
 	}
 	sm.load()
+	sm.loadDevices()
 	return sm
 }
 
 // load reads sessions from storage
 func (sm *SessionManager) load() error {
 	var sessions []*models.ClientSession
-	if err := sm.storage.LoadJSON("sessions.json", &sessions); err != nil {
+	if err := sm.storage.LoadJSON(SessionsFile, &sessions); err != nil {
 		return err
 	}
 	for _, sess := range sessions {
@@ -43,25 +60,34 @@ func (sm *SessionManager) save() error {
 	for _, sess := range sm.sessions {
 		sessions = append(sessions, sess)
 	}
-	return sm.storage.SaveJSON("sessions.json", sessions)
+	return sm.storage.SaveJSON(SessionsFile, sessions)
 }
 
-// RegisterOrUpdate creates a new session or updates existing one
-func (sm *SessionManager) RegisterOrUpdate(clientID, clientName, configID, ipAddress string) (*models.ClientSession, error) {
+// RegisterOrUpdate creates a new session or updates existing one for a
+// paired device. Sessions never move between devices: a client ID whose
+// session belongs to another device gets ErrSessionForbidden.
+func (sm *SessionManager) RegisterOrUpdate(deviceID, clientID, clientName, configID, ipAddress string) (*models.ClientSession, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	// Check if client already has a session
-	for _, sess := range sm.sessions {
-		if sess.ClientID == clientID {
-			// Update existing session
-			sess.ClientName = clientName
-			sess.IPAddress = ipAddress
-			sess.LastConnected = time.Now()
-			sess.LastActive = time.Now()
-			if configID != "" {
-				sess.ConfigID = configID
-			}
-			sm.save()
-			return sess, nil
+	sess, err := sm.findClient(deviceID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if sess != nil {
+		// Update existing session
+		sess.ClientName = clientName
+		sess.DeviceID = deviceID
+		sess.IPAddress = ipAddress
+		sess.LastConnected = time.Now()
+		sess.LastActive = time.Now()
+		if configID != "" {
+			sess.ConfigID = configID
 		}
+		sm.save()
+		copied := *sess
+		return &copied, nil
 	}
 
 	// Create new session
@@ -69,6 +95,7 @@ func (sm *SessionManager) RegisterOrUpdate(clientID, clientName, configID, ipAdd
 		SessionID:     uuid.New().String(),
 		ClientID:      clientID,
 		ClientName:    clientName,
+		DeviceID:      deviceID,
 		ConfigID:      configID,
 		IPAddress:     ipAddress,
 		LastConnected: time.Now(),
@@ -77,50 +104,177 @@ func (sm *SessionManager) RegisterOrUpdate(clientID, clientName, configID, ipAdd
 
 	sm.sessions[session.SessionID] = session
 	sm.save()
-	return session, nil
+	copied := *session
+	return &copied, nil
 }
 
-// Get retrieves a session by session ID
+// Get retrieves a copy of a session by session ID. Sessions are only
+// changed through the manager, which holds the lock.
 func (sm *SessionManager) Get(sessionID string) (*models.ClientSession, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
 	sess, ok := sm.sessions[sessionID]
 	if !ok {
 		return nil, fmt.Errorf("session not found: %s", sessionID)
 	}
-	return sess, nil
+	copied := *sess
+	return &copied, nil
+}
+
+// GetByClientID retrieves a copy of a device's session by client ID
+func (sm *SessionManager) GetByClientID(deviceID, clientID string) (*models.ClientSession, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sess, err := sm.findClient(deviceID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("session not found for client: %s", clientID)
+	}
+	copied := *sess
+	return &copied, nil
 }
 
-// GetByClientID retrieves a session by client ID
-func (sm *SessionManager) GetByClientID(clientID string) (*models.ClientSession, error) {
+// findClient returns a device's session for a client ID, or nil if there is
+// none. Sessions from before pairing have no device and are only given to
+// the device paired with their client ID. Callers hold sm.mu.
+func (sm *SessionManager) findClient(deviceID, clientID string) (*models.ClientSession, error) {
 	for _, sess := range sm.sessions {
-		if sess.ClientID == clientID {
+		if sess.ClientID != clientID {
+			continue
+		}
+		if sess.DeviceID == deviceID {
+			return sess, nil
+		}
+		if device, ok := sm.devices[deviceID]; ok && sess.DeviceID == "" && device.ClientID == clientID {
 			return sess, nil
 		}
+		return nil, ErrSessionForbidden
 	}
-	return nil, fmt.Errorf("session not found for client: %s", clientID)
+	return nil, nil
 }
 
-// List returns all sessions
+// List returns copies of all sessions
 func (sm *SessionManager) List() []*models.ClientSession {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
 	sessions := make([]*models.ClientSession, 0, len(sm.sessions))
 	for _, sess := range sm.sessions {
-		sessions = append(sessions, sess)
+		copied := *sess
+		sessions = append(sessions, &copied)
 	}
 	return sessions
 }
 
+// OnConfigChange registers a callback for sessions moved to another
+// configuration
+func (sm *SessionManager) OnConfigChange(fn func(sessionID string)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onConfigChange = fn
+}
+
 // UpdateConfig updates the configuration for a session
 func (sm *SessionManager) UpdateConfig(sessionID, configID string) error {
+	sm.mu.Lock()
 	sess, ok := sm.sessions[sessionID]
 	if !ok {
+		sm.mu.Unlock()
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
+	changed := sess.ConfigID != configID
 	sess.ConfigID = configID
 	sess.LastActive = time.Now()
+	err := sm.save()
+	onConfigChange := sm.onConfigChange
+	sm.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if changed && onConfigChange != nil {
+		onConfigChange(sessionID)
+	}
+	return nil
+}
+
+// UsingConfigs returns copies of the sessions using any of the given
+// configurations
+func (sm *SessionManager) UsingConfigs(configIDs []string) []*models.ClientSession {
+	wanted := make(map[string]bool, len(configIDs))
+	for _, id := range configIDs {
+		wanted[id] = true
+	}
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sessions := make([]*models.ClientSession, 0)
+	for _, sess := range sm.sessions {
+		if wanted[sess.ConfigID] {
+			copied := *sess
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions
+}
+
+// SetRole sets the role of a session and of its device, which caps every
+// session of the device. Empty uses the configuration's role and lets the
+// device's next session pin its cap again.
+func (sm *SessionManager) SetRole(sessionID, role string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sess, ok := sm.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	sess.Role = role
+	if device, ok := sm.devices[sess.DeviceID]; ok {
+		device.Role = role
+		if err := sm.saveDevices(); err != nil {
+			return err
+		}
+	}
+	return sm.save()
+}
+
+// SetLocked locks or unlocks a session's grid
+func (sm *SessionManager) SetLocked(sessionID string, locked bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sess, ok := sm.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	sess.Locked = locked
+	return sm.save()
+}
+
+// Rename changes the name shown for a session
+func (sm *SessionManager) Rename(sessionID, name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sess, ok := sm.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	sess.ClientName = name
 	return sm.save()
 }
 
 // UpdateActivity updates the last activity time for a session
 func (sm *SessionManager) UpdateActivity(sessionID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	sess, ok := sm.sessions[sessionID]
 	if !ok {
 		return fmt.Errorf("session not found: %s", sessionID)
@@ -131,17 +285,59 @@ func (sm *SessionManager) UpdateActivity(sessionID string) error {
 
 // Delete removes a session
 func (sm *SessionManager) Delete(sessionID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	delete(sm.sessions, sessionID)
 	return sm.save()
 }
 
-// CleanupInactive removes sessions inactive for more than the specified duration
-func (sm *SessionManager) CleanupInactive(duration time.Duration) error {
-	cutoff := time.Now().Add(-duration)
+// MarkSeen records that a session's client connected or disconnected
+func (sm *SessionManager) MarkSeen(sessionID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sess, ok := sm.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	sess.LastSeen = time.Now()
+	return sm.save()
+}
+
+// Heartbeat records a heartbeat from a session's client. Heartbeats are
+// frequent, so they are only written along with the next save.
+func (sm *SessionManager) Heartbeat(sessionID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sess, ok := sm.sessions[sessionID]; ok {
+		sess.LastSeen = time.Now()
+	}
+}
+
+// ExpireSessions removes sessions whose clients haven't been heard from for
+// longer than retention, keeping connected ones. A zero retention keeps every
+// session. It returns how many sessions were removed.
+func (sm *SessionManager) ExpireSessions(retention time.Duration, connected func(sessionID string) bool) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	removed := 0
 	for sessionID, sess := range sm.sessions {
-		if sess.LastActive.Before(cutoff) {
-			delete(sm.sessions, sessionID)
+		if connected(sessionID) || sess.LastContact().After(cutoff) {
+			continue
 		}
+		delete(sm.sessions, sessionID)
+		removed++
 	}
-	return sm.save()
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, sm.save()
 }
@@ -0,0 +1,258 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robomon1/robo-stream/server/internal/models"
+	"github.com/robomon1/robo-stream/server/internal/storage"
+)
+
+// SettingsFile is the data file holding the server settings
+const SettingsFile = "settings.json"
+
+// Setting keys, shared by the settings file, flags and environment variables
+const (
+	SettingListenAddress    = "listen_address"
+	SettingPort             = "port"
+	SettingDataDir          = "data_dir"
+	SettingIdleTimeout      = "idle_timeout"
+	SettingSessionRetention = "session_retention"
+	SettingCleanupInterval  = "cleanup_interval"
+	SettingOBSAutoConnect   = "obs_auto_connect"
+	SettingLogLevel         = "log_level"
+	SettingTLS              = "tls"
+	SettingTLSCertFile      = "tls_cert_file"
+	SettingTLSKeyFile       = "tls_key_file"
+)
+
+// SettingKeys lists every setting that can be overridden
+var SettingKeys = []string{
+	SettingListenAddress,
+	SettingPort,
+	SettingDataDir,
+	SettingIdleTimeout,
+	SettingSessionRetention,
+	SettingCleanupInterval,
+	SettingOBSAutoConnect,
+	SettingLogLevel,
+	SettingTLS,
+	SettingTLSCertFile,
+	SettingTLSKeyFile,
+}
+
+// logLevels are the accepted log levels
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// SettingsManager manages the server settings. Values given by flags or
+// environment variables override the settings file for the current run and
+// are never written back to it.
+type SettingsManager struct {
+	storage   *storage.Storage
+	saved     models.Settings
+	current   models.Settings
+	overrides map[string]string
+	mu        sync.RWMutex
+}
+
+// NewSettingsManager loads the settings file and applies overrides on top.
+// An invalid settings file falls back to the defaults; invalid overrides are
+// an error since they were typed for this run.
+func NewSettingsManager(storage *storage.Storage, overrides map[string]string) (*SettingsManager, error) {
+	sm := &SettingsManager{
+		storage:   storage,
+		overrides: overrides,
+	}
+
+	sm.saved = models.DefaultSettings()
+	if err := storage.LoadJSON(SettingsFile, &sm.saved); err != nil {
+		storageLog.Warn("Failed to read settings, using defaults", "file", SettingsFile, "error", err)
+		sm.saved = models.DefaultSettings()
+	}
+	if err := ValidateSettings(sm.saved); err != nil {
+		storageLog.Warn("Invalid settings, using defaults", "file", SettingsFile, "error", err)
+		sm.saved = models.DefaultSettings()
+	}
+
+	current, err := sm.effective(sm.saved)
+	if err != nil {
+		return nil, err
+	}
+	sm.current = current
+	return sm, nil
+}
+
+// Get returns the settings in effect
+func (sm *SettingsManager) Get() models.Settings {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.current
+}
+
+// Overridden returns the keys fixed by flags or environment variables
+func (sm *SettingsManager) Overridden() []string {
+	keys := make([]string, 0, len(sm.overrides))
+	for _, key := range SettingKeys {
+		if _, ok := sm.overrides[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Update saves new settings and returns the settings in effect, which still
+// honour any overrides
+func (sm *SettingsManager) Update(settings models.Settings) (models.Settings, error) {
+	if err := ValidateSettings(settings); err != nil {
+		return models.Settings{}, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	// Overridden values belong to this run, so the file keeps its own
+	settings = sm.keepSaved(settings)
+	current, err := sm.effective(settings)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	if err := sm.storage.SaveJSON(SettingsFile, settings); err != nil {
+		return models.Settings{}, err
+	}
+	sm.saved = settings
+	sm.current = current
+	return current, nil
+}
+
+// keepSaved replaces overridden settings with the values from the file
+func (sm *SettingsManager) keepSaved(settings models.Settings) models.Settings {
+	for key := range sm.overrides {
+		switch key {
+		case SettingListenAddress:
+			settings.ListenAddress = sm.saved.ListenAddress
+		case SettingPort:
+			settings.Port = sm.saved.Port
+		case SettingIdleTimeout:
+			settings.IdleTimeoutMinutes = sm.saved.IdleTimeoutMinutes
+		case SettingSessionRetention:
+			settings.SessionRetentionDays = sm.saved.SessionRetentionDays
+		case SettingCleanupInterval:
+			settings.CleanupIntervalMinutes = sm.saved.CleanupIntervalMinutes
+		case SettingOBSAutoConnect:
+			settings.OBSAutoConnect = sm.saved.OBSAutoConnect
+		case SettingLogLevel:
+			settings.LogLevel = sm.saved.LogLevel
+		case SettingTLS:
+			settings.TLS = sm.saved.TLS
+		case SettingTLSCertFile:
+			settings.TLSCertFile = sm.saved.TLSCertFile
+		case SettingTLSKeyFile:
+			settings.TLSKeyFile = sm.saved.TLSKeyFile
+		}
+	}
+	settings.DataDir = ""
+	return settings
+}
+
+// effective applies the overrides to saved settings
+func (sm *SettingsManager) effective(saved models.Settings) (models.Settings, error) {
+	settings := saved
+	if err := ApplySettingOverrides(&settings, sm.overrides); err != nil {
+		return models.Settings{}, err
+	}
+	settings.DataDir = sm.storage.GetDataDir()
+	return settings, ValidateSettings(settings)
+}
+
+// ApplySettingOverrides parses raw flag or environment values into settings.
+// Durations accept Go syntax ("90s", "1h") or a plain number of minutes, or
+// of days for the session retention.
+func ApplySettingOverrides(settings *models.Settings, overrides map[string]string) error {
+	for key, value := range overrides {
+		var err error
+		switch key {
+		case SettingListenAddress:
+			settings.ListenAddress = value
+		case SettingPort:
+			settings.Port, err = strconv.Atoi(value)
+		case SettingDataDir:
+			settings.DataDir = value
+		case SettingIdleTimeout:
+			settings.IdleTimeoutMinutes, err = parseMinutes(value)
+		case SettingSessionRetention:
+			settings.SessionRetentionDays, err = parseDays(value)
+		case SettingCleanupInterval:
+			settings.CleanupIntervalMinutes, err = parseMinutes(value)
+		case SettingOBSAutoConnect:
+			settings.OBSAutoConnect, err = strconv.ParseBool(value)
+		case SettingLogLevel:
+			settings.LogLevel = strings.ToLower(value)
+		case SettingTLS:
+			settings.TLS, err = strconv.ParseBool(value)
+		case SettingTLSCertFile:
+			settings.TLSCertFile = value
+		case SettingTLSKeyFile:
+			settings.TLSKeyFile = value
+		default:
+			err = fmt.Errorf("unknown setting")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	return nil
+}
+
+// ValidateSettings checks that settings can be applied
+func ValidateSettings(settings models.Settings) error {
+	if net.ParseIP(settings.ListenAddress) == nil && settings.ListenAddress != "localhost" {
+		return fmt.Errorf("invalid listen address %q", settings.ListenAddress)
+	}
+	if settings.Port < 1 || settings.Port > 65535 {
+		return fmt.Errorf("invalid port %d", settings.Port)
+	}
+	if settings.IdleTimeoutMinutes < 1 {
+		return fmt.Errorf("idle timeout must be at least 1 minute")
+	}
+	if settings.SessionRetentionDays < 0 {
+		return fmt.Errorf("session retention can't be negative")
+	}
+	if settings.CleanupIntervalMinutes < 1 {
+		return fmt.Errorf("cleanup interval must be at least 1 minute")
+	}
+	if !logLevels[settings.LogLevel] {
+		return fmt.Errorf("invalid log level %q", settings.LogLevel)
+	}
+	if (settings.TLSCertFile == "") != (settings.TLSKeyFile == "") {
+		return fmt.Errorf("a TLS certificate file needs a key file, and the other way round")
+	}
+	return nil
+}
+
+// parseMinutes reads a duration as whole minutes
+func parseMinutes(value string) (int, error) {
+	if minutes, err := strconv.Atoi(value); err == nil {
+		return minutes, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int(d / time.Minute), nil
+}
+
+// parseDays reads a duration as whole days
+func parseDays(value string) (int, error) {
+	if days, err := strconv.Atoi(value); err == nil {
+		return days, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int(d / (24 * time.Hour)), nil
+}
@@ -0,0 +1,8 @@
+package models
+
+// AssignmentRule picks the configuration a newly registered client starts with
+type AssignmentRule struct {
+	ClientID   string `json:"client_id,omitempty"`   // glob matched against the client ID
+	ClientName string `json:"client_name,omitempty"` // glob matched against the client name
+	ConfigID   string `json:"config_id"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Audit entry actions
+const (
+	AuditOBSRename = "obs_rename" // button params followed a rename in OBS
+)
+
+// AuditEntry records a change the server made to the data on its own
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Message   string    `json:"message"`
+	ButtonIDs []string  `json:"button_ids,omitempty"`
+}
@@ -5,6 +5,7 @@ import "time"
 // Button represents a reusable button in the library
 type Button struct {
 	ID          string       `json:"id"`
+	Slug        string       `json:"slug,omitempty"` // stable name used by declarative config files
 	Name        string       `json:"name"`
 	Description string       `json:"description"`
 	Icon        string       `json:"icon"`
@@ -5,11 +5,13 @@ import "time"
 // Configuration represents a button layout for a specific role/client
 type Configuration struct {
 	ID          string            `json:"id"`
+	Slug        string            `json:"slug,omitempty"` // stable name used by declarative config files
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
 	Grid        GridConfig        `json:"grid"`
 	Buttons     map[string]string `json:"buttons"` // position (btn-0-0) -> button ID
 	IsDefault   bool              `json:"is_default"`
+	Role        string            `json:"role,omitempty"` // default role for sessions using it
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 }
@@ -22,10 +24,10 @@ type GridConfig struct {
 
 // ResolvedConfiguration is what gets sent to clients with full button details
 type ResolvedConfiguration struct {
-	ID      string            `json:"id"`
-	Name    string            `json:"name"`
-	Grid    GridConfig        `json:"grid"`
-	Buttons []ResolvedButton  `json:"buttons"`
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	Grid    GridConfig       `json:"grid"`
+	Buttons []ResolvedButton `json:"buttons"`
 }
 
 // ResolvedButton is a button with position information for the client
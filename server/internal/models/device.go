@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PairedDevice is a client that redeemed a pairing code and holds a token
+type PairedDevice struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ClientID string `json:"client_id"`
+	// Role caps the role of every session of the device. It's the role of
+	// its first session unless the operator sets one.
+	Role     string    `json:"role,omitempty"`
+	PairedAt time.Time `json:"paired_at"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// PairingCode is a short-lived, single-use code shown in the server UI
+type PairingCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// QRCode is a PNG data URL encoding the server address and code
+	QRCode string `json:"qr_code,omitempty"`
+	// Fingerprint is the SHA-256 fingerprint of the server's TLS
+	// certificate, if it serves HTTPS
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
@@ -2,6 +2,12 @@ package models
 
 // OBSConfig stores OBS WebSocket connection settings
 type OBSConfig struct {
-	URL      string `json:"url"`
-	Password string `json:"password"`
+	URL string `json:"url"`
+
+	// Password is only held in memory. It is encrypted into
+	// EncryptedPassword before saving and never sent to the UI or clients;
+	// older versions stored it here in plaintext.
+	Password          string `json:"password,omitempty"`
+	EncryptedPassword string `json:"encrypted_password,omitempty"`
+	HasPassword       bool   `json:"has_password"`
 }
@@ -0,0 +1,93 @@
+package models
+
+// Built-in roles
+const (
+	RoleOperator = "operator" // runs everything; used when nothing else is assigned
+	RoleTalent   = "talent"   // switches scenes and sources, mutes inputs
+	RoleViewer   = "viewer"   // sees the deck but can't run actions
+)
+
+// AllActions in AllowedActions allows every action type
+const AllActions = "*"
+
+// Role limits what a client session may do. Roles are attached to sessions
+// or, as a default for every session using it, to configurations.
+type Role struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	AllowedActions []string `json:"allowed_actions"`           // action types, or "*"
+	AllowedButtons []string `json:"allowed_buttons,omitempty"` // library button IDs; empty allows any
+}
+
+// AllowsAction reports whether the role may run an action type
+func (r *Role) AllowsAction(actionType string) bool {
+	for _, allowed := range r.AllowedActions {
+		if allowed == AllActions || allowed == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsButton reports whether the role may press a button
+func (r *Role) AllowsButton(buttonID string) bool {
+	if len(r.AllowedButtons) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedButtons {
+		if allowed == buttonID {
+			return true
+		}
+	}
+	return false
+}
+
+// Within reports whether the role allows nothing beyond what other allows
+func (r *Role) Within(other *Role) bool {
+	for _, action := range r.AllowedActions {
+		if !other.AllowsAction(action) {
+			return false
+		}
+	}
+	if len(other.AllowedButtons) == 0 {
+		return true
+	}
+	if len(r.AllowedButtons) == 0 {
+		return false
+	}
+	for _, button := range r.AllowedButtons {
+		if !other.AllowsButton(button) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultRoles returns the built-in roles
+func DefaultRoles() []*Role {
+	return []*Role{
+		{
+			Name:           RoleOperator,
+			Description:    "Full control of OBS",
+			AllowedActions: []string{AllActions},
+		},
+		{
+			Name:        RoleTalent,
+			Description: "Scenes, sources and mutes only",
+			AllowedActions: []string{
+				"switch_scene",
+				"toggle_source_visibility",
+				"show_source",
+				"hide_source",
+				"toggle_input_mute",
+				"mute_input",
+				"unmute_input",
+			},
+		},
+		{
+			Name:           RoleViewer,
+			Description:    "Read-only",
+			AllowedActions: []string{},
+		},
+	}
+}
@@ -2,13 +2,40 @@ package models
 
 import "time"
 
+// Session presence, derived from the client's events connection
+const (
+	PresenceOnline  = "online"  // connected and recently used
+	PresenceIdle    = "idle"    // connected but no recent button presses
+	PresenceOffline = "offline" // not connected
+)
+
 // ClientSession tracks which client is using which configuration
 type ClientSession struct {
 	SessionID     string    `json:"session_id"`
 	ClientID      string    `json:"client_id"`
 	ClientName    string    `json:"client_name"`
+	DeviceID      string    `json:"device_id,omitempty"`
 	ConfigID      string    `json:"config_id"`
+	Role          string    `json:"role,omitempty"`   // overrides the configuration's role
+	Locked        bool      `json:"locked,omitempty"` // refuses button presses until unlocked
 	IPAddress     string    `json:"ip_address"`
 	LastConnected time.Time `json:"last_connected"`
 	LastActive    time.Time `json:"last_active"`
+	LastSeen      time.Time `json:"last_seen"` // last heartbeat over the events connection
+
+	// Online and Presence are set when listing sessions. Online is true while
+	// the client holds an events connection.
+	Online   bool   `json:"online"`
+	Presence string `json:"presence,omitempty"`
+}
+
+// LastContact returns when the client was last heard from in any way
+func (s *ClientSession) LastContact() time.Time {
+	last := s.LastSeen
+	for _, t := range []time.Time{s.LastActive, s.LastConnected} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
 }
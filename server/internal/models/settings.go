@@ -0,0 +1,30 @@
+package models
+
+// Settings holds the server settings saved in the data directory. Flags and
+// environment variables can override any of them for a single run.
+type Settings struct {
+	ListenAddress          string `json:"listen_address"` // interface the client API binds to
+	Port                   int    `json:"port"`
+	DataDir                string `json:"data_dir,omitempty"`     // set by flag, env or platform default; not saved
+	IdleTimeoutMinutes     int    `json:"idle_timeout_minutes"`   // connected sessions without presses become idle
+	SessionRetentionDays   int    `json:"session_retention_days"` // offline sessions are removed after this; 0 keeps them
+	CleanupIntervalMinutes int    `json:"cleanup_interval_minutes"`
+	OBSAutoConnect         bool   `json:"obs_auto_connect"`
+	LogLevel               string `json:"log_level"`               // debug, info, warn or error
+	TLS                    bool   `json:"tls"`                     // serve the client API over HTTPS
+	TLSCertFile            string `json:"tls_cert_file,omitempty"` // with TLSKeyFile, used instead of a self-signed certificate
+	TLSKeyFile             string `json:"tls_key_file,omitempty"`
+}
+
+// DefaultSettings returns the settings used when nothing is configured
+func DefaultSettings() Settings {
+	return Settings{
+		ListenAddress:          "0.0.0.0",
+		Port:                   8080,
+		IdleTimeoutMinutes:     5,
+		SessionRetentionDays:   30,
+		CleanupIntervalMinutes: 5,
+		OBSAutoConnect:         true,
+		LogLevel:               "info",
+	}
+}
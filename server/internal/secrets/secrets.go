@@ -0,0 +1,173 @@
+// Package secrets encrypts values such as the OBS WebSocket password before
+// they are written to the data directory.
+//
+// Values are sealed with AES-256-GCM. The key is either random and kept in a
+// 0600 key file next to the data, or derived from a user passphrase with
+// scrypt so that nothing on disk is enough to decrypt it.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeyFile holds the random key when no passphrase is used
+	KeyFile = "secret.key"
+
+	// SaltFile holds the scrypt salt when a passphrase is used
+	SaltFile = "secret.salt"
+
+	// prefix marks sealed values so plaintext left by older versions can be
+	// told apart and migrated
+	prefix = "enc:v1:"
+
+	keySize  = 32
+	saltSize = 16
+)
+
+// ErrWrongKey is returned when a value can't be decrypted with the current key
+var ErrWrongKey = errors.New("secret can't be decrypted with the current key or passphrase")
+
+// Box seals and opens secrets with one key
+type Box struct {
+	aead cipher.AEAD
+}
+
+// Open returns a Box for the data directory. With a passphrase the key is
+// derived from it; otherwise a random key is loaded from, or created in, the
+// key file.
+func Open(dataDir, passphrase string) (*Box, error) {
+	var key []byte
+	var err error
+	if passphrase != "" {
+		key, err = passphraseKey(dataDir, passphrase)
+	} else {
+		key, err = fileKey(dataDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts a value. Empty values stay empty.
+func (b *Box) Seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func (b *Box) Open(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	if !IsSealed(sealed) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, prefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+
+	plaintext, err := b.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether a value was produced by Seal
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// fileKey loads the random key, creating it on first use
+func fileKey(dataDir string) ([]byte, error) {
+	path := filepath.Join(dataDir, KeyFile)
+
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%s has the wrong size", KeyFile)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writeNew(path, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// passphraseKey derives the key from a passphrase and the stored salt,
+// creating the salt on first use
+func passphraseKey(dataDir, passphrase string) ([]byte, error) {
+	path := filepath.Join(dataDir, SaltFile)
+
+	salt, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		if err := writeNew(path, salt); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+}
+
+// writeNew writes a file readable only by the owner, failing if it exists
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
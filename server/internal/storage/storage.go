@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,14 +14,26 @@ import (
 type Storage struct {
 	dataDir string
 	mu      sync.RWMutex
+
+	// checksums holds the content hash of each file as we last read or
+	// wrote it, so edits made outside the app can be told apart from ours
+	checksumMu sync.Mutex
+	checksums  map[string]string
 }
 
-// New creates a new Storage instance
+// New creates a new Storage instance. The data directory and everything in
+// it is restricted to the current user.
 func New(dataDir string) (*Storage, error) {
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := restrictPermissions(dataDir); err != nil {
 		return nil, err
 	}
-	return &Storage{dataDir: dataDir}, nil
+	return &Storage{
+		dataDir:   dataDir,
+		checksums: make(map[string]string),
+	}, nil
 }
 
 // LoadJSON loads data from a JSON file
@@ -30,16 +45,22 @@ func (s *Storage) LoadJSON(filename string, v interface{}) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
+			s.setChecksum(filename, nil)
 			return nil // File doesn't exist yet, return empty
 		}
 		return err
 	}
 
 	if len(data) == 0 {
+		s.setChecksum(filename, data)
 		return nil // Empty file
 	}
 
-	return json.Unmarshal(data, v)
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	s.setChecksum(filename, data)
+	return nil
 }
 
 // SaveJSON saves data to a JSON file
@@ -52,11 +73,87 @@ func (s *Storage) SaveJSON(filename string, v interface{}) error {
 		return err
 	}
 
+	// Write to a temp file and rename so watchers and readers never see a
+	// half-written file
 	path := filepath.Join(s.dataDir, filename)
-	return os.WriteFile(path, data, 0644)
+	tmp, err := os.CreateTemp(s.dataDir, "."+filename+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	s.setChecksum(filename, data)
+	return nil
+}
+
+// Modified reports whether a file changed on disk since it was last loaded
+// or saved through this Storage
+func (s *Storage) Modified(filename string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(filepath.Join(s.dataDir, filename))
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	s.checksumMu.Lock()
+	defer s.checksumMu.Unlock()
+	known, ok := s.checksums[filename]
+	if !ok {
+		return true, nil
+	}
+	return known != checksum(data), nil
 }
 
 // GetDataDir returns the data directory path
 func (s *Storage) GetDataDir() string {
 	return s.dataDir
 }
+
+// restrictPermissions makes the data directory tree private to the owner,
+// tightening files written by older versions with 0644
+func restrictPermissions(dataDir string) error {
+	return filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(0600)
+		if d.IsDir() {
+			mode = 0700
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&^mode == 0 {
+			return nil
+		}
+		return os.Chmod(path, info.Mode().Perm()&mode)
+	})
+}
+
+// setChecksum records the content we last saw for a file
+func (s *Storage) setChecksum(filename string, data []byte) {
+	s.checksumMu.Lock()
+	defer s.checksumMu.Unlock()
+	s.checksums[filename] = checksum(data)
+}
+
+// checksum returns the hex SHA-256 of file contents
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/robomon1/robo-stream/server/internal/logging"
+)
+
+// watchDebounce is how long a file must be quiet before we report it, so
+// editors and sync tools that write in several steps trigger one reload
+const watchDebounce = 300 * time.Millisecond
+
+var logger = logging.For(logging.Storage)
+
+// Watcher reports data files that were changed outside the app
+type Watcher struct {
+	storage  *Storage
+	files    map[string]bool
+	onChange func(filename string)
+	fsw      *fsnotify.Watcher
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Watch starts watching the data directory for changes to the given files.
+// onChange is called from a background goroutine with the file name whenever
+// its content differs from what this Storage last read or wrote.
+func (s *Storage) Watch(files []string, onChange func(filename string)) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory rather than the files: sync tools and editors
+	// usually replace files by rename, which drops per-file watches
+	if err := fsw.Add(s.dataDir); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		storage:  s,
+		files:    make(map[string]bool, len(files)),
+		onChange: onChange,
+		fsw:      fsw,
+		done:     make(chan struct{}),
+	}
+	for _, f := range files {
+		w.files[f] = true
+	}
+
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Stop stops watching and waits for pending callbacks to finish
+func (w *Watcher) Stop() {
+	close(w.done)
+	w.fsw.Close()
+	w.wg.Wait()
+}
+
+// run collects filesystem events and reports debounced changes
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	pending := make(map[string]time.Time)
+	ticker := time.NewTicker(watchDebounce / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if w.files[name] {
+				pending[name] = time.Now()
+			}
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("Data directory watch error", "error", err)
+
+		case now := <-ticker.C:
+			for name, at := range pending {
+				if now.Sub(at) < watchDebounce {
+					continue
+				}
+				delete(pending, name)
+
+				// Our own writes update the stored checksum, so this
+				// filters them out
+				modified, err := w.storage.Modified(name)
+				if err != nil {
+					logger.Warn("Failed to check file for changes", "file", name, "error", err)
+					continue
+				}
+				if modified {
+					w.onChange(name)
+				}
+			}
+		}
+	}
+}
@@ -4,10 +4,14 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"log"
+	"os"
 
 	// "runtime"
 
+	"github.com/robomon1/robo-stream/server/internal/core"
+	"github.com/robomon1/robo-stream/server/internal/logging"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -28,11 +32,17 @@ func loadIcon() []byte {
 }
 
 func main() {
+	// Settings given on the command line or in the environment
+	overrides, err := core.ParseSettingOverrides(flag.NewFlagSet("robo-stream-server", flag.ContinueOnError), os.Args[1:])
+	if err != nil {
+		logging.For(logging.App).Warn("Ignoring command line flags", "error", err)
+	}
+
 	// Create an instance of the app structure
-	app := NewApp()
+	app := NewApp(overrides)
 
 	// Create application with options
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:     "Robo-Stream Server",
 		Width:     1280,
 		Height:    800,